@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"my-flutter-backend/config"
+	"my-flutter-backend/internal/jobs"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Sweep ALPHA manual / lewat cron, misal untuk mengisi ulang rentang tanggal setelah server lama mati:
+//
+//	go run ./cmd/alpha-sweep -from 2026-10-01 -to 2026-10-15 [-org 3]
+//
+// Aman dijalankan berulang (jadwal yang sudah punya kehadiran tidak disentuh).
+func main() {
+	today := time.Now().Format("2006-01-02")
+	from := flag.String("from", today, "Tanggal awal jadwal (YYYY-MM-DD)")
+	to := flag.String("to", today, "Tanggal akhir jadwal (YYYY-MM-DD)")
+	orgID := flag.Uint("org", 0, "ID organisasi (0 = semua)")
+	flag.Parse()
+
+	for _, d := range []string{*from, *to} {
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			log.Fatalf("Format tanggal harus YYYY-MM-DD: %s", d)
+		}
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: File .env tidak ditemukan, menggunakan environment variables sistem.")
+	}
+	config.ConnectDB()
+
+	created, err := jobs.RunAlphaSweep(config.DB, *from, *to, *orgID)
+	if err != nil {
+		log.Fatalf("Sweep ALPHA gagal: %v", err)
+	}
+	fmt.Printf("Sweep ALPHA %s s/d %s selesai: %d kehadiran ALPHA dibuat\n", *from, *to, created)
+}
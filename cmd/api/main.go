@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"my-flutter-backend/config"
+	"my-flutter-backend/internal/jobs"
 	"my-flutter-backend/internal/middleware"
 	"my-flutter-backend/internal/routes"
 
@@ -20,6 +21,7 @@ func main() {
 
 	fmt.Println("2. Mencoba koneksi ke Database...")
 	config.ConnectDB()
+	config.LoadJWTKeys()
 	fmt.Println("3. Database berhasil terhubung! Menyiapkan routes...")
 
 	app := fiber.New()
@@ -35,6 +37,7 @@ func main() {
 	middleware.Init(config.DB)
 
 	routes.SetupASNRoutes(app, config.DB)
+	routes.SetupSessionRoutes(app, config.DB)
 	routes.SetupKehadiranRoutes(app, config.DB)
 	routes.SetupPerizinanRoutes(app, config.DB)
 	routes.SetupJadwalRoutes(app, config.DB)
@@ -46,6 +49,14 @@ func main() {
 	routes.SetupHariLiburRoutes(app, config.DB)
 	routes.SetupRoleRoutes(app, config.DB)
 	routes.SetupReportRoutes(app, config.DB)
+	routes.SetupAuditRoutes(app, config.DB)
+	routes.SetupServiceAccountRoutes(app, config.DB)
+	routes.SetupNotifikasiRoutes(app, config.DB)
+
+	// Job Berkala (Background)
+	jobs.StartAlphaSweep(config.DB)   // Tandai ALPHA setelah shift selesai tanpa absen
+	jobs.StartAutoCheckout(config.DB) // Tutup kehadiran yang lupa check-out (TIDAK_ABSEN_PULANG)
+	jobs.StartSelfiePurge(config.DB)  // Hapus file selfie yang melewati retensi organisasi
 
 	fmt.Println("4. Server siap! Menunggu request di port :3000")
 	app.Listen(":3000")
@@ -0,0 +1,44 @@
+// Mock IdP: OpenID Connect provider sederhana untuk development & uji coba login SSO secara lokal.
+// JANGAN dipakai di production (tanpa password, kunci dibuat ulang setiap start).
+//
+// Jalankan:
+//
+//	MOCK_IDP_PORT=9000 go run ./cmd/mock-idp
+//
+// Lalu set di backend:
+//
+//	OIDC_ISSUER=http://localhost:9000
+//	OIDC_CLIENT_ID=absensi        (bebas, mock tidak memvalidasi client)
+//	OIDC_CLIENT_SECRET=rahasia
+//	OIDC_REDIRECT_URI=http://localhost:5173/sso/callback
+//
+// Halaman /authorize menampilkan form isian NIP; atau langsung lewati form dengan ?login_hint=<NIP>.
+// Subject (sub) dibuat deterministik dari NIP ("mock-<NIP>"), claim NIP dikirim sebagai "nip".
+// Logika server ada di internal/idpmock (dipakai juga oleh test login SSO).
+package main
+
+import (
+	"fmt"
+	"log"
+	"my-flutter-backend/internal/idpmock"
+	"os"
+)
+
+func main() {
+	port := os.Getenv("MOCK_IDP_PORT")
+	if port == "" {
+		port = "9000"
+	}
+	issuerURL := os.Getenv("MOCK_IDP_ISSUER")
+	if issuerURL == "" {
+		issuerURL = "http://localhost:" + port
+	}
+
+	srv, err := idpmock.New(issuerURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Mock IdP berjalan di", issuerURL)
+	log.Fatal(srv.App().Listen(":" + port))
+}
@@ -0,0 +1,52 @@
+// Mock LDAP: Server LDAP minimal (hanya simple bind) untuk development & uji coba backend password LDAP.
+// JANGAN dipakai di production (tanpa TLS, user & password dari environment).
+//
+// Jalankan:
+//
+//	MOCK_LDAP_PORT=3389 MOCK_LDAP_USERS="198001012005011001:rahasia,199002022010012002:sandi" go run ./cmd/mock-ldap
+//
+// Lalu atur organisasi (PUT /api/admin/organisasi/:id):
+//
+//	{"password_backends": "ldap,local", "ldap_url": "ldap://localhost:3389",
+//	 "ldap_user_dn": "uid={nip},ou=pegawai,dc=dinas,dc=go,dc=id"}
+//
+// Template DN mock diatur lewat MOCK_LDAP_USER_DN (default sama dengan contoh di atas).
+// Hentikan mock untuk menguji fallback ke backend berikutnya saat LDAP tidak tersedia.
+// Logika server ada di internal/ldapmock (dipakai juga oleh test internal/passwordauth).
+package main
+
+import (
+	"fmt"
+	"log"
+	"my-flutter-backend/internal/ldapmock"
+	"net"
+	"os"
+	"strings"
+)
+
+func main() {
+	port := os.Getenv("MOCK_LDAP_PORT")
+	if port == "" {
+		port = "3389"
+	}
+	template := os.Getenv("MOCK_LDAP_USER_DN")
+	if template == "" {
+		template = "uid={nip},ou=pegawai,dc=dinas,dc=go,dc=id"
+	}
+	passwords := map[string]string{}
+	for _, entry := range strings.Split(os.Getenv("MOCK_LDAP_USERS"), ",") {
+		nip, password, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if ok && nip != "" {
+			passwords[nip] = password
+		}
+	}
+	srv := ldapmock.New(template, passwords)
+	srv.Logger = log.Default()
+
+	ln, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Mock LDAP berjalan di ldap://localhost:%s (%d user)\n", port, len(srv.Users))
+	log.Fatal(srv.Serve(ln))
+}
@@ -24,10 +24,18 @@ func ConnectDB() {
 
 	// Auto Migration: Membuat tabel otomatis berdasarkan struct di folder model
 	db.AutoMigrate(
-		&model.Organisasi{}, &model.Lokasi{}, &model.Role{}, &model.Permission{},
+		&model.Organisasi{}, &model.Lokasi{}, &model.LokasiAkses{}, &model.Role{}, &model.Permission{},
 		&model.ASN{}, &model.Kehadiran{}, &model.PerizinanCuti{},
 		&model.PerizinanKehadiran{}, &model.Jadwal{}, &model.Shift{}, &model.HariLibur{},
 		&model.Device{}, &model.Banner{},
+		&model.Session{}, &model.RefreshToken{}, &model.PasswordResetOTP{},
+		&model.LoginThrottle{}, &model.LoginLockoutEvent{},
+		&model.TwoFactor{}, &model.RecoveryCode{},
+		&model.AuditLog{}, &model.SSOLoginState{},
+		&model.DeviceChangeRequest{}, &model.DeviceHistory{},
+		&model.PasswordHistory{}, &model.ASNInvitation{},
+		&model.ServiceAccount{}, &model.Notifikasi{},
+		&model.FotoKehadiran{},
 	)
 	// database.SeedAll(db) // Dipindahkan ke cmd/seeder/main.go
 
@@ -0,0 +1,191 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTKey adalah satu kunci penandatangan/verifikasi token yang diidentifikasi dengan "kid".
+// SignKey bernilai nil jika kunci hanya dipakai untuk verifikasi (misal kunci lama / public key saja).
+type JWTKey struct {
+	KID       string
+	Method    jwt.SigningMethod
+	SignKey   interface{}
+	VerifyKey interface{}
+}
+
+// JWTKeySet menyimpan semua kunci aktif. Token baru selalu ditandatangani dengan ActiveKID,
+// tetapi token lama yang ditandatangani kunci lain di set ini tetap valid sampai kadaluwarsa.
+type JWTKeySet struct {
+	ActiveKID string
+	keys      map[string]*JWTKey
+	order     []string
+}
+
+var JWT *JWTKeySet
+
+// LoadJWTKeys membaca konfigurasi kunci JWT dari environment:
+//
+//	JWT_KEYS       = "kid:secret,kid2:file:/path/private.pem,kid3:file:/path/public.pem"
+//	JWT_ACTIVE_KID = kid yang dipakai untuk menandatangani token baru (default: entri pertama)
+//
+// Secret biasa => HS256. File PEM private/public RSA => RS256, Ed25519 => EdDSA.
+// Jika JWT_KEYS kosong, dipakai JWT_SECRET dengan kid "default" (kompatibel dengan token lama).
+// Jika keduanya kosong, server tidak mau start kecuali JWT_DEV_INSECURE=true (secret bawaan, khusus development).
+func LoadJWTKeys() {
+	ks, err := ParseJWTKeys(GetEnv("JWT_KEYS", ""), GetEnv("JWT_ACTIVE_KID", ""))
+	if err != nil {
+		panic("Konfigurasi JWT tidak valid: " + err.Error())
+	}
+	JWT = ks
+}
+
+func ParseJWTKeys(spec, activeKID string) (*JWTKeySet, error) {
+	if strings.TrimSpace(spec) == "" {
+		secret := GetEnv("JWT_SECRET", "")
+		if secret == "" {
+			// Secret bawaan hanya boleh dipakai jika diminta eksplisit untuk development lokal
+			if GetEnv("JWT_DEV_INSECURE", "") != "true" {
+				return nil, errors.New("JWT_KEYS atau JWT_SECRET wajib diatur (JWT_DEV_INSECURE=true untuk development lokal)")
+			}
+			fmt.Println("Warning: JWT_DEV_INSECURE=true, menggunakan secret bawaan (JANGAN dipakai di production).")
+			secret = "rahasia_negara"
+		}
+		spec = "default:" + secret
+	}
+
+	ks := &JWTKeySet{keys: make(map[string]*JWTKey)}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("entri JWT_KEYS harus berformat kid:secret atau kid:file:/path.pem (%q)", entry)
+		}
+
+		key, err := parseJWTKey(parts[0], parts[1])
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := ks.keys[key.KID]; exists {
+			return nil, fmt.Errorf("kid %q terdaftar lebih dari sekali", key.KID)
+		}
+		ks.keys[key.KID] = key
+		ks.order = append(ks.order, key.KID)
+	}
+
+	if len(ks.order) == 0 {
+		return nil, errors.New("tidak ada kunci JWT yang terdaftar")
+	}
+
+	if activeKID == "" {
+		activeKID = ks.order[0]
+	}
+	active, ok := ks.keys[activeKID]
+	if !ok {
+		return nil, fmt.Errorf("JWT_ACTIVE_KID %q tidak ada di JWT_KEYS", activeKID)
+	}
+	if active.SignKey == nil {
+		return nil, fmt.Errorf("kunci aktif %q hanya berisi public key, tidak bisa menandatangani token", activeKID)
+	}
+	ks.ActiveKID = activeKID
+
+	return ks, nil
+}
+
+func parseJWTKey(kid, material string) (*JWTKey, error) {
+	if !strings.HasPrefix(material, "file:") {
+		return &JWTKey{KID: kid, Method: jwt.SigningMethodHS256, SignKey: []byte(material), VerifyKey: []byte(material)}, nil
+	}
+
+	pemBytes, err := os.ReadFile(strings.TrimPrefix(material, "file:"))
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca kunci %q: %w", kid, err)
+	}
+
+	// Coba sebagai private key dulu (bisa sign & verify), lalu public key (verify saja)
+	if priv, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes); err == nil {
+		return &JWTKey{KID: kid, Method: jwt.SigningMethodRS256, SignKey: priv, VerifyKey: &priv.PublicKey}, nil
+	}
+	if priv, err := jwt.ParseEdPrivateKeyFromPEM(pemBytes); err == nil {
+		return &JWTKey{KID: kid, Method: jwt.SigningMethodEdDSA, SignKey: priv, VerifyKey: priv.(ed25519.PrivateKey).Public()}, nil
+	}
+	if pub, err := jwt.ParseRSAPublicKeyFromPEM(pemBytes); err == nil {
+		return &JWTKey{KID: kid, Method: jwt.SigningMethodRS256, VerifyKey: pub}, nil
+	}
+	if pub, err := jwt.ParseEdPublicKeyFromPEM(pemBytes); err == nil {
+		return &JWTKey{KID: kid, Method: jwt.SigningMethodEdDSA, VerifyKey: pub}, nil
+	}
+
+	return nil, fmt.Errorf("kunci %q bukan PEM RSA atau Ed25519 yang valid", kid)
+}
+
+// Sign menandatangani claims dengan kunci aktif dan menambahkan header "kid".
+func (ks *JWTKeySet) Sign(claims jwt.Claims) (string, error) {
+	key := ks.keys[ks.ActiveKID]
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.KID
+	return token.SignedString(key.SignKey)
+}
+
+// Parse memvalidasi token menggunakan kunci sesuai header "kid".
+// Token tanpa kid (diterbitkan sebelum rotasi kunci) diverifikasi dengan kunci "default" bila ada.
+func (ks *JWTKeySet) Parse(tokenString string) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			kid = "default"
+		}
+
+		key, ok := ks.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("kid %q tidak dikenal", kid)
+		}
+		// Cegah algorithm confusion: alg di header harus sama dengan tipe kunci
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("algoritma %s tidak sesuai untuk kid %q", token.Method.Alg(), kid)
+		}
+		return key.VerifyKey, nil
+	})
+}
+
+// JWKS mengembalikan public key (RS256/EdDSA) dalam format JSON Web Key Set
+// agar service lain bisa memverifikasi token tanpa mengetahui secret.
+// Kunci HMAC tidak pernah dipublikasikan.
+func (ks *JWTKeySet) JWKS() map[string]interface{} {
+	keys := make([]map[string]interface{}, 0)
+	for _, kid := range ks.order {
+		key := ks.keys[kid]
+		switch pub := key.VerifyKey.(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, map[string]interface{}{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"alg": key.Method.Alg(),
+				"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			keys = append(keys, map[string]interface{}{
+				"kty": "OKP",
+				"crv": "Ed25519",
+				"kid": kid,
+				"use": "sig",
+				"alg": key.Method.Alg(),
+				"x":   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	return map[string]interface{}{"keys": keys}
+}
@@ -10,7 +10,12 @@ require (
 	gorm.io/gorm v1.31.1
 )
 
-require github.com/joho/godotenv v1.5.1
+require (
+	github.com/joho/godotenv v1.5.1
+	gorm.io/driver/sqlite v1.6.0
+)
+
+require github.com/mattn/go-sqlite3 v1.14.22 // indirect
 
 require (
 	filippo.io/edwards25519 v1.1.0 // indirect
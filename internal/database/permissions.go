@@ -0,0 +1,71 @@
+package database
+
+// PermissionDef: Satu entri katalog permission
+type PermissionDef struct {
+	Nama      string
+	Deskripsi string
+}
+
+// RoleDef: Role bawaan beserta permission default-nya
+type RoleDef struct {
+	NamaRole    string
+	Superuser   bool // Lolos semua cek permission
+	Permissions []string
+}
+
+// Nama permission yang dipakai di route & handler
+const (
+	PermKelolaOrganisasi = "kelola_organisasi"
+	PermEditJadwal       = "edit_jadwal"
+	PermApproveCuti      = "approve_cuti"
+	PermViewRekap        = "view_rekap"
+	PermOverrideApproval = "override_approval"
+	PermKelolaPegawai    = "kelola_pegawai"
+	PermKelolaBanner     = "kelola_banner"
+	PermKelolaRole       = "kelola_role"
+	PermKelolaLokasi     = "kelola_lokasi"
+	PermViewAudit        = "view_audit"
+	PermKelolaAPIKey     = "kelola_api_key"
+	PermReviewWajah      = "review_wajah"
+)
+
+// PermissionCatalogue: Daftar pusat semua permission yang dikenal aplikasi.
+// Permission baru WAJIB didaftarkan di sini agar ikut ter-seed dan muncul di pengaturan role.
+var PermissionCatalogue = []PermissionDef{
+	{Nama: PermKelolaOrganisasi, Deskripsi: "Kelola organisasi lain (lintas dinas)"},
+	{Nama: PermEditJadwal, Deskripsi: "Kelola jadwal, shift, dan hari libur"},
+	{Nama: PermApproveCuti, Deskripsi: "Menyetujui/menolak izin, cuti, dan koreksi kehadiran bawahan"},
+	{Nama: PermViewRekap, Deskripsi: "Melihat dashboard dan rekap kehadiran organisasi"},
+	{Nama: PermOverrideApproval, Deskripsi: "Memproses approval pegawai yang bukan bawahan langsung"},
+	{Nama: PermKelolaPegawai, Deskripsi: "Kelola data pegawai, device, sesi, 2FA, dan kunci login"},
+	{Nama: PermKelolaBanner, Deskripsi: "Kelola banner aplikasi mobile"},
+	{Nama: PermKelolaRole, Deskripsi: "Kelola role dan permission"},
+	{Nama: PermKelolaLokasi, Deskripsi: "Kelola info organisasi dan lokasi absen"},
+	{Nama: PermViewAudit, Deskripsi: "Melihat audit log perubahan data administratif"},
+	{Nama: PermKelolaAPIKey, Deskripsi: "Kelola service account & API key integrasi sistem lain (payroll, e-Kinerja)"},
+	{Nama: PermReviewWajah, Deskripsi: "Meninjau check-in yang gagal verifikasi wajah"},
+}
+
+// DefaultRoles: Mapping permission default untuk role bawaan.
+// Otorisasi tidak bergantung pada nama role, sehingga role boleh di-rename dari menu Role.
+var DefaultRoles = []RoleDef{
+	{
+		NamaRole:    "Super Admin",
+		Superuser:   true,
+		Permissions: []string{PermKelolaOrganisasi, PermEditJadwal, PermViewRekap, PermOverrideApproval, PermKelolaPegawai, PermKelolaBanner, PermKelolaRole, PermKelolaLokasi, PermViewAudit, PermKelolaAPIKey, PermReviewWajah},
+	},
+	{
+		NamaRole:    "Admin",
+		Permissions: []string{PermEditJadwal, PermApproveCuti, PermViewRekap, PermOverrideApproval, PermKelolaPegawai, PermKelolaBanner, PermKelolaRole, PermKelolaLokasi, PermViewAudit, PermKelolaAPIKey, PermReviewWajah},
+	},
+	{
+		// view_rekap sekarang membuka dashboard & rekap seluruh organisasi, jadi tidak lagi diberikan ke Atasan/Pegawai.
+		// Rekap bawahan untuk Atasan tetap lewat /api/atasan/reports.
+		NamaRole:    "Atasan",
+		Permissions: []string{PermApproveCuti},
+	},
+	{
+		NamaRole:    "Pegawai",
+		Permissions: []string{},
+	},
+}
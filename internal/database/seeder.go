@@ -27,73 +27,32 @@ func SeedAll(db *gorm.DB) {
 	}
 	db.FirstOrCreate(&lokasi, model.Lokasi{NamaLokasi: lokasi.NamaLokasi})
 
-	// 3. Seed Roles
-	roles := []model.Role{
-		{NamaRole: "Admin"},
-		{NamaRole: "Atasan"},
-		{NamaRole: "Pegawai"},
-		{NamaRole: "Super Admin"}, // New Role
+	// 3. Seed Permissions (dari katalog pusat, lihat permissions.go)
+	permByName := make(map[string]model.Permission)
+	for _, def := range PermissionCatalogue {
+		p := model.Permission{NamaPermission: def.Nama}
+		db.FirstOrCreate(&p, model.Permission{NamaPermission: def.Nama})
+		db.Model(&p).Update("deskripsi", def.Deskripsi)
+		permByName[def.Nama] = p
 	}
-	for _, r := range roles {
-		db.FirstOrCreate(&r, model.Role{NamaRole: r.NamaRole})
-	}
-
-	// 4. Seed Permissions (Contoh)
-	perms := []model.Permission{
-		{NamaPermission: "kelola_organisasi"}, // New Permission
-		{NamaPermission: "edit_jadwal"},
-		{NamaPermission: "approve_cuti"},
-		{NamaPermission: "view_rekap"},
-	}
-	for _, p := range perms {
-		db.FirstOrCreate(&p, model.Permission{NamaPermission: p.NamaPermission})
-	}
-
-	// 4.1 Assign Permissions to Roles (Mapping Permission ke Role)
-	var superAdminRole, adminRole, atasanRole, pegawaiRole model.Role
-	db.Where("nama_role = ?", "Super Admin").First(&superAdminRole)
-	db.Where("nama_role = ?", "Admin").First(&adminRole)     // ID 2
-	db.Where("nama_role = ?", "Atasan").First(&atasanRole)   // ID 3
-	db.Where("nama_role = ?", "Pegawai").First(&pegawaiRole) // ID 4
-
-	var allPerms []model.Permission
-	db.Find(&allPerms)
-
-	// 0. Super Admin
-	var superAdminPerms []model.Permission
-	for _, p := range allPerms {
-		if p.NamaPermission != "approve_cuti" {
-			superAdminPerms = append(superAdminPerms, p)
-		}
-	}
-	db.Model(&superAdminRole).Association("Permissions").Replace(superAdminPerms)
 
-	// 1. Admin: Punya semua KECUALI 'kelola_organisasi'
-	var adminPerms []model.Permission
-	for _, p := range allPerms {
-		if p.NamaPermission != "kelola_organisasi" {
-			adminPerms = append(adminPerms, p)
-		}
-	}
-	db.Model(&adminRole).Association("Permissions").Replace(adminPerms)
-
-	// 2. Atasan: Semua kecuali 'edit_jadwal' & 'kelola_organisasi'
-	var atasanPerms []model.Permission
-	for _, p := range allPerms {
-		if p.NamaPermission != "edit_jadwal" && p.NamaPermission != "kelola_organisasi" {
-			atasanPerms = append(atasanPerms, p)
-		}
-	}
-	db.Model(&atasanRole).Association("Permissions").Replace(atasanPerms)
+	// 4. Seed Roles & Assign Permissions (Mapping Permission ke Role)
+	rolesByName := make(map[string]model.Role)
+	for _, def := range DefaultRoles {
+		r := model.Role{NamaRole: def.NamaRole}
+		db.FirstOrCreate(&r, model.Role{NamaRole: def.NamaRole})
+		db.Model(&r).Update("superuser", def.Superuser)
 
-	// 3. Pegawai (ID 3): Cuma punya permission 'view_rekap'
-	var pegawaiPerms []model.Permission
-	for _, p := range allPerms {
-		if p.NamaPermission == "view_rekap" {
-			pegawaiPerms = append(pegawaiPerms, p)
+		var rolePerms []model.Permission
+		for _, name := range def.Permissions {
+			rolePerms = append(rolePerms, permByName[name])
 		}
+		db.Model(&r).Association("Permissions").Replace(rolePerms)
+		rolesByName[def.NamaRole] = r
 	}
-	db.Model(&pegawaiRole).Association("Permissions").Replace(pegawaiPerms)
+	superAdminRole := rolesByName["Super Admin"]
+	adminRole := rolesByName["Admin"]
+	pegawaiRole := rolesByName["Pegawai"]
 
 	// 5. Seed Shift Default
 	shiftNormal := model.Shift{
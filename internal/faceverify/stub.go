@@ -0,0 +1,74 @@
+package faceverify
+
+import (
+	"image"
+	_ "image/jpeg" // Registrasi decoder JPEG
+	_ "image/png"  // Registrasi decoder PNG
+	"math/bits"
+	"os"
+)
+
+// Stub: Verifier lokal deterministik TANPA pengenalan wajah, untuk pengujian & lingkungan dev.
+// Skor = kemiripan average-hash 8x8 grayscale kedua gambar (foto yang sama -> 1.0).
+type Stub struct{}
+
+func (Stub) Name() string { return VerifierStub }
+
+func (Stub) Compare(selfiePath, referencePath string) (float64, error) {
+	if referencePath == "" {
+		return 0, ErrNoReference
+	}
+	ref, err := averageHash(referencePath)
+	if err != nil {
+		return 0, ErrNoReference
+	}
+	selfie, err := averageHash(selfiePath)
+	if err != nil {
+		return 0, err
+	}
+	return 1 - float64(bits.OnesCount64(ref^selfie))/64, nil
+}
+
+// averageHash: 64 bit, bit = 1 jika blok 8x8 lebih terang dari rata-rata gambar
+func averageHash(path string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return 0, err
+	}
+
+	b := img.Bounds()
+	var blocks [64]float64
+	var counts [64]int
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			bx := (x - b.Min.X) * 8 / b.Dx()
+			by := (y - b.Min.Y) * 8 / b.Dy()
+			i := by*8 + bx
+			blocks[i] += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)
+			counts[i]++
+		}
+	}
+
+	var total float64
+	for i := range blocks {
+		if counts[i] > 0 {
+			blocks[i] /= float64(counts[i])
+		}
+		total += blocks[i]
+	}
+	mean := total / 64
+
+	var hash uint64
+	for i, v := range blocks {
+		if v > mean {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash, nil
+}
@@ -0,0 +1,100 @@
+package faceverify
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeHalfImage: PNG 16x16, separuh kiri terang (atau gelap jika inverted)
+func writeHalfImage(t *testing.T, path string, inverted bool) {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			light := x < 8
+			if inverted {
+				light = !light
+			}
+			if light {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create %s: %v", path, err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatalf("encode %s: %v", path, err)
+	}
+}
+
+func TestStubCompare(t *testing.T) {
+	dir := t.TempDir()
+	ref := filepath.Join(dir, "ref.png")
+	same := filepath.Join(dir, "same.png")
+	inverted := filepath.Join(dir, "inverted.png")
+	writeHalfImage(t, ref, false)
+	writeHalfImage(t, same, false)
+	writeHalfImage(t, inverted, true)
+
+	cases := []struct {
+		name, selfie, reference string
+		want                    float64
+		wantErr                 error
+	}{
+		{"SameImage", same, ref, 1, nil},
+		{"InvertedImage", inverted, ref, 0, nil},
+		{"NoReference", same, "", 0, ErrNoReference},
+		{"MissingReferenceFile", same, filepath.Join(dir, "tidak-ada.png"), 0, ErrNoReference},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			score, err := Stub{}.Compare(tc.selfie, tc.reference)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil || score != tc.want {
+				t.Fatalf("score = %v, err = %v, want %v", score, err, tc.want)
+			}
+		})
+	}
+
+	// Selfie tidak terbaca bukan masalah referensi
+	if _, err := (Stub{}).Compare(filepath.Join(dir, "tidak-ada.png"), ref); err == nil || errors.Is(err, ErrNoReference) {
+		t.Errorf("selfie hilang: err = %v, want error selain ErrNoReference", err)
+	}
+
+	// Deterministik: hasil sama untuk input yang sama
+	first, _ := Stub{}.Compare(inverted, ref)
+	second, _ := Stub{}.Compare(inverted, ref)
+	if first != second {
+		t.Errorf("skor tidak deterministik: %v vs %v", first, second)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	cases := []struct {
+		score, threshold float64
+		want             string
+	}{
+		{0.9, 0.8, StatusLolos},
+		{0.8, 0.8, StatusLolos}, // Tepat di ambang -> lolos
+		{0.79, 0.8, StatusGagal},
+		{0, 0.8, StatusGagal},
+	}
+	for _, tc := range cases {
+		if got := Evaluate(tc.score, tc.threshold); got != tc.want {
+			t.Errorf("Evaluate(%v, %v) = %s, want %s", tc.score, tc.threshold, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package faceverify
+
+import (
+	"errors"
+	"my-flutter-backend/config"
+	"strings"
+)
+
+// FaceVerifier: Pembanding wajah selfie absen dengan foto referensi pegawai (layanan eksternal, model lokal, dst).
+type FaceVerifier interface {
+	Name() string
+	// Compare: Skor kemiripan 0..1 (1 = identik). ErrNoReference jika foto referensi tidak bisa dipakai,
+	// ErrUnavailable jika layanan tidak bisa dihubungi.
+	Compare(selfiePath, referencePath string) (float64, error)
+}
+
+var (
+	ErrNoReference = errors.New("foto referensi tidak tersedia untuk verifikasi wajah")
+	ErrUnavailable = errors.New("layanan verifikasi wajah tidak tersedia")
+)
+
+const (
+	VerifierStub = "stub"
+)
+
+// Status hasil verifikasi yang disimpan di kehadiran
+const (
+	StatusLolos = "LOLOS"
+	StatusGagal = "GAGAL" // Skor di bawah ambang lolos -> masuk antrean review admin
+	StatusError = "ERROR" // Tidak bisa diverifikasi (tanpa foto referensi / layanan mati) -> masuk antrean review admin
+)
+
+// FromEnv: Verifier server dari FACE_VERIFIER. Kosong/"none" = fitur verifikasi wajah tidak tersedia.
+func FromEnv() FaceVerifier {
+	switch strings.ToLower(strings.TrimSpace(config.GetEnv("FACE_VERIFIER", ""))) {
+	case VerifierStub:
+		return Stub{}
+	default:
+		return nil
+	}
+}
+
+// Enabled: Verifikasi wajah hanya bisa diaktifkan organisasi jika server punya verifier
+func Enabled() bool {
+	return FromEnv() != nil
+}
+
+// Evaluate: Status dari skor sesuai ambang organisasi
+func Evaluate(score, threshold float64) string {
+	if score >= threshold {
+		return StatusLolos
+	}
+	return StatusGagal
+}
@@ -0,0 +1,165 @@
+package geofence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// --- GEOFENCE POLYGON (GeoJSON) ---
+// Lokasi boleh memakai batas area berbentuk GeoJSON Polygon / MultiPolygon (koordinat [longitude, latitude]),
+// untuk area memanjang/tidak beraturan yang tidak pas dengan titik tengah + radius.
+
+const maxVertices = 5000
+
+// Ring: Cincin tertutup [[lng, lat], ...]. Polygon: ring[0] batas luar, sisanya lubang (area yang dikecualikan).
+type Ring [][2]float64
+type Polygon []Ring
+
+// Geometry: Polygon atau MultiPolygon yang sudah tervalidasi
+type Geometry struct {
+	Polygons []Polygon
+}
+
+type rawGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    *rawGeometry    `json:"geometry"` // Jika dikirim sebagai Feature
+}
+
+// Parse: Validasi GeoJSON (Polygon, MultiPolygon, atau Feature berisi salah satunya)
+func Parse(data []byte) (*Geometry, error) {
+	var raw rawGeometry
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, errors.New("geofence harus berupa GeoJSON yang valid")
+	}
+	if raw.Type == "Feature" {
+		if raw.Geometry == nil {
+			return nil, errors.New("Feature GeoJSON tidak memiliki geometry")
+		}
+		raw = *raw.Geometry
+	}
+
+	var polygons []Polygon
+	switch raw.Type {
+	case "Polygon":
+		var p Polygon
+		if err := json.Unmarshal(raw.Coordinates, &p); err != nil {
+			return nil, errors.New("coordinates Polygon tidak valid")
+		}
+		polygons = []Polygon{p}
+	case "MultiPolygon":
+		if err := json.Unmarshal(raw.Coordinates, &polygons); err != nil {
+			return nil, errors.New("coordinates MultiPolygon tidak valid")
+		}
+	default:
+		return nil, fmt.Errorf("tipe geofence %q tidak didukung (hanya Polygon atau MultiPolygon)", raw.Type)
+	}
+
+	if len(polygons) == 0 {
+		return nil, errors.New("geofence tidak memiliki polygon")
+	}
+	vertices := 0
+	for i, p := range polygons {
+		if len(p) == 0 {
+			return nil, fmt.Errorf("polygon ke-%d tidak memiliki ring", i+1)
+		}
+		for j, ring := range p {
+			if len(ring) < 4 {
+				return nil, fmt.Errorf("ring ke-%d polygon ke-%d minimal 4 titik (titik pertama diulang di akhir)", j+1, i+1)
+			}
+			if ring[0] != ring[len(ring)-1] {
+				return nil, fmt.Errorf("ring ke-%d polygon ke-%d harus tertutup (titik terakhir = titik pertama)", j+1, i+1)
+			}
+			for _, pt := range ring {
+				if pt[0] < -180 || pt[0] > 180 || pt[1] < -90 || pt[1] > 90 {
+					return nil, fmt.Errorf("koordinat [%v, %v] di luar jangkauan (format [longitude, latitude])", pt[0], pt[1])
+				}
+			}
+			vertices += len(ring)
+		}
+	}
+	if vertices > maxVertices {
+		return nil, fmt.Errorf("geofence maksimal %d titik", maxVertices)
+	}
+	return &Geometry{Polygons: polygons}, nil
+}
+
+// JSON: Bentuk GeoJSON geometry yang disimpan (Feature dinormalisasi jadi geometry)
+func (g *Geometry) JSON() ([]byte, error) {
+	if len(g.Polygons) == 1 {
+		return json.Marshal(map[string]interface{}{"type": "Polygon", "coordinates": g.Polygons[0]})
+	}
+	return json.Marshal(map[string]interface{}{"type": "MultiPolygon", "coordinates": g.Polygons})
+}
+
+// Contains: Titik berada di dalam salah satu polygon (dan tidak di dalam lubangnya)
+func (g *Geometry) Contains(lat, lng float64) bool {
+	for _, p := range g.Polygons {
+		if !ringContains(p[0], lat, lng) {
+			continue
+		}
+		inHole := false
+		for _, hole := range p[1:] {
+			if ringContains(hole, lat, lng) {
+				inHole = true
+				break
+			}
+		}
+		if !inHole {
+			return true
+		}
+	}
+	return false
+}
+
+// DistanceMeter: 0 jika di dalam area, selain itu jarak (meter, perkiraan) ke tepi terdekat
+func (g *Geometry) DistanceMeter(lat, lng float64) float64 {
+	if g.Contains(lat, lng) {
+		return 0
+	}
+	min := math.MaxFloat64
+	for _, p := range g.Polygons {
+		for _, ring := range p {
+			for i := 0; i+1 < len(ring); i++ {
+				if d := segmentDistance(lat, lng, ring[i], ring[i+1]); d < min {
+					min = d
+				}
+			}
+		}
+	}
+	return min
+}
+
+// ringContains: Ray casting (even-odd) di bidang lng/lat, cukup akurat untuk area seukuran kompleks kantor
+func ringContains(ring Ring, lat, lng float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > lat) != (yj > lat) && lng < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// segmentDistance: Jarak titik ke segmen a-b dengan proyeksi equirectangular lokal (meter)
+func segmentDistance(lat, lng float64, a, b [2]float64) float64 {
+	const R = 6371000
+	cosLat := math.Cos(lat * math.Pi / 180)
+	toXY := func(pLng, pLat float64) (float64, float64) {
+		return (pLng - lng) * math.Pi / 180 * R * cosLat, (pLat - lat) * math.Pi / 180 * R
+	}
+	ax, ay := toXY(a[0], a[1])
+	bx, by := toXY(b[0], b[1])
+
+	dx, dy := bx-ax, by-ay
+	t := 0.0
+	if l2 := dx*dx + dy*dy; l2 > 0 {
+		t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/l2))
+	}
+	px, py := ax+t*dx, ay+t*dy
+	return math.Hypot(px, py)
+}
@@ -1,17 +1,23 @@
 package handler
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"math/rand"
+	"math/big"
 	"my-flutter-backend/config"
+	"my-flutter-backend/internal/middleware"
 	"my-flutter-backend/internal/model"
+	"my-flutter-backend/internal/oidc"
+	"my-flutter-backend/internal/passwordauth"
 	"my-flutter-backend/internal/repository"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -21,11 +27,20 @@ import (
 )
 
 type ASNHandler struct {
-	repo repository.ASNRepository
+	repo          repository.ASNRepository
+	sessionRepo   repository.SessionRepository
+	otpRepo       repository.OTPRepository
+	throttleRepo  repository.LoginThrottleRepository
+	twoFactorRepo repository.TwoFactorRepository
+	ssoRepo       repository.SSORepository
+	deviceRepo    repository.DeviceRepository
+	inviteRepo    repository.InvitationRepository
+	roleRepo      repository.RoleRepository
+	sso           oidc.Provider // nil jika SSO belum dikonfigurasi
 }
 
-func NewASNHandler(repo repository.ASNRepository) *ASNHandler {
-	return &ASNHandler{repo: repo}
+func NewASNHandler(repo repository.ASNRepository, sessionRepo repository.SessionRepository, otpRepo repository.OTPRepository, throttleRepo repository.LoginThrottleRepository, twoFactorRepo repository.TwoFactorRepository, ssoRepo repository.SSORepository, deviceRepo repository.DeviceRepository, inviteRepo repository.InvitationRepository, roleRepo repository.RoleRepository, sso oidc.Provider) *ASNHandler {
+	return &ASNHandler{repo: repo, sessionRepo: sessionRepo, otpRepo: otpRepo, throttleRepo: throttleRepo, twoFactorRepo: twoFactorRepo, ssoRepo: ssoRepo, deviceRepo: deviceRepo, inviteRepo: inviteRepo, roleRepo: roleRepo, sso: sso}
 }
 
 type LoginRequest struct {
@@ -43,59 +58,87 @@ func (h *ASNHandler) Login(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format data salah"})
 	}
 
+	// 0. Cek Kunci Brute Force (per NIP & per IP)
+	if lockedUntil := h.loginLockedUntil(req.NIP, c.IP()); lockedUntil != nil {
+		return loginLockedResponse(c, *lockedUntil)
+	}
+
 	// 1. Cari ASN by NIP
 	asn, err := h.repo.FindByNIP(req.NIP)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "NIP atau Password salah"})
+		return h.loginFailed(c, req.NIP, false, "NIP atau Password salah")
 	}
 
 	// Cek Status Aktif
 	if !asn.IsActive {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Akun Anda dinonaktifkan. Silakan hubungi Admin."})
 	}
+	if asn.ActivationPending {
+		return activationPendingResponse(c)
+	}
 
-	// 2. Cek Password
-	err = bcrypt.CompareHashAndPassword([]byte(asn.Password), []byte(req.Password))
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "NIP atau Password salah"})
+	// 2. Cek Password (lokal / LDAP sesuai urutan backend organisasi)
+	if err := passwordauth.Verify(asn, req.Password); err != nil {
+		if errors.Is(err, passwordauth.ErrUnavailable) {
+			return passwordBackendUnavailable(c, err)
+		}
+		return h.loginFailed(c, req.NIP, true, "NIP atau Password salah")
+	}
+	h.throttleRepo.Reset(loginScopeNIP, req.NIP) // Login berhasil -> reset penghitung gagal NIP
+
+	// Organisasi SSO-only: Login password ditolak
+	if ssoRequired(asn) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Organisasi Anda mewajibkan login melalui SSO", "sso_required": true})
 	}
 
 	// 3. Cek Device Binding (Logika Keamanan)
-	if req.DeviceID != "" {
-		// Cek apakah akun ini sudah punya device terdaftar
-		if len(asn.Devices) > 0 {
-			isRegistered := false
-			for _, d := range asn.Devices {
-				if d.UUID == req.DeviceID {
-					isRegistered = true
-					break
-				}
-			}
-			if !isRegistered {
-				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-					"error": "Akun ini terkunci pada perangkat lain. Hubungi admin untuk reset.",
-				})
-			}
-		} else {
-			// Jika belum punya device, daftarkan device ini (Binding Pertama Kali)
-			newDevice := model.Device{
-				ASNID:         asn.ID,
-				UUID:          req.DeviceID,
-				Brand:         req.Brand,
-				Series:        req.Series,
-				FirebaseToken: req.FirebaseToken,
-			}
-			if err := h.repo.AddDevice(&newDevice); err != nil {
-				// Kemungkinan error: UUID sudah dipakai user lain (karena unique)
-				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-					"error": "Perangkat ini sudah digunakan oleh akun lain.",
-				})
-			}
+	deviceID, rejected := h.bindDevice(asn, req.DeviceID, req.Brand, req.Series, req.FirebaseToken)
+	if rejected != nil {
+		return c.Status(fiber.StatusForbidden).JSON(rejected)
+	}
+
+	// 4. Buat Session & Return Token ke Client
+	return h.completeMobileLogin(c, asn, deviceID)
+}
+
+// bindDevice: Device binding login mobile. Device baru otomatis didaftarkan selama jumlahnya belum mencapai
+// batas organisasi (max_devices); selebihnya login ditolak dan pegawai bisa mengajukan ganti device.
+// Mengembalikan body respons 403 jika ditolak.
+func (h *ASNHandler) bindDevice(asn *model.ASN, uuid, brand, series, firebaseToken string) (*uint, fiber.Map) {
+	if uuid == "" {
+		return nil, nil
+	}
+
+	// Device sudah terdaftar di akun ini
+	for i := range asn.Devices {
+		if asn.Devices[i].UUID == uuid {
+			return &asn.Devices[i].ID, nil
 		}
 	}
 
-	// 4. Generate Token JWT
-	accessToken, refreshToken, err := generateTokens(asn)
+	// Batas device penuh -> harus lewat permintaan ganti device
+	if len(asn.Devices) >= maxDevices(&asn.Organisasi) {
+		return nil, h.deviceChangeRejection(asn, uuid, brand, series, firebaseToken)
+	}
+
+	// Masih ada slot, daftarkan device ini
+	newDevice := model.Device{
+		ASNID:         asn.ID,
+		UUID:          uuid,
+		Brand:         brand,
+		Series:        series,
+		FirebaseToken: firebaseToken,
+	}
+	if err := h.deviceRepo.Bind(&newDevice, "LOGIN", nil); err != nil {
+		// Kemungkinan error: UUID sudah dipakai user lain (karena unique)
+		return nil, fiber.Map{"error": "Perangkat ini sudah digunakan oleh akun lain."}
+	}
+	return &newDevice.ID, nil
+}
+
+// completeMobileLogin: Buat session MOBILE, generate token JWT, lalu kirim data user (dipakai Login & SSO)
+func (h *ASNHandler) completeMobileLogin(c *fiber.Ctx, asn *model.ASN, deviceID *uint) error {
+	accessToken, refreshToken, err := h.startSession(c, asn, deviceID, "MOBILE")
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat token"})
 	}
@@ -111,11 +154,16 @@ func (h *ASNHandler) Login(c *fiber.Ctx) error {
 		nipAtasan = asn.Atasan.NIP
 	}
 
-	// 4. Return Token ke Client
+	mustChangePassword, passwordExpired := passwordChangeRequired(asn)
+
+	// Return Token ke Client
 	return c.JSON(fiber.Map{
-		"message":       "Login berhasil",
-		"token":         accessToken,  // Access Token (15 Menit)
-		"refresh_token": refreshToken, // Refresh Token (7 Hari)
+		"message":              "Login berhasil",
+		"token":                accessToken,        // Access Token (15 Menit)
+		"refresh_token":        refreshToken,       // Refresh Token (7 Hari)
+		"must_change_password": mustChangePassword, // App wajib menampilkan layar ganti password
+		"password_expired":     passwordExpired,
+		"admin_butuh_2fa":      requiresTwoFactor(asn), // Fitur admin hanya lewat login web + 2FA
 		"data": fiber.Map{
 			"nip":         asn.NIP,
 			"nama":        asn.Nama,
@@ -138,27 +186,55 @@ func (h *ASNHandler) WebLogin(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format data salah"})
 	}
 
+	// 0. Cek Kunci Brute Force (per NIP & per IP)
+	if lockedUntil := h.loginLockedUntil(req.NIP, c.IP()); lockedUntil != nil {
+		return loginLockedResponse(c, *lockedUntil)
+	}
+
 	// 1. Cari ASN by NIP
 	asn, err := h.repo.FindByNIP(req.NIP)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "NIP atau Password salah"})
+		return h.loginFailed(c, req.NIP, false, "NIP atau Password salah")
 	}
 
 	// Cek Status Aktif
 	if !asn.IsActive {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Akun Anda dinonaktifkan. Silakan hubungi Admin."})
 	}
+	if asn.ActivationPending {
+		return activationPendingResponse(c)
+	}
 
-	// 2. Cek Password
-	err = bcrypt.CompareHashAndPassword([]byte(asn.Password), []byte(req.Password))
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "NIP atau Password salah"})
+	// 2. Cek Password (lokal / LDAP sesuai urutan backend organisasi)
+	if err := passwordauth.Verify(asn, req.Password); err != nil {
+		if errors.Is(err, passwordauth.ErrUnavailable) {
+			return passwordBackendUnavailable(c, err)
+		}
+		return h.loginFailed(c, req.NIP, true, "NIP atau Password salah")
+	}
+	h.throttleRepo.Reset(loginScopeNIP, req.NIP) // Login berhasil -> reset penghitung gagal NIP
+
+	// Organisasi SSO-only: Login password ditolak
+	if ssoRequired(asn) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Organisasi Anda mewajibkan login melalui SSO", "sso_required": true})
 	}
 
 	// 3. SKIP Cek Device Binding (Khusus Web)
 
-	// 4. Generate Token JWT
-	accessToken, refreshToken, err := generateTokens(asn)
+	// 4. Cek 2FA (TOTP): Token baru diterbitkan setelah faktor kedua diverifikasi
+	tf, err := h.twoFactorRepo.GetByASN(asn.ID)
+	enrolled := err == nil && tf.ConfirmedAt != nil
+	if enrolled || requiresTwoFactor(asn) {
+		return h.twoFactorChallenge(c, asn, enrolled)
+	}
+
+	// 5. Buat Session & Return Token ke Client
+	return h.completeWebLogin(c, asn, nil)
+}
+
+// completeWebLogin: Buat session WEB, generate token JWT, lalu kirim data user (dipakai WebLogin & langkah 2FA)
+func (h *ASNHandler) completeWebLogin(c *fiber.Ctx, asn *model.ASN, extra fiber.Map) error {
+	accessToken, refreshToken, err := h.startSession(c, asn, nil, "WEB")
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat token"})
 	}
@@ -174,11 +250,14 @@ func (h *ASNHandler) WebLogin(c *fiber.Ctx) error {
 		nipAtasan = asn.Atasan.NIP
 	}
 
-	// 5. Return Token ke Client
-	return c.JSON(fiber.Map{
-		"message":       "Login berhasil",
-		"token":         accessToken,  // Access Token (15 Menit)
-		"refresh_token": refreshToken, // Refresh Token (7 Hari)
+	mustChangePassword, passwordExpired := passwordChangeRequired(asn)
+
+	response := fiber.Map{
+		"message":              "Login berhasil",
+		"token":                accessToken,  // Access Token (15 Menit)
+		"refresh_token":        refreshToken, // Refresh Token (7 Hari)
+		"must_change_password": mustChangePassword,
+		"password_expired":     passwordExpired,
 		"data": fiber.Map{
 			"nip":         asn.NIP,
 			"nama":        asn.Nama,
@@ -191,7 +270,11 @@ func (h *ASNHandler) WebLogin(c *fiber.Ctx) error {
 			"nip_atasan":  nipAtasan,
 			"foto":        asn.Foto,
 		},
-	})
+	}
+	for key, value := range extra {
+		response[key] = value
+	}
+	return c.JSON(response)
 }
 
 type RefreshTokenRequest struct {
@@ -205,35 +288,63 @@ func (h *ASNHandler) RefreshToken(c *fiber.Ctx) error {
 	}
 
 	// Parse & Validasi Refresh Token
-	token, err := jwt.Parse(req.RefreshToken, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fiber.ErrUnauthorized
-		}
-		return []byte("rahasia_negara"), nil
-	})
+	token, err := config.JWT.Parse(req.RefreshToken)
 
 	if err != nil || !token.Valid {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Refresh token tidak valid atau kadaluwarsa"})
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
+	if !ok || claims["type"] != "refresh" {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token claims invalid"})
 	}
 
-	// Ambil User ID dari claims refresh token
-	userIDFloat, ok := claims["user_id"].(float64)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token invalid (user_id)"})
+	// Refresh token lama (sebelum ada session) tidak punya sid/jti -> wajib login ulang
+	sid, okSID := claims["sid"].(float64)
+	jti, okJTI := claims["jti"].(string)
+	if !okSID || !okJTI {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Sesi telah berakhir, silakan login kembali"})
 	}
-	userID := uint(userIDFloat)
-	asn, err := h.repo.FindByID(userID)
+
+	// 1. Cocokkan dengan token yang tersimpan di server
+	stored, err := h.sessionRepo.GetRefreshTokenByHash(hashToken(jti))
+	if err != nil || stored.SessionID != uint(sid) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Refresh token tidak dikenal"})
+	}
+
+	session, err := h.sessionRepo.GetByID(stored.SessionID)
+	if err != nil || session.RevokedAt != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Sesi telah berakhir, silakan login kembali"})
+	}
+
+	// 2. Reuse Detection: token yang sudah dirotasi dipakai lagi -> cabut seluruh session
+	fresh, err := h.sessionRepo.MarkRefreshTokenUsed(stored.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memvalidasi refresh token"})
+	}
+	if !fresh {
+		h.sessionRepo.Revoke(session.ID, "REUSE_DETECTED")
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Refresh token sudah pernah digunakan. Demi keamanan sesi ini dicabut, silakan login kembali."})
+	}
+
+	// 3. Cek status akun & perubahan password
+	asn, err := h.repo.FindByID(session.ASNID)
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User tidak ditemukan"})
 	}
+	if !asn.IsActive {
+		h.sessionRepo.Revoke(session.ID, "NONAKTIF")
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Akun Anda dinonaktifkan. Silakan hubungi Admin."})
+	}
+	if asn.PasswordChangedAt != nil && session.CreatedAt.Before(*asn.PasswordChangedAt) {
+		h.sessionRepo.Revoke(session.ID, "PASSWORD_CHANGED")
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Password telah diubah, silakan login kembali"})
+	}
 
-	// Generate Token Baru
-	newAccessToken, newRefreshToken, err := generateTokens(asn)
+	// 4. Rotasi: terbitkan pasangan token baru di session yang sama
+	session.IPAddress = c.IP()
+	session.UserAgent = c.Get("User-Agent")
+	newAccessToken, newRefreshToken, err := h.issueTokens(session, asn)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal generate token"})
 	}
@@ -312,8 +423,12 @@ func (h *ASNHandler) UpdateProfile(c *fiber.Ctx) error {
 				}
 			}
 		} else {
-			// Jika bukan base64, asumsikan ini URL atau Path yang sudah valid (atau update string biasa)
-			asn.Foto = req.Foto
+			// Jika bukan base64, hanya path hasil upload (uploads/profile/...) yang diterima
+			path, ok := profilePhotoPath(req.Foto)
+			if !ok {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Foto harus berupa gambar base64 atau path uploads/profile"})
+			}
+			asn.Foto = path
 		}
 	}
 
@@ -340,28 +455,53 @@ func (h *ASNHandler) ChangePassword(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
 	}
 
-	asn, err := h.repo.FindByID(asnID)
+	asn, err := h.repo.FindByIDWithOrganisasi(asnID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User tidak ditemukan"})
 	}
 
+	// Password dikelola direktori organisasi (LDAP) -> ganti di sana
+	if !passwordauth.LocalManaged(asn) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Password akun Anda dikelola direktori organisasi. Silakan ganti password melalui direktori tersebut."})
+	}
+
 	// Cek Password Lama
 	if err := bcrypt.CompareHashAndPassword([]byte(asn.Password), []byte(req.OldPassword)); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Password lama salah"})
 	}
 
-	// Hash Password Baru
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	// Validasi (kebijakan organisasi & riwayat password) lalu Hash Password Baru
+	hashedPassword, err := h.newPasswordHash(&asn.Organisasi, asn, req.NewPassword)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengenkripsi password"})
+		return passwordPolicyResponse(c, err)
 	}
 
-	asn.Password = string(hashedPassword)
-	if err := h.repo.Update(asn); err != nil {
+	if err := h.repo.SetPassword(asn, hashedPassword, false); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal update password"})
 	}
 
-	return c.JSON(fiber.Map{"message": "Password berhasil diubah"})
+	// Cabut semua sesi lama (termasuk yang mungkin dicuri), lalu buat sesi baru untuk perangkat ini
+	h.sessionRepo.RevokeAllByASN(asn.ID, "PASSWORD_CHANGED")
+
+	var deviceID *uint
+	client := "MOBILE"
+	if sid, ok := c.Locals("session_id").(float64); ok {
+		if current, err := h.sessionRepo.GetByID(uint(sid)); err == nil {
+			deviceID = current.DeviceID
+			client = current.Client
+		}
+	}
+
+	accessToken, refreshToken, err := h.startSession(c, asn, deviceID, client)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Password diubah, tetapi gagal membuat sesi baru. Silakan login kembali."})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":       "Password berhasil diubah",
+		"token":         accessToken,
+		"refresh_token": refreshToken,
+	})
 }
 
 func (h *ASNHandler) GetAll(c *fiber.Ctx) error {
@@ -386,9 +526,10 @@ func (h *ASNHandler) GetAll(c *fiber.Ctx) error {
 }
 
 func (h *ASNHandler) GetASNDetail(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
 	id, _ := strconv.Atoi(c.Params("id"))
 
-	asn, err := h.repo.FindByID(uint(id))
+	asn, err := h.repo.FindByIDInOrg(uint(id), orgID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pegawai tidak ditemukan"})
 	}
@@ -427,33 +568,29 @@ func (h *ASNHandler) CreateASN(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
 	}
 
-	// 1. Cek Permission User yang Login
+	// 1. Ambil User yang Login
 	userID := uint(c.Locals("user_id").(float64))
 	currentUser, err := h.repo.FindByID(userID)
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User tidak ditemukan"})
 	}
 
-	canManageOrg := false
-	for _, p := range currentUser.Role.Permissions {
-		if p.NamaPermission == "kelola_organisasi" {
-			canManageOrg = true
-			break
-		}
-	}
-
-	// 2. Tentukan Organisasi ID
+	// 2. Tentukan Organisasi ID (pemegang kelola_organisasi / superuser boleh membuat pegawai di organisasi lain)
 	orgID := currentUser.OrganisasiID
-	if canManageOrg && req.OrganisasiID != 0 {
+	if req.OrganisasiID != 0 && middleware.HasPermission(c, "kelola_organisasi") {
 		orgID = req.OrganisasiID
 	}
 
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-
+	org, err := h.repo.GetOrganisasiByID(orgID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Organisasi tidak ditemukan"})
+	}
+	if status, msg := checkAssignableRole(c, h.roleRepo, req.RoleID); msg != "" {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
 	asn := model.ASN{
 		Nama:         toTitleCase(req.Nama), // Standardize Name
 		NIP:          req.NIP,
-		Password:     string(hashedPassword),
 		Jabatan:      req.Jabatan,
 		Bidang:       req.Bidang,
 		RoleID:       req.RoleID,
@@ -463,11 +600,46 @@ func (h *ASNHandler) CreateASN(c *fiber.Ctx) error {
 		NoHP:         req.NoHP,
 	}
 
+	// Password kosong -> Akun undangan, pegawai membuat password sendiri lewat link aktivasi
+	invite := req.Password == ""
+	if invite {
+		if msg := invitationUnsupported(org, req.Email); msg != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+		}
+		asn.ActivationPending = true
+	} else {
+		hashedPassword, err := h.newPasswordHash(org, &asn, req.Password)
+		if err != nil {
+			return passwordPolicyResponse(c, err)
+		}
+		asn.Password = hashedPassword
+		asn.MustChangePassword = true // Password awal dari admin, wajib diganti saat login pertama
+	}
+
 	if err := h.repo.Create(&asn); err != nil {
 		// Tampilkan error asli agar ketahuan penyebabnya (misal: Duplicate NIP)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"message": "Pegawai berhasil ditambahkan", "data": asn})
+	middleware.SetAuditEntityID(c, asn.ID)
+
+	if !invite {
+		h.repo.AddPasswordHistory(asn.ID, asn.Password)
+		return c.JSON(fiber.Map{"message": "Pegawai berhasil ditambahkan", "data": asn})
+	}
+	inv, err := h.sendInvitation(&asn, &userID, inviteTTL())
+	if err != nil {
+		return c.JSON(fiber.Map{
+			"message":           "Pegawai berhasil ditambahkan, tetapi email undangan aktivasi gagal dikirim. Silakan kirim ulang undangan.",
+			"data":              asn,
+			"undangan_terkirim": false,
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message":           "Pegawai berhasil ditambahkan. Undangan aktivasi telah dikirim ke " + asn.Email,
+		"data":              asn,
+		"undangan_terkirim": true,
+		"undangan_berlaku":  inv.ExpiresAt,
+	})
 }
 
 func (h *ASNHandler) ImportASN(c *fiber.Ctx) error {
@@ -477,19 +649,28 @@ func (h *ASNHandler) ImportASN(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format data tidak valid"})
 	}
 
-	successCount := 0
+	org, err := h.repo.GetOrganisasiByID(orgID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil kebijakan password organisasi"})
+	}
+
+	actorID := uint(c.Locals("user_id").(float64))
+	successCount, invitedCount := 0, 0
+	failed := []fiber.Map{}       // Baris yang dilewati beserta alasannya
+	inviteFailed := []fiber.Map{} // Akun dibuat, tetapi email undangan gagal dikirim
 	for _, req := range reqs {
 		// Skip jika NIP kosong
 		if req.NIP == "" {
 			continue
 		}
-
-		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if _, msg := checkAssignableRole(c, h.roleRepo, req.RoleID); msg != "" {
+			failed = append(failed, fiber.Map{"nip": req.NIP, "error": msg})
+			continue
+		}
 
 		asn := model.ASN{
 			Nama:         toTitleCase(req.Nama), // Standardize Name
 			NIP:          req.NIP,
-			Password:     string(hashedPassword),
 			Jabatan:      req.Jabatan,
 			Bidang:       req.Bidang,
 			RoleID:       req.RoleID,
@@ -499,26 +680,72 @@ func (h *ASNHandler) ImportASN(c *fiber.Ctx) error {
 			NoHP:         req.NoHP,
 		}
 
-		// Abaikan error duplicate entry agar proses tetap lanjut untuk data lain
-		if err := h.repo.Create(&asn); err == nil {
-			successCount++
+		// Baris tanpa password -> Akun undangan
+		invite := req.Password == ""
+		if invite {
+			if msg := invitationUnsupported(org, req.Email); msg != "" {
+				failed = append(failed, fiber.Map{"nip": req.NIP, "error": msg})
+				continue
+			}
+			asn.ActivationPending = true
+		} else {
+			hashedPassword, err := h.newPasswordHash(org, &asn, req.Password)
+			if err != nil {
+				failed = append(failed, fiber.Map{"nip": req.NIP, "error": err.Error()})
+				continue
+			}
+			asn.Password = hashedPassword
+			asn.MustChangePassword = true
+		}
+
+		// Lewati error duplicate entry agar proses tetap lanjut untuk data lain
+		if err := h.repo.Create(&asn); err != nil {
+			failed = append(failed, fiber.Map{"nip": req.NIP, "error": "NIP sudah terdaftar atau data tidak valid"})
+			continue
 		}
+		successCount++
+
+		if !invite {
+			h.repo.AddPasswordHistory(asn.ID, asn.Password)
+			continue
+		}
+		if _, err := h.sendInvitation(&asn, &actorID, inviteTTL()); err != nil {
+			inviteFailed = append(inviteFailed, fiber.Map{"nip": asn.NIP, "email": asn.Email})
+			continue
+		}
+		invitedCount++
 	}
 
-	return c.JSON(fiber.Map{"message": fmt.Sprintf("Berhasil mengimport %d data pegawai", successCount)})
+	return c.JSON(fiber.Map{
+		"message":        fmt.Sprintf("Berhasil mengimport %d data pegawai (%d undangan aktivasi terkirim)", successCount, invitedCount),
+		"gagal":          failed,
+		"undangan_gagal": inviteFailed,
+	})
 }
 
 func (h *ASNHandler) UpdateASN(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
 	id, _ := strconv.Atoi(c.Params("id"))
 	var req model.ASN
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
 	}
 
-	asn, err := h.repo.FindByID(uint(id))
+	asn, err := h.repo.FindByIDInOrg(uint(id), orgID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pegawai tidak ditemukan"})
 	}
+	if req.RoleID != asn.RoleID || req.Email != asn.Email {
+		// Ganti role/email akun yang lebih tinggi = jalan pengambilalihan akun (lupa password ke email baru)
+		if status, msg := checkManageableASN(c, h.roleRepo, asn); msg != "" {
+			return c.Status(status).JSON(fiber.Map{"error": msg})
+		}
+	}
+	if req.RoleID != asn.RoleID {
+		if status, msg := checkAssignableRole(c, h.roleRepo, req.RoleID); msg != "" {
+			return c.Status(status).JSON(fiber.Map{"error": msg})
+		}
+	}
 
 	asn.Nama = toTitleCase(req.Nama) // Standardize Name
 
@@ -533,14 +760,22 @@ func (h *ASNHandler) UpdateASN(c *fiber.Ctx) error {
 	if err := h.repo.Update(asn); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal update pegawai"})
 	}
+	if !asn.IsActive {
+		h.sessionRepo.RevokeAllByASN(asn.ID, "NONAKTIF")
+	}
 	return c.JSON(fiber.Map{"message": "Data pegawai berhasil diupdate"})
 }
 
 func (h *ASNHandler) DeleteASN(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
 	id, _ := strconv.Atoi(c.Params("id"))
-	if err := h.repo.Delete(uint(id)); err != nil {
+	if _, err := h.repo.FindByIDInOrg(uint(id), orgID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pegawai tidak ditemukan"})
+	}
+	if err := h.repo.Delete(uint(id), orgID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus pegawai"})
 	}
+	h.sessionRepo.RevokeAllByASN(uint(id), "DIHAPUS")
 	return c.JSON(fiber.Map{"message": "Pegawai berhasil dihapus"})
 }
 
@@ -549,37 +784,48 @@ type ResetPasswordRequest struct {
 }
 
 func (h *ASNHandler) ResetUserPassword(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
 	id, _ := strconv.Atoi(c.Params("id"))
 	var req ResetPasswordRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
 	}
 
-	if len(req.NewPassword) < 6 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Password minimal 6 karakter"})
-	}
-
-	asn, err := h.repo.FindByID(uint(id))
+	asn, err := h.repo.FindByIDInOrg(uint(id), orgID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pegawai tidak ditemukan"})
 	}
+	if status, msg := checkManageableASN(c, h.roleRepo, asn); msg != "" {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	org, err := h.repo.GetOrganisasiByID(asn.OrganisasiID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil kebijakan password organisasi"})
+	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	hashedPassword, err := h.newPasswordHash(org, asn, req.NewPassword)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengenkripsi password"})
+		return passwordPolicyResponse(c, err)
 	}
 
-	asn.Password = string(hashedPassword)
-	if err := h.repo.Update(asn); err != nil {
+	// Password dari admin bersifat sementara -> pegawai wajib menggantinya saat login berikutnya
+	if err := h.repo.SetPassword(asn, hashedPassword, true); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal reset password"})
 	}
+	h.sessionRepo.RevokeAllByASN(asn.ID, "PASSWORD_CHANGED")
 
 	return c.JSON(fiber.Map{"message": "Password berhasil di-reset"})
 }
 
 func (h *ASNHandler) ResetDevice(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
 	id, _ := strconv.Atoi(c.Params("id"))
-	if err := h.repo.ResetDevice(uint(id)); err != nil {
+	asn, err := h.repo.FindByIDInOrg(uint(id), orgID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pegawai tidak ditemukan"})
+	}
+	actorID := uint(c.Locals("user_id").(float64))
+	if err := h.deviceRepo.UnbindAll(asn.ID, "RESET_ADMIN", &actorID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal reset device"})
 	}
 	return c.JSON(fiber.Map{"message": "Device berhasil di-reset, user bisa login di HP baru"})
@@ -628,6 +874,14 @@ func (h *ASNHandler) UpdateAtasan(c *fiber.Ctx) error {
 		if req.AtasanID == asnID {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Tidak bisa menjadikan diri sendiri sebagai atasan"})
 		}
+		// Validasi: Atasan harus pegawai aktif di organisasi yang sama dan berhak approve (sama seperti daftar /atasan-list)
+		atasan, err := h.repo.FindByIDInOrg(req.AtasanID, asn.OrganisasiID)
+		if err != nil || !atasan.IsActive {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Atasan tidak ditemukan di organisasi Anda"})
+		}
+		if !canApproveBawahan(atasan) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Pegawai tersebut tidak memiliki izin approve_cuti sehingga tidak bisa dijadikan atasan"})
+		}
 		atasanID := req.AtasanID
 		asn.AtasanID = &atasanID
 	} else {
@@ -645,45 +899,269 @@ func (h *ASNHandler) UpdateAtasan(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"message": "Atasan berhasil diperbarui"})
 }
 
+// canApproveBawahan: Role atasan punya approve_cuti (atau superuser)
+func canApproveBawahan(atasan *model.ASN) bool {
+	if atasan.Role.Superuser {
+		return true
+	}
+	for _, p := range atasan.Role.Permissions {
+		if p.NamaPermission == "approve_cuti" {
+			return true
+		}
+	}
+	return false
+}
+
+// JWKS: Public key untuk verifikasi token oleh service lain (kosong jika hanya memakai HS256)
+func (h *ASNHandler) JWKS(c *fiber.Ctx) error {
+	return c.JSON(config.JWT.JWKS())
+}
+
+const refreshTokenTTL = time.Hour * 24 * 7 // Refresh Token berlaku 7 Hari
+
+// startSession membuat session baru (satu family refresh token) lalu menerbitkan token pertamanya
+func (h *ASNHandler) startSession(c *fiber.Ctx, asn *model.ASN, deviceID *uint, client string) (string, string, error) {
+	session := model.Session{
+		ASNID:      asn.ID,
+		DeviceID:   deviceID,
+		Client:     client,
+		IPAddress:  c.IP(),
+		UserAgent:  c.Get("User-Agent"),
+		LastSeenAt: time.Now(),
+		ExpiresAt:  time.Now().Add(refreshTokenTTL),
+	}
+	if err := h.sessionRepo.Create(&session); err != nil {
+		return "", "", err
+	}
+	return h.issueTokens(&session, asn)
+}
+
+// issueTokens menerbitkan access + refresh token baru untuk session dan menyimpan hash refresh token-nya
+func (h *ASNHandler) issueTokens(session *model.Session, asn *model.ASN) (string, string, error) {
+	jti, err := generateRandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Session MOBILE tidak melewati 2FA: akun yang wajib 2FA tidak boleh memakai fitur admin dengan token ini
+	need2FA := session.Client == "MOBILE" && requiresTwoFactor(asn)
+	accessToken, refreshToken, err := generateTokens(asn, session.ID, jti, need2FA)
+	if err != nil {
+		return "", "", err
+	}
+
+	expiresAt := time.Now().Add(refreshTokenTTL)
+	if err := h.sessionRepo.CreateRefreshToken(&model.RefreshToken{
+		SessionID: session.ID,
+		TokenHash: hashToken(jti),
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return "", "", err
+	}
+
+	session.LastSeenAt = time.Now()
+	session.ExpiresAt = expiresAt
+	if err := h.sessionRepo.Update(session); err != nil {
+		return "", "", err
+	}
+
+	return accessToken, refreshToken, nil
+}
+
 // Helper function untuk membuat JWT
-func generateTokens(asn *model.ASN) (string, string, error) {
+func generateTokens(asn *model.ASN, sessionID uint, jti string, need2FA bool) (string, string, error) {
 	// 1. Access Token (15 Menit)
 	accessClaims := jwt.MapClaims{
 		"user_id":       asn.ID,
 		"nip":           asn.NIP,
 		"role":          asn.Role.NamaRole, // Tambahkan Role ke Token
+		"role_id":       asn.RoleID,        // Key cache permission di middleware.Permission
 		"organisasi_id": asn.OrganisasiID,
+		"sid":           sessionID,
 		"exp":           time.Now().Add(time.Minute * 15).Unix(), // Token berlaku 15 Menit
 	}
+	if need2FA {
+		accessClaims["need_2fa"] = true // Ditolak middleware.Permission (lihat issueTokens)
+	}
 
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	accessTokenString, err := accessToken.SignedString([]byte("rahasia_negara"))
+	accessTokenString, err := config.JWT.Sign(accessClaims)
 	if err != nil {
 		return "", "", err
 	}
 
-	// 2. Refresh Token (7 Hari)
+	// 2. Refresh Token (7 Hari), jti disimpan (dalam bentuk hash) di tabel refresh_tokens
 	refreshClaims := jwt.MapClaims{
 		"user_id": asn.ID,
-		"exp":     time.Now().Add(time.Hour * 24 * 7).Unix(), // Token berlaku 7 Hari
+		"sid":     sessionID,
+		"jti":     jti,
+		"type":    "refresh",
+		"exp":     time.Now().Add(refreshTokenTTL).Unix(),
 	}
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshTokenString, err := refreshToken.SignedString([]byte("rahasia_negara"))
+	refreshTokenString, err := config.JWT.Sign(refreshClaims)
 
-	// Pastikan key "rahasia_negara" ini SAMA dengan yang ada di auth_middleware.go
+	// Kunci aktif & kid diatur lewat JWT_KEYS / JWT_ACTIVE_KID (config/jwt.go)
 	return accessTokenString, refreshTokenString, err
 }
 
-// --- FITUR LUPA PASSWORD (OTP) ---
+// generateRandomToken menghasilkan string acak (hex) yang aman secara kriptografis
+func generateRandomToken(nBytes int) (string, error) {
+	b := make([]byte, nBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashToken: token rahasia hanya disimpan dalam bentuk SHA-256 di database
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
 
-var (
-	otpStore = make(map[string]OTPData)
-	otpMutex sync.Mutex
+// --- PROTEKSI BRUTE FORCE LOGIN ---
+
+const (
+	loginScopeNIP = "NIP"
+	loginScopeIP  = "IP"
 )
 
-type OTPData struct {
-	Code      string
-	ExpiresAt time.Time
+// Pengaturan lockout (bisa diubah lewat ENV)
+func loginMaxFailed(scope string) int {
+	if scope == loginScopeIP {
+		return config.GetEnvAsInt("LOGIN_MAX_FAILED_PER_IP", 20)
+	}
+	return config.GetEnvAsInt("LOGIN_MAX_FAILED_PER_NIP", 5)
+}
+
+func loginFailWindow() time.Duration {
+	return time.Duration(config.GetEnvAsInt("LOGIN_FAIL_WINDOW_MINUTES", 15)) * time.Minute
+}
+
+// loginLockDuration: Durasi kunci berlipat ganda setiap level (5, 10, 20, ... menit) sampai batas maksimal
+func loginLockDuration(level int) time.Duration {
+	d := time.Duration(config.GetEnvAsInt("LOGIN_LOCKOUT_BASE_MINUTES", 5)) * time.Minute
+	maxDuration := time.Duration(config.GetEnvAsInt("LOGIN_LOCKOUT_MAX_MINUTES", 24*60)) * time.Minute
+	for i := 1; i < level && d < maxDuration; i++ {
+		d *= 2
+	}
+	if d > maxDuration {
+		d = maxDuration
+	}
+	return d
+}
+
+// passwordBackendUnavailable: Direktori password organisasi tidak bisa dihubungi.
+// Tidak dihitung sebagai login gagal agar akun tidak terkunci saat server LDAP bermasalah.
+func passwordBackendUnavailable(c *fiber.Ctx, err error) error {
+	fmt.Println("Verifikasi password gagal:", err)
+	return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Layanan verifikasi password sedang tidak tersedia. Silakan coba beberapa saat lagi."})
+}
+
+// loginLockedUntil: Waktu kunci terlama yang masih aktif untuk NIP atau IP ini (nil jika tidak terkunci)
+func (h *ASNHandler) loginLockedUntil(nip, ip string) *time.Time {
+	var until *time.Time
+	for _, k := range [][2]string{{loginScopeNIP, nip}, {loginScopeIP, ip}} {
+		t, err := h.throttleRepo.Get(k[0], k[1])
+		if err != nil || t.LockedUntil == nil || !t.LockedUntil.After(time.Now()) {
+			continue
+		}
+		if until == nil || t.LockedUntil.After(*until) {
+			until = t.LockedUntil
+		}
+	}
+	return until
+}
+
+// loginFailed: Catat kegagalan login untuk NIP & IP, kunci jika melewati batas.
+// NIP yang tidak terdaftar (nipKnown=false) hanya dihitung per IP agar tebakan NIP acak tidak membuat baris throttle baru.
+func (h *ASNHandler) loginFailed(c *fiber.Ctx, nip string, nipKnown bool, message string) error {
+	ipLock, ipRemaining := h.registerLoginFailure(loginScopeIP, c.IP(), c.IP())
+
+	var nipLock *time.Time
+	remaining := min(ipRemaining, loginMaxFailed(loginScopeNIP)-1)
+	if nipKnown {
+		nipLock, remaining = h.registerLoginFailure(loginScopeNIP, nip, c.IP())
+	}
+
+	if nipLock != nil || ipLock != nil {
+		until := nipLock
+		if until == nil || (ipLock != nil && ipLock.After(*until)) {
+			until = ipLock
+		}
+		return loginLockedResponse(c, *until)
+	}
+
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"error":          message,
+		"sisa_percobaan": remaining,
+	})
+}
+
+func (h *ASNHandler) registerLoginFailure(scope, key, ip string) (*time.Time, int) {
+	maxFailed := loginMaxFailed(scope)
+	now := time.Now()
+	levelReset := time.Duration(config.GetEnvAsInt("LOGIN_LOCKOUT_RESET_HOURS", 24)) * time.Hour
+	t, err := h.throttleRepo.RegisterFailure(scope, key, now, now.Add(-loginFailWindow()), now.Add(-levelReset))
+	if err != nil {
+		return nil, maxFailed
+	}
+	if t.FailedCount < maxFailed {
+		return nil, maxFailed - t.FailedCount
+	}
+
+	level := t.LockLevel + 1
+	until := now.Add(loginLockDuration(level))
+	locked, err := h.throttleRepo.Lock(t, maxFailed, level, until)
+	if err != nil {
+		return nil, 0
+	}
+	if !locked {
+		// Request paralel lain sudah mengunci lebih dulu -> pakai waktu kunci miliknya
+		if cur, err := h.throttleRepo.Get(scope, key); err == nil && cur.LockedUntil != nil && cur.LockedUntil.After(now) {
+			return cur.LockedUntil, 0
+		}
+		return nil, 0
+	}
+
+	h.throttleRepo.CreateEvent(&model.LoginLockoutEvent{
+		Scope:       scope,
+		Key:         key,
+		Event:       "LOCKED",
+		LockLevel:   level,
+		LockedUntil: &until,
+		IPAddress:   ip,
+	})
+	return &until, 0
+}
+
+// loginLockedResponse: 429 + retry_after (detik) agar aplikasi mobile bisa menampilkan hitung mundur
+func loginLockedResponse(c *fiber.Ctx, until time.Time) error {
+	retryAfter := int(time.Until(until).Seconds()) + 1
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error":        fmt.Sprintf("Terlalu banyak percobaan login gagal. Coba lagi dalam %d menit.", (retryAfter+59)/60),
+		"retry_after":  retryAfter,
+		"locked_until": until,
+	})
+}
+
+// --- FITUR LUPA PASSWORD (OTP) ---
+
+// Pengaturan OTP (bisa diubah lewat ENV)
+func otpTTL() time.Duration {
+	return time.Duration(config.GetEnvAsInt("OTP_TTL_MINUTES", 5)) * time.Minute
+}
+
+func otpMaxAttempts() int {
+	return config.GetEnvAsInt("OTP_MAX_ATTEMPTS", 5)
+}
+
+func otpResendCooldown() time.Duration {
+	return time.Duration(config.GetEnvAsInt("OTP_RESEND_COOLDOWN_SECONDS", 60)) * time.Second
+}
+
+func resetTokenTTL() time.Duration {
+	return time.Duration(config.GetEnvAsInt("OTP_RESET_TOKEN_TTL_MINUTES", 10)) * time.Minute
 }
 
 type ForgotPasswordRequest struct {
@@ -696,31 +1174,76 @@ func (h *ASNHandler) RequestOTP(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
 	}
 
-	// 1. Cari ASN
+	now := time.Now()
+	oneHourAgo := now.Add(-time.Hour)
+
+	// 1. Rate Limit per IP (mencegah spam ke banyak NIP dari satu sumber)
+	ipCount, _ := h.otpRepo.CountByIPSince(c.IP(), oneHourAgo)
+	if ipCount >= int64(config.GetEnvAsInt("OTP_MAX_PER_IP_PER_HOUR", 20)) {
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "Terlalu banyak permintaan OTP dari perangkat ini. Coba lagi nanti."})
+	}
+
+	// 2. Cari ASN
 	asn, err := h.repo.FindByNIP(req.NIP)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "NIP tidak ditemukan"})
 	}
 
-	// 2. Cek Email
+	// Password akun SSO-only dikelola di penyedia SSO
+	if ssoRequired(asn) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Organisasi Anda memakai SSO. Reset password dilakukan di penyedia SSO.", "sso_required": true})
+	}
+	if !passwordauth.LocalManaged(asn) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Password akun Anda dikelola direktori organisasi. Reset password dilakukan melalui direktori tersebut."})
+	}
+
+	// 3. Cek Email
 	if asn.Email == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Email tidak terdaftar. Silakan hubungi Admin untuk update data."})
 	}
 
-	// 3. Generate OTP (6 Digit)
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-	otp := fmt.Sprintf("%06d", rng.Intn(1000000))
+	// 4. Cooldown Kirim Ulang & Rate Limit per NIP
+	if last, err := h.otpRepo.GetLatestByNIP(req.NIP); err == nil {
+		if wait := last.CreatedAt.Add(otpResendCooldown()).Sub(now); wait > 0 {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error":       fmt.Sprintf("Tunggu %d detik sebelum meminta OTP baru", int(wait.Seconds())+1),
+				"retry_after": int(wait.Seconds()) + 1,
+			})
+		}
+	}
+
+	nipCount, _ := h.otpRepo.CountByNIPSince(req.NIP, oneHourAgo)
+	if nipCount >= int64(config.GetEnvAsInt("OTP_MAX_PER_NIP_PER_HOUR", 5)) {
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "Batas permintaan OTP untuk NIP ini tercapai. Coba lagi dalam 1 jam."})
+	}
+
+	// 5. Generate OTP (6 Digit, crypto/rand)
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat kode OTP"})
+	}
+	otp := fmt.Sprintf("%06d", n.Int64())
+
+	codeHash, err := bcrypt.GenerateFromPassword([]byte(otp), bcrypt.DefaultCost)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat kode OTP"})
+	}
 
-	// 4. Simpan OTP (In-Memory)
-	otpMutex.Lock()
-	otpStore[req.NIP] = OTPData{
-		Code:      otp,
-		ExpiresAt: time.Now().Add(5 * time.Minute), // Berlaku 5 menit
+	// 6. Simpan OTP (Hash) & Nonaktifkan OTP sebelumnya
+	h.otpRepo.InvalidateActiveByNIP(req.NIP)
+	record := model.PasswordResetOTP{
+		ASNID:     asn.ID,
+		NIP:       asn.NIP,
+		RequestIP: c.IP(),
+		CodeHash:  string(codeHash),
+		ExpiresAt: now.Add(otpTTL()),
+	}
+	if err := h.otpRepo.Create(&record); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan kode OTP"})
 	}
-	otpMutex.Unlock()
 
-	// 5. Kirim Email Menggunakan Gomail
-	if err := sendOTPEmail(asn.Email, asn.Nama, otp); err != nil {
+	// 7. Kirim Email Menggunakan Gomail
+	if err := sendOTPEmail(asn.Email, asn.Nama, otp, int(otpTTL().Minutes())); err != nil {
 		fmt.Printf("Error sending email: %v\n", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengirim email OTP. Cek log server."})
 	}
@@ -732,12 +1255,25 @@ func (h *ASNHandler) RequestOTP(c *fiber.Ctx) error {
 	}
 
 	return c.JSON(fiber.Map{
-		"message": fmt.Sprintf("Kode OTP telah dikirim ke email %s", maskedEmail),
+		"message":     fmt.Sprintf("Kode OTP telah dikirim ke email %s", maskedEmail),
+		"expires_in":  int(otpTTL().Seconds()),
+		"retry_after": int(otpResendCooldown().Seconds()),
 	})
 }
 
+// Helper function untuk mengirim email OTP reset password
+func sendOTPEmail(toEmail, namaUser, otpCode string, ttlMinutes int) error {
+	return sendEmail(toEmail, "Kode OTP Reset Password", fmt.Sprintf(`
+		<h3>Halo %s,</h3>
+		<p>Anda melakukan permintaan reset password. Berikut adalah kode OTP Anda:</p>
+		<h1 style="color: #3b82f6; letter-spacing: 5px;">%s</h1>
+		<p>Kode ini berlaku selama %d menit. Jangan berikan kepada siapapun.</p>
+		<p>Jika ini bukan Anda, abaikan email ini.</p>
+	`, namaUser, otpCode, ttlMinutes))
+}
+
 // Helper function untuk mengirim email menggunakan SMTP (Gomail)
-func sendOTPEmail(toEmail, namaUser, otpCode string) error {
+func sendEmail(toEmail, subject, htmlBody string) error {
 	// KONFIGURASI SMTP (Ganti dengan kredensial asli atau ambil dari ENV)
 	// Jika menggunakan Gmail, pastikan menggunakan "App Password", bukan password login biasa.
 	smtpHost := config.GetEnv("SMTP_HOST", "smtp.gmail.com")
@@ -748,14 +1284,8 @@ func sendOTPEmail(toEmail, namaUser, otpCode string) error {
 	m := gomail.NewMessage()
 	m.SetHeader("From", smtpUser)
 	m.SetHeader("To", toEmail)
-	m.SetHeader("Subject", "Kode OTP Reset Password")
-	m.SetBody("text/html", fmt.Sprintf(`
-		<h3>Halo %s,</h3>
-		<p>Anda melakukan permintaan reset password. Berikut adalah kode OTP Anda:</p>
-		<h1 style="color: #3b82f6; letter-spacing: 5px;">%s</h1>
-		<p>Kode ini berlaku selama 5 menit. Jangan berikan kepada siapapun.</p>
-		<p>Jika ini bukan Anda, abaikan email ini.</p>
-	`, namaUser, otpCode))
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/html", htmlBody)
 
 	d := gomail.NewDialer(smtpHost, smtpPort, smtpUser, smtpPass)
 	return d.DialAndSend(m)
@@ -766,30 +1296,64 @@ type VerifyOTPRequest struct {
 	OTP string `json:"otp"`
 }
 
+// VerifyOTP: Jika OTP benar, kembalikan reset_token berumur pendek untuk langkah reset password
 func (h *ASNHandler) VerifyOTP(c *fiber.Ctx) error {
 	var req VerifyOTPRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
 	}
 
-	otpMutex.Lock()
-	data, exists := otpStore[req.NIP]
-	otpMutex.Unlock()
-
-	if !exists || time.Now().After(data.ExpiresAt) {
+	record, err := h.otpRepo.GetLatestByNIP(req.NIP)
+	if err != nil || record.VerifiedAt != nil || record.InvalidatedAt != nil || time.Now().After(record.ExpiresAt) {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Kode OTP kadaluwarsa atau tidak valid"})
 	}
 
-	if data.Code != req.OTP {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Kode OTP salah"})
+	// Batasi jumlah tebakan: jatah percobaan dipakai atomik SEBELUM membandingkan kode
+	ok, err := h.otpRepo.UseAttempt(record.ID, otpMaxAttempts())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memverifikasi OTP"})
+	}
+	if !ok {
+		h.otpRepo.Invalidate(record.ID)
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "Terlalu banyak percobaan. Silakan minta kode OTP baru."})
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(record.CodeHash), []byte(req.OTP)); err != nil {
+		sisa := otpMaxAttempts() - (record.Attempts + 1)
+		if sisa <= 0 {
+			sisa = 0
+			h.otpRepo.Invalidate(record.ID)
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":          "Kode OTP salah",
+			"sisa_percobaan": sisa,
+		})
 	}
 
-	return c.JSON(fiber.Map{"message": "OTP Valid"})
+	// OTP Valid -> Terbitkan Reset Token (sekali pakai)
+	resetToken, err := generateRandomToken(32)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat token reset"})
+	}
+
+	ok, err = h.otpRepo.MarkVerified(record.ID, hashToken(resetToken), time.Now().Add(resetTokenTTL()))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan token reset"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Kode OTP kadaluwarsa atau tidak valid"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":     "OTP Valid",
+		"reset_token": resetToken,
+		"expires_in":  int(resetTokenTTL().Seconds()),
+	})
 }
 
 type ResetPasswordFinalRequest struct {
 	NIP         string `json:"nip"`
-	OTP         string `json:"otp"`
+	ResetToken  string `json:"reset_token"` // Didapat dari /forgot-password/verify
 	NewPassword string `json:"new_password"`
 }
 
@@ -799,39 +1363,38 @@ func (h *ASNHandler) ResetPasswordFinal(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
 	}
 
-	// Validasi OTP lagi sebelum reset (Stateless Security)
-	otpMutex.Lock()
-	data, exists := otpStore[req.NIP]
-	otpMutex.Unlock()
-
-	if !exists || time.Now().After(data.ExpiresAt) || data.Code != req.OTP {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Sesi OTP tidak valid, silakan ulangi permintaan OTP"})
-	}
-
-	if len(req.NewPassword) < 6 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Password minimal 6 karakter"})
+	// Validasi Reset Token
+	record, err := h.otpRepo.GetByResetTokenHash(hashToken(req.ResetToken))
+	if err != nil || req.ResetToken == "" || record.NIP != req.NIP || record.ResetAt != nil ||
+		record.ResetTokenExpiresAt == nil || time.Now().After(*record.ResetTokenExpiresAt) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Sesi reset password tidak valid, silakan ulangi permintaan OTP"})
 	}
 
 	// Update Password
-	asn, err := h.repo.FindByNIP(req.NIP)
+	asn, err := h.repo.FindByIDWithOrganisasi(record.ASNID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pegawai tidak ditemukan"})
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	hashedPassword, err := h.newPasswordHash(&asn.Organisasi, asn, req.NewPassword)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengenkripsi password"})
+		return passwordPolicyResponse(c, err)
 	}
 
-	asn.Password = string(hashedPassword)
-	if err := h.repo.Update(asn); err != nil {
+	// Tandai token sudah dipakai (atomik) agar request paralel dengan token yang sama ditolak
+	ok, err := h.otpRepo.MarkReset(record.ID)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal reset password"})
 	}
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Sesi reset password tidak valid, silakan ulangi permintaan OTP"})
+	}
 
-	// Hapus OTP agar tidak bisa dipakai lagi
-	otpMutex.Lock()
-	delete(otpStore, req.NIP)
-	otpMutex.Unlock()
+	if err := h.repo.SetPassword(asn, hashedPassword, false); err != nil {
+		h.otpRepo.UnmarkReset(record.ID)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal reset password"})
+	}
+	h.sessionRepo.RevokeAllByASN(asn.ID, "PASSWORD_CHANGED")
 
 	return c.JSON(fiber.Map{"message": "Password berhasil diubah. Silakan login kembali."})
 }
@@ -885,6 +1448,54 @@ func (h *ASNHandler) UploadFotoProfile(c *fiber.Ctx) error {
 	})
 }
 
+// SetFotoReferensi (Admin): Atur foto pembanding verifikasi wajah pegawai.
+// Upload file (form "foto", JPG/PNG maks 2MB), atau tanpa file untuk memakai foto profil saat ini
+// setelah admin memastikan foto tersebut memang wajah pegawai ybs.
+func (h *ASNHandler) SetFotoReferensi(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	asn, err := h.repo.FindByIDInOrg(uint(id), orgID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pegawai tidak ditemukan"})
+	}
+	if status, msg := checkManageableASN(c, h.roleRepo, asn); msg != "" {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	file, err := c.FormFile("foto")
+	if err != nil {
+		// Tanpa file: salin path foto profil yang sekarang
+		path, ok := profilePhotoPath(asn.Foto)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Pegawai belum memiliki foto profil. Upload foto referensi (field: foto)."})
+		}
+		if _, err := os.Stat(path); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "File foto profil tidak ditemukan. Upload foto referensi (field: foto)."})
+		}
+		asn.FotoReferensi = path
+	} else {
+		if file.Size > 2097152 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Ukuran file maksimal 2MB"})
+		}
+		ext := strings.ToLower(filepath.Ext(file.Filename))
+		if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Foto referensi harus JPG atau PNG"})
+		}
+		os.MkdirAll(profileUploadDir, 0755)
+		pathFile := fmt.Sprintf("%s/ref_%d_%d%s", profileUploadDir, asn.ID, time.Now().Unix(), ext)
+		if err := c.SaveFile(file, pathFile); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan file"})
+		}
+		asn.FotoReferensi = pathFile
+	}
+
+	if err := h.repo.Update(asn); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan foto referensi"})
+	}
+	return c.JSON(fiber.Map{"message": "Foto referensi verifikasi wajah berhasil disimpan", "foto_referensi": asn.FotoReferensi})
+}
+
 // GetFotoProfile: Mengambil file foto profil berdasarkan ID ASN
 func (h *ASNHandler) GetFotoProfile(c *fiber.Ctx) error {
 	id, _ := strconv.Atoi(c.Params("id"))
@@ -899,6 +1510,11 @@ func (h *ASNHandler) GetFotoProfile(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).SendString("Foto belum diatur")
 	}
 
+	// Data lama bisa berisi path sembarang: hanya file di uploads/profile yang dikirim
+	if _, ok := profilePhotoPath(asn.Foto); !ok {
+		return c.Status(fiber.StatusNotFound).SendString("File foto tidak ditemukan")
+	}
+
 	// Cek apakah file benar-benar ada di disk
 	if _, err := os.Stat(asn.Foto); os.IsNotExist(err) {
 		// Jika path di DB ada tapi file fisik hilang
@@ -926,23 +1542,27 @@ type UpdateStatusRequest struct {
 }
 
 func (h *ASNHandler) ToggleActiveASN(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
 	id, _ := strconv.Atoi(c.Params("id"))
 	var req UpdateStatusRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
 	}
 
-	asn, err := h.repo.FindByID(uint(id))
+	asn, err := h.repo.FindByIDInOrg(uint(id), orgID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pegawai tidak ditemukan"})
 	}
 
 	// Gunakan UpdateStatus agar aman dari issue GORM association
-	if err := h.repo.UpdateStatus(asn.ID, req.IsActive); err != nil {
+	if err := h.repo.UpdateStatus(asn.ID, orgID, req.IsActive); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal update status pegawai"})
 	}
 
 	asn.IsActive = req.IsActive
+	if !asn.IsActive {
+		h.sessionRepo.RevokeAllByASN(asn.ID, "NONAKTIF")
+	}
 	status := "nonaktif"
 	if asn.IsActive {
 		status = "aktif"
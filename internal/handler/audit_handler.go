@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"my-flutter-backend/internal/middleware"
+	"my-flutter-backend/internal/repository"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AuditHandler struct {
+	repo repository.AuditLogRepository
+}
+
+func NewAuditHandler(repo repository.AuditLogRepository) *AuditHandler {
+	return &AuditHandler{repo: repo}
+}
+
+// GetAll: List audit log organisasi sendiri
+// GET /api/admin/audit?actor_nip=&action=&entity_type=&entity_id=&start_date=2024-10-01&end_date=2024-10-31&page=1&limit=50
+// Pemegang kelola_organisasi boleh melihat organisasi lain lewat ?organisasi_id=
+func (h *AuditHandler) GetAll(c *fiber.Ctx) error {
+	filter := repository.AuditLogFilter{
+		OrganisasiID: uint(c.Locals("organisasi_id").(float64)),
+		ActorNIP:     c.Query("actor_nip"),
+		Action:       strings.ToUpper(c.Query("action")),
+		EntityType:   c.Query("entity_type"),
+		EntityID:     uint(c.QueryInt("entity_id")),
+	}
+
+	if orgID := c.QueryInt("organisasi_id"); orgID > 0 && middleware.HasPermission(c, "kelola_organisasi") {
+		filter.OrganisasiID = uint(orgID)
+	}
+
+	if s := c.Query("start_date"); s != "" {
+		from, err := time.ParseInLocation("2006-01-02", s, time.Local)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format start_date harus YYYY-MM-DD"})
+		}
+		filter.From = &from
+	}
+	if s := c.Query("end_date"); s != "" {
+		to, err := time.ParseInLocation("2006-01-02", s, time.Local)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format end_date harus YYYY-MM-DD"})
+		}
+		to = to.AddDate(0, 0, 1) // Inklusif sampai akhir hari
+		filter.To = &to
+	}
+
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	filter.Limit = limit
+	filter.Offset = (page - 1) * limit
+
+	logs, total, err := h.repo.Find(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil audit log"})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": logs,
+		"meta": fiber.Map{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
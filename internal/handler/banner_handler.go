@@ -2,6 +2,7 @@ package handler
 
 import (
 	"fmt"
+	"my-flutter-backend/internal/middleware"
 	"my-flutter-backend/internal/model"
 	"my-flutter-backend/internal/repository"
 	"os"
@@ -85,20 +86,29 @@ func (h *BannerHandler) Create(c *fiber.Ctx) error {
 	if err := h.repo.Create(&banner); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat banner"})
 	}
+	middleware.SetAuditEntityID(c, banner.ID)
 	return c.JSON(fiber.Map{"message": "Banner berhasil dibuat", "data": banner})
 }
 
 func (h *BannerHandler) Delete(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
 	id, _ := strconv.Atoi(c.Params("id"))
-	if err := h.repo.Delete(uint(id)); err != nil {
+	if _, err := h.repo.GetByIDInOrg(uint(id), orgID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Banner tidak ditemukan"})
+	}
+	if err := h.repo.Delete(uint(id), orgID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menonaktifkan banner"})
 	}
 	return c.JSON(fiber.Map{"message": "Banner berhasil dinonaktifkan"})
 }
 
 func (h *BannerHandler) ToggleStatus(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
 	id, _ := strconv.Atoi(c.Params("id"))
-	if err := h.repo.ToggleStatus(uint(id)); err != nil {
+	if _, err := h.repo.GetByIDInOrg(uint(id), orgID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Banner tidak ditemukan"})
+	}
+	if err := h.repo.ToggleStatus(uint(id), orgID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengubah status banner"})
 	}
 	return c.JSON(fiber.Map{"message": "Status banner berhasil diubah"})
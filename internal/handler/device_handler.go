@@ -0,0 +1,287 @@
+package handler
+
+import (
+	"errors"
+	"my-flutter-backend/config"
+	"my-flutter-backend/internal/middleware"
+	"my-flutter-backend/internal/model"
+	"my-flutter-backend/internal/repository"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// --- MULTI DEVICE & PERMINTAAN GANTI DEVICE ---
+// Alur HP baru saat batas device organisasi (max_devices) sudah penuh:
+//  1. POST /api/login dari HP baru -> 403 + device_change_token (password sudah terverifikasi)
+//  2. POST /api/device-change/request dengan token + alasan -> status MENUNGGU
+//  3. Atasan / admin (kelola_pegawai) menyetujui di aplikasi -> device lama dilepas, HP baru terdaftar
+//  4. Pegawai login ulang dari HP baru
+
+const (
+	deviceChangeTokenTTL = 15 * time.Minute
+	maxDevicesLimit      = 10
+)
+
+// maxDevices: Batas device per pegawai sesuai pengaturan organisasi (minimal 1)
+func maxDevices(org *model.Organisasi) int {
+	if org.MaxDevices < 1 {
+		return 1
+	}
+	return org.MaxDevices
+}
+
+// deviceChangeRejection: Respons login HP baru saat batas device penuh, berisi token untuk mengajukan ganti device
+func (h *ASNHandler) deviceChangeRejection(asn *model.ASN, uuid, brand, series, firebaseToken string) fiber.Map {
+	if pending, err := h.deviceRepo.GetPendingRequest(asn.ID); err == nil && pending.UUID == uuid {
+		return fiber.Map{
+			"error":                  "Permintaan ganti perangkat Anda sedang menunggu persetujuan atasan/admin.",
+			"device_change_required": true,
+			"device_change_request":  pending,
+		}
+	}
+
+	response := fiber.Map{
+		"error":                  "Akun ini sudah terikat pada perangkat lain. Ajukan permintaan ganti perangkat atau hubungi admin.",
+		"device_change_required": true,
+		"max_devices":            maxDevices(&asn.Organisasi),
+	}
+
+	now := time.Now()
+	token, err := config.JWT.Sign(jwt.MapClaims{
+		"user_id":        asn.ID,
+		"nip":            asn.NIP,
+		"type":           "device_change",
+		"uuid":           uuid,
+		"brand":          brand,
+		"series":         series,
+		"firebase_token": firebaseToken,
+		"iat":            now.Unix(),
+		"exp":            now.Add(deviceChangeTokenTTL).Unix(),
+	})
+	if err == nil {
+		response["device_change_token"] = token
+		response["expires_in"] = int(deviceChangeTokenTTL.Seconds())
+	}
+	return response
+}
+
+func (h *ASNHandler) parseDeviceChangeToken(tokenString string) (*model.ASN, jwt.MapClaims, error) {
+	token, err := config.JWT.Parse(tokenString)
+	if err != nil || !token.Valid {
+		return nil, nil, errors.New("token tidak valid")
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	nip, _ := claims["nip"].(string)
+	uuid, _ := claims["uuid"].(string)
+	if claims["type"] != "device_change" || nip == "" || uuid == "" {
+		return nil, nil, errors.New("token tidak valid")
+	}
+
+	asn, err := h.repo.FindByNIP(nip)
+	if err != nil || !asn.IsActive {
+		return nil, nil, errors.New("akun tidak valid")
+	}
+	// Token tidak berlaku lagi jika password diubah setelah token diterbitkan
+	iat, _ := claims["iat"].(float64)
+	if asn.PasswordChangedAt != nil && asn.PasswordChangedAt.Unix() > int64(iat) {
+		return nil, nil, errors.New("token kadaluwarsa")
+	}
+	return asn, claims, nil
+}
+
+type DeviceChangeSubmitRequest struct {
+	DeviceChangeToken string `json:"device_change_token"`
+	Alasan            string `json:"alasan"`
+	ReplaceDeviceID   *uint  `json:"replace_device_id"` // Opsional: device lama yang ingin dilepas
+}
+
+// SubmitDeviceChange: Pegawai mengajukan HP baru (dari layar login, tanpa access token)
+func (h *ASNHandler) SubmitDeviceChange(c *fiber.Ctx) error {
+	var req DeviceChangeSubmitRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format data salah"})
+	}
+
+	asn, claims, err := h.parseDeviceChangeToken(req.DeviceChangeToken)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Sesi pengajuan ganti perangkat tidak valid atau kadaluwarsa, silakan login ulang"})
+	}
+	if strings.TrimSpace(req.Alasan) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Alasan wajib diisi"})
+	}
+
+	uuid, _ := claims["uuid"].(string)
+	for _, d := range asn.Devices {
+		if d.UUID == uuid {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Perangkat ini sudah terdaftar, silakan login ulang"})
+		}
+	}
+	if req.ReplaceDeviceID != nil {
+		owned := false
+		for _, d := range asn.Devices {
+			owned = owned || d.ID == *req.ReplaceDeviceID
+		}
+		if !owned {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Perangkat yang ingin diganti tidak ditemukan"})
+		}
+	}
+	if _, err := h.deviceRepo.GetPendingRequest(asn.ID); err == nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Masih ada permintaan ganti perangkat yang menunggu persetujuan"})
+	}
+
+	nipAtasan := ""
+	if asn.Atasan != nil {
+		nipAtasan = asn.Atasan.NIP
+	}
+	brand, _ := claims["brand"].(string)
+	series, _ := claims["series"].(string)
+	firebaseToken, _ := claims["firebase_token"].(string)
+
+	request := model.DeviceChangeRequest{
+		ASNID:           asn.ID,
+		NIPAtasan:       nipAtasan,
+		UUID:            uuid,
+		Brand:           brand,
+		Series:          series,
+		FirebaseToken:   firebaseToken,
+		ReplaceDeviceID: req.ReplaceDeviceID,
+		Alasan:          strings.TrimSpace(req.Alasan),
+		Status:          "MENUNGGU",
+	}
+	if err := h.deviceRepo.CreateRequest(&request); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengajukan ganti perangkat"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Permintaan ganti perangkat dikirim. Silakan login kembali setelah disetujui atasan/admin.",
+		"data":    request,
+	})
+}
+
+// GetMyDevices: Device terdaftar, batas device, dan riwayat permintaan milik user login
+func (h *ASNHandler) GetMyDevices(c *fiber.Ctx) error {
+	asnID := uint(c.Locals("user_id").(float64))
+	asn, err := h.repo.FindByIDWithOrganisasi(asnID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User tidak ditemukan"})
+	}
+	requests, _ := h.deviceRepo.GetRequestsByASN(asnID)
+
+	return c.JSON(fiber.Map{"data": fiber.Map{
+		"max_devices": maxDevices(&asn.Organisasi),
+		"devices":     asn.Devices,
+		"requests":    requests,
+	}})
+}
+
+// GetDeviceChangeApprovals: Permintaan yang menunggu. Atasan melihat milik bawahannya, admin (kelola_pegawai) semua di organisasi.
+func (h *ASNHandler) GetDeviceChangeApprovals(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
+	nipAtasan := c.Locals("nip").(string)
+	if middleware.HasPermission(c, "kelola_pegawai") {
+		nipAtasan = ""
+	}
+
+	list, err := h.deviceRepo.GetPendingRequests(orgID, nipAtasan)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data"})
+	}
+	return c.JSON(fiber.Map{"data": list})
+}
+
+type DeviceChangeApprovalRequest struct {
+	Status  string `json:"status"` // DISETUJUI / DITOLAK
+	Catatan string `json:"catatan"`
+}
+
+// ProcessDeviceChange: Setujui / tolak permintaan ganti device (atasan langsung atau admin kelola_pegawai)
+func (h *ASNHandler) ProcessDeviceChange(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
+	userID := uint(c.Locals("user_id").(float64))
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	var req DeviceChangeApprovalRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
+	}
+	if req.Status != "DISETUJUI" && req.Status != "DITOLAK" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Status harus DISETUJUI atau DITOLAK"})
+	}
+
+	request, err := h.deviceRepo.GetRequestByIDInOrg(uint(id), orgID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Permintaan tidak ditemukan"})
+	}
+	// Atasan langsung hanya dihitung jika (masih) memegang approve_cuti, atasan_id diisi sendiri oleh pegawai
+	isAtasan := request.NIPAtasan != "" && request.NIPAtasan == c.Locals("nip").(string) && middleware.HasPermission(c, "approve_cuti")
+	if !isAtasan && !middleware.HasPermission(c, "kelola_pegawai") {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Hanya atasan langsung (approve_cuti) atau admin yang bisa memproses permintaan ini"})
+	}
+	if request.ASNID == userID && !middleware.IsSuperuser(c) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Tidak bisa memproses permintaan sendiri"})
+	}
+
+	if req.Status == "DITOLAK" {
+		if err := h.deviceRepo.Reject(request, userID, req.Catatan); err != nil {
+			return deviceChangeProcessError(c, err)
+		}
+		return c.JSON(fiber.Map{"message": "Permintaan ganti perangkat ditolak", "data": request})
+	}
+
+	owner, err := h.repo.FindByIDWithOrganisasi(request.ASNID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pegawai tidak ditemukan"})
+	}
+	device, released, err := h.deviceRepo.Approve(request, maxDevices(&owner.Organisasi), userID, req.Catatan)
+	if err != nil {
+		return deviceChangeProcessError(c, err)
+	}
+	// Sesi di HP yang dilepas tidak berlaku lagi
+	for _, deviceID := range released {
+		h.sessionRepo.RevokeAllByDevice(deviceID, "DEVICE_DIGANTI")
+	}
+
+	return c.JSON(fiber.Map{
+		"message":          "Permintaan ganti perangkat disetujui",
+		"data":             request,
+		"device":           device,
+		"released_devices": released,
+	})
+}
+
+func deviceChangeProcessError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, repository.ErrDeviceRequestProcessed):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Permintaan sudah diproses sebelumnya"})
+	case errors.Is(err, repository.ErrDeviceInUse):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Perangkat ini sudah digunakan oleh akun lain"})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memproses permintaan"})
+}
+
+// GetDeviceHistory: Admin melihat device aktif, riwayat semua device yang pernah terikat, dan permintaan ganti device pegawai
+func (h *ASNHandler) GetDeviceHistory(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
+	id, _ := strconv.Atoi(c.Params("id"))
+	asn, err := h.repo.FindByIDInOrg(uint(id), orgID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pegawai tidak ditemukan"})
+	}
+
+	history, err := h.deviceRepo.GetHistory(asn.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil riwayat device"})
+	}
+	requests, _ := h.deviceRepo.GetRequestsByASN(asn.ID)
+
+	return c.JSON(fiber.Map{"data": fiber.Map{
+		"devices":  asn.Devices,
+		"history":  history,
+		"requests": requests,
+	}})
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"fmt"
+	"my-flutter-backend/internal/middleware"
+	"my-flutter-backend/internal/model"
+	"my-flutter-backend/internal/repository"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// atasan_id diisi sendiri oleh pegawai: hanya pemegang approve_cuti di organisasi yang sama yang boleh jadi atasan / menyetujui
+func TestAtasanRequiresApprovePermission(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&model.DeviceChangeRequest{}, &model.DeviceHistory{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	middleware.Init(db)
+
+	approveCuti := model.Permission{NamaPermission: "approve_cuti"}
+	mustCreate(t, db, &approveCuti)
+	orgA := model.Organisasi{NamaOrganisasi: "Org A"}
+	mustCreate(t, db, &orgA)
+	orgB := model.Organisasi{NamaOrganisasi: "Org B"}
+	mustCreate(t, db, &orgB)
+	atasanRole := model.Role{NamaRole: "Atasan", Permissions: []model.Permission{approveCuti}}
+	mustCreate(t, db, &atasanRole)
+	pegawaiRole := model.Role{NamaRole: "Pegawai"}
+	mustCreate(t, db, &pegawaiRole)
+
+	pegawai := model.ASN{OrganisasiID: orgA.ID, RoleID: pegawaiRole.ID, Nama: "Pegawai", NIP: "P-1", IsActive: true}
+	mustCreate(t, db, &pegawai)
+	rekan := model.ASN{OrganisasiID: orgA.ID, RoleID: pegawaiRole.ID, Nama: "Rekan", NIP: "P-2", IsActive: true}
+	mustCreate(t, db, &rekan)
+	atasan := model.ASN{OrganisasiID: orgA.ID, RoleID: atasanRole.ID, Nama: "Atasan", NIP: "A-1", IsActive: true}
+	mustCreate(t, db, &atasan)
+	atasanLain := model.ASN{OrganisasiID: orgB.ID, RoleID: atasanRole.ID, Nama: "Atasan B", NIP: "B-1", IsActive: true}
+	mustCreate(t, db, &atasanLain)
+
+	hdl := NewASNHandler(repository.NewASNRepository(db), repository.NewSessionRepository(db), repository.NewOTPRepository(db),
+		repository.NewLoginThrottleRepository(db), repository.NewTwoFactorRepository(db), repository.NewSSORepository(db),
+		repository.NewDeviceRepository(db), repository.NewInvitationRepository(db), repository.NewRoleRepository(db), nil)
+	appAs := func(user model.ASN) *fiber.App {
+		app := fiber.New()
+		app.Use(asOrg(user.ID, user.OrganisasiID), func(c *fiber.Ctx) error {
+			c.Locals("nip", user.NIP)
+			c.Locals("role_id", float64(user.RoleID))
+			return c.Next()
+		})
+		app.Post("/api/asn/atasan", hdl.UpdateAtasan)
+		app.Post("/api/device-change/approval/:id", hdl.ProcessDeviceChange)
+		return app
+	}
+
+	updateCases := []struct {
+		name     string
+		atasanID uint
+		want     int
+	}{
+		{"WithoutApproveCuti", rekan.ID, fiber.StatusBadRequest},
+		{"OtherOrganisasi", atasanLain.ID, fiber.StatusBadRequest},
+		{"NotFound", 9999, fiber.StatusBadRequest},
+		{"Approver", atasan.ID, fiber.StatusOK},
+	}
+	for _, tc := range updateCases {
+		t.Run("UpdateAtasan"+tc.name, func(t *testing.T) {
+			if status, body := doRequest(t, appAs(pegawai), "POST", "/api/asn/atasan", fmt.Sprintf(`{"atasan_id":%d}`, tc.atasanID)); status != tc.want {
+				t.Fatalf("status = %d, want %d (body: %s)", status, tc.want, body)
+			}
+		})
+	}
+
+	// Permintaan lama yang menunjuk rekan tanpa approve_cuti (misal diajukan sebelum validasi ada) tidak boleh diproses rekan tersebut
+	viaRekan := model.DeviceChangeRequest{ASNID: pegawai.ID, NIPAtasan: rekan.NIP, UUID: "hp-baru-1", Status: "MENUNGGU"}
+	mustCreate(t, db, &viaRekan)
+	viaAtasan := model.DeviceChangeRequest{ASNID: pegawai.ID, NIPAtasan: atasan.NIP, UUID: "hp-baru-2", Status: "MENUNGGU"}
+	mustCreate(t, db, &viaAtasan)
+
+	body := `{"status":"DITOLAK","catatan":"bukan HP dinas"}`
+	if status, resp := doRequest(t, appAs(rekan), "POST", fmt.Sprintf("/api/device-change/approval/%d", viaRekan.ID), body); status != fiber.StatusForbidden {
+		t.Fatalf("rekan tanpa approve_cuti: status = %d, want 403 (body: %s)", status, resp)
+	}
+	if status, resp := doRequest(t, appAs(atasan), "POST", fmt.Sprintf("/api/device-change/approval/%d", viaAtasan.ID), body); status != fiber.StatusOK {
+		t.Fatalf("atasan approve_cuti: status = %d, want 200 (body: %s)", status, resp)
+	}
+
+	var got model.DeviceChangeRequest
+	db.First(&got, viaRekan.ID)
+	if got.Status != "MENUNGGU" {
+		t.Errorf("permintaan via rekan berubah status: %s", got.Status)
+	}
+	var saved model.ASN
+	db.First(&saved, pegawai.ID)
+	if saved.AtasanID == nil || *saved.AtasanID != atasan.ID {
+		t.Errorf("atasan_id = %v, want %d", saved.AtasanID, atasan.ID)
+	}
+}
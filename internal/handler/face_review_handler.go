@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"errors"
+	"my-flutter-backend/internal/faceverify"
+	"my-flutter-backend/internal/middleware"
+	"my-flutter-backend/internal/model"
+	"my-flutter-backend/internal/repository"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// --- VERIFIKASI WAJAH ---
+// Selfie check-in dibandingkan dengan foto referensi pegawai (ASN.FotoReferensi, hanya diatur admin) jika organisasi
+// mengaktifkan face_verify_enabled dan server punya FACE_VERIFIER. Foto profil tidak dipakai karena bisa diganti
+// pegawai sendiri. Hasil GAGAL/ERROR tetap tercatat sebagai kehadiran tapi masuk antrean review admin.
+
+var errFaceRejected = errors.New("wajah pada selfie tidak cocok dengan foto referensi")
+
+const profileUploadDir = "uploads/profile"
+
+// profilePhotoPath: Path foto hasil upload server (di bawah uploads/profile). Path lain tidak pernah dibuka server.
+func profilePhotoPath(path string) (string, bool) {
+	clean := filepath.ToSlash(filepath.Clean(path))
+	if path == "" || !strings.HasPrefix(clean, profileUploadDir+"/") {
+		return "", false
+	}
+	return clean, true
+}
+
+// verifyCheckInFace: Isi skor & status wajah di kehadiran. errFaceRejected jika skor di bawah face_reject_threshold.
+func verifyCheckInFace(verifier faceverify.FaceVerifier, org *model.Organisasi, asn *model.ASN, foto *model.FotoKehadiran, kehadiran *model.Kehadiran) error {
+	reference, _ := profilePhotoPath(asn.FotoReferensi) // Kosong -> ErrNoReference
+	score, err := verifier.Compare(foto.Path, reference)
+	if err != nil {
+		// Foto referensi belum diatur admin / layanan mati: jangan blokir absen, serahkan ke admin
+		kehadiran.FaceStatus = faceverify.StatusError
+		kehadiran.FaceReviewStatus = "MENUNGGU"
+		return nil
+	}
+
+	if org.FaceRejectThreshold > 0 && score < org.FaceRejectThreshold {
+		return errFaceRejected
+	}
+	kehadiran.FaceScore = &score
+	kehadiran.FaceStatus = faceverify.Evaluate(score, org.FaceMatchThreshold)
+	if kehadiran.FaceStatus != faceverify.StatusLolos {
+		kehadiran.FaceReviewStatus = "MENUNGGU"
+	}
+	return nil
+}
+
+type FaceReviewHandler struct {
+	repo repository.KehadiranRepository
+}
+
+func NewFaceReviewHandler(repo repository.KehadiranRepository) *FaceReviewHandler {
+	return &FaceReviewHandler{repo: repo}
+}
+
+// GetQueue: Antrean review verifikasi wajah (?status=MENUNGGU|DITERIMA|DITOLAK, default MENUNGGU)
+func (h *FaceReviewHandler) GetQueue(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
+	status := strings.ToUpper(c.Query("status", "MENUNGGU"))
+	if status != "MENUNGGU" && status != "DITERIMA" && status != "DITOLAK" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Status harus MENUNGGU, DITERIMA, atau DITOLAK"})
+	}
+
+	items, err := h.repo.GetFaceReviewQueue(orgID, status)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil antrean review wajah"})
+	}
+
+	data := make([]fiber.Map, 0, len(items))
+	for _, item := range items {
+		data = append(data, fiber.Map{
+			"kehadiran":      item.Kehadiran,
+			"nip":            item.NIP,
+			"nama":           item.Nama,
+			"foto_profil":    item.Foto,
+			"foto_referensi": item.FotoReferensi,
+			"selfie":         selfieURL(&model.FotoKehadiran{KehadiranID: item.ID, Jenis: "MASUK"}),
+		})
+	}
+	return c.JSON(fiber.Map{"data": data})
+}
+
+type FaceReviewRequest struct {
+	Keputusan string `json:"keputusan"` // DITERIMA / DITOLAK
+	Catatan   string `json:"catatan"`
+}
+
+// Review: Terima (wajah memang pegawai ybs) atau tolak (indikasi titip absen) hasil verifikasi yang gagal
+func (h *FaceReviewHandler) Review(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
+	userID := uint(c.Locals("user_id").(float64))
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	var req FaceReviewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
+	}
+	keputusan := strings.ToUpper(req.Keputusan)
+	if keputusan != "DITERIMA" && keputusan != "DITOLAK" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Keputusan harus DITERIMA atau DITOLAK"})
+	}
+	if keputusan == "DITOLAK" && strings.TrimSpace(req.Catatan) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Catatan wajib diisi jika verifikasi ditolak"})
+	}
+
+	if err := h.repo.ReviewFace(uint(id), orgID, keputusan, strings.TrimSpace(req.Catatan), userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Kehadiran tidak ditemukan atau sudah direview"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan review"})
+	}
+	middleware.SetAuditEntityID(c, uint(id))
+	return c.JSON(fiber.Map{"message": "Review verifikasi wajah disimpan", "keputusan": keputusan})
+}
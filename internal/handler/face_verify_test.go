@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"my-flutter-backend/internal/faceverify"
+	"my-flutter-backend/internal/model"
+	"my-flutter-backend/internal/repository"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// writeFaceImage: PNG 16x16 dengan area terang sesuai light(x, y), untuk skor stub yang bisa ditebak
+func writeFaceImage(t *testing.T, path string, light func(x, y int) bool) {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			if light(x, y) {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Pembanding adalah FotoReferensi (diatur admin) di bawah uploads/profile; skor stub menentukan LOLOS/GAGAL/tolak
+func TestVerifyCheckInFaceThresholds(t *testing.T) {
+	t.Chdir(t.TempDir())
+	left := func(x, y int) bool { return x < 8 }
+	writeFaceImage(t, "uploads/profile/ref_1.png", left)
+	writeFaceImage(t, "outside/ref_1.png", left)
+	writeFaceImage(t, "selfie/same.png", left)
+	writeFaceImage(t, "selfie/half.png", func(x, y int) bool { return y < 8 }) // Skor stub 0.5
+	writeFaceImage(t, "selfie/inverted.png", func(x, y int) bool { return x >= 8 })
+
+	org := &model.Organisasi{FaceMatchThreshold: 0.8, FaceRejectThreshold: 0.3}
+	noReject := &model.Organisasi{FaceMatchThreshold: 0.8}
+
+	cases := []struct {
+		name, selfie, reference string
+		org                     *model.Organisasi
+		wantErr                 error
+		wantStatus, wantReview  string
+	}{
+		{"Match", "selfie/same.png", "uploads/profile/ref_1.png", org, nil, faceverify.StatusLolos, ""},
+		{"BelowMatchThreshold", "selfie/half.png", "uploads/profile/ref_1.png", org, nil, faceverify.StatusGagal, "MENUNGGU"},
+		{"BelowRejectThreshold", "selfie/inverted.png", "uploads/profile/ref_1.png", org, errFaceRejected, "", ""},
+		{"RejectDisabled", "selfie/inverted.png", "uploads/profile/ref_1.png", noReject, nil, faceverify.StatusGagal, "MENUNGGU"},
+		{"NoReference", "selfie/same.png", "", org, nil, faceverify.StatusError, "MENUNGGU"},
+		{"ReferenceOutsideUploads", "selfie/same.png", "outside/ref_1.png", org, nil, faceverify.StatusError, "MENUNGGU"},
+		{"ReferenceTraversal", "selfie/same.png", "uploads/profile/../../outside/ref_1.png", org, nil, faceverify.StatusError, "MENUNGGU"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			asn := &model.ASN{Foto: "selfie/same.png", FotoReferensi: tc.reference} // Foto profil tidak boleh dipakai
+			var kehadiran model.Kehadiran
+			err := verifyCheckInFace(faceverify.Stub{}, tc.org, asn, &model.FotoKehadiran{Path: tc.selfie}, &kehadiran)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if kehadiran.FaceStatus != tc.wantStatus || kehadiran.FaceReviewStatus != tc.wantReview {
+				t.Fatalf("status = %q/%q, want %q/%q", kehadiran.FaceStatus, kehadiran.FaceReviewStatus, tc.wantStatus, tc.wantReview)
+			}
+		})
+	}
+}
+
+// Pegawai tidak bisa menunjuk file sembarang sebagai foto profil
+func TestUpdateProfileFotoPath(t *testing.T) {
+	db := newTestDB(t)
+	org := model.Organisasi{NamaOrganisasi: "Org A"}
+	mustCreate(t, db, &org)
+	asn := model.ASN{OrganisasiID: org.ID, Nama: "Pegawai", NIP: "P-1", IsActive: true}
+	mustCreate(t, db, &asn)
+
+	hdl := NewASNHandler(repository.NewASNRepository(db), repository.NewSessionRepository(db), repository.NewOTPRepository(db),
+		repository.NewLoginThrottleRepository(db), repository.NewTwoFactorRepository(db), repository.NewSSORepository(db),
+		repository.NewDeviceRepository(db), repository.NewInvitationRepository(db), repository.NewRoleRepository(db), nil)
+	app := fiber.New()
+	app.Use(asOrg(asn.ID, org.ID))
+	app.Put("/api/asn/profile", hdl.UpdateProfile)
+
+	cases := []struct {
+		foto string
+		want int
+	}{
+		{"/etc/passwd", fiber.StatusBadRequest},
+		{"uploads/profile/../../config/.env", fiber.StatusBadRequest},
+		{"uploads/selfie/1.jpg", fiber.StatusBadRequest},
+		{"uploads/profile/1_1700000000.jpg", fiber.StatusOK},
+	}
+	for _, tc := range cases {
+		if status, body := doRequest(t, app, "PUT", "/api/asn/profile", `{"foto":"`+tc.foto+`"}`); status != tc.want {
+			t.Errorf("foto %q: status = %d, want %d (body: %s)", tc.foto, status, tc.want, body)
+		}
+	}
+
+	var got model.ASN
+	if err := db.First(&got, asn.ID).Error; err != nil || got.Foto != "uploads/profile/1_1700000000.jpg" || got.FotoReferensi != "" {
+		t.Errorf("foto = %q, foto_referensi = %q, err = %v", got.Foto, got.FotoReferensi, err)
+	}
+}
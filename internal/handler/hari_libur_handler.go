@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"my-flutter-backend/internal/middleware"
 	"my-flutter-backend/internal/model"
 	"my-flutter-backend/internal/repository"
 	"strconv"
@@ -38,17 +39,19 @@ func (h *HariLiburHandler) Create(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan data"})
 	}
 
+	middleware.SetAuditEntityID(c, req.ID)
 	return c.JSON(fiber.Map{"message": "Hari libur berhasil ditambahkan", "data": req})
 }
 
 func (h *HariLiburHandler) Update(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
 	id, _ := strconv.Atoi(c.Params("id"))
 	var req model.HariLibur
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
 	}
 
-	libur, err := h.repo.GetByID(uint(id))
+	libur, err := h.repo.GetByIDInOrg(uint(id), orgID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Data tidak ditemukan"})
 	}
@@ -63,8 +66,12 @@ func (h *HariLiburHandler) Update(c *fiber.Ctx) error {
 }
 
 func (h *HariLiburHandler) Delete(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
 	id, _ := strconv.Atoi(c.Params("id"))
-	if err := h.repo.Delete(uint(id)); err != nil {
+	if _, err := h.repo.GetByIDInOrg(uint(id), orgID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Data tidak ditemukan"})
+	}
+	if err := h.repo.Delete(uint(id), orgID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus data"})
 	}
 	return c.JSON(fiber.Map{"message": "Data berhasil dihapus"})
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"my-flutter-backend/config"
+	"my-flutter-backend/internal/middleware"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// impersonationTTL: Umur token impersonasi (ENV IMPERSONATION_TTL_MINUTES, default 15, maks 60 menit)
+func impersonationTTL() time.Duration {
+	minutes := config.GetEnvAsInt("IMPERSONATION_TTL_MINUTES", 15)
+	if minutes < 1 || minutes > 60 {
+		minutes = 15
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+type ImpersonateRequest struct {
+	Alasan string `json:"alasan"` // Wajib: misal nomor tiket / keluhan pegawai
+}
+
+// Impersonate: Super Admin mendapat access token berumur pendek sebagai pegawai target,
+// untuk melihat tampilan aplikasi mobile persis seperti pegawai tersebut (jadwal, status hari ini, rekap).
+// Token ditandai claim "act" (aktor asli), tanpa session & refresh token.
+// POST /api/admin/asn/:id/impersonate
+func (h *ASNHandler) Impersonate(c *fiber.Ctx) error {
+	var req ImpersonateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
+	}
+	req.Alasan = strings.TrimSpace(req.Alasan)
+	if req.Alasan == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Alasan impersonasi wajib diisi"})
+	}
+
+	id, _ := strconv.Atoi(c.Params("id"))
+	target, err := h.repo.FindByID(uint(id))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pegawai tidak ditemukan"})
+	}
+	if !target.IsActive {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Pegawai nonaktif tidak bisa di-impersonasi"})
+	}
+	// Cegah impersonasi sesama superuser (dan diri sendiri)
+	actorID := uint(c.Locals("user_id").(float64))
+	if target.Role.Superuser || target.ID == actorID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Akun ini tidak bisa di-impersonasi"})
+	}
+
+	actorNIP, _ := c.Locals("nip").(string)
+	now := time.Now()
+	expiresAt := now.Add(impersonationTTL())
+	claims := jwt.MapClaims{
+		"user_id":       target.ID,
+		"nip":           target.NIP,
+		"role":          target.Role.NamaRole,
+		"role_id":       target.RoleID,
+		"organisasi_id": target.OrganisasiID,
+		"act": map[string]interface{}{
+			"user_id": actorID,
+			"nip":     actorNIP,
+		},
+		"iat": now.Unix(),
+		"exp": expiresAt.Unix(),
+	}
+	token, err := config.JWT.Sign(claims)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat token"})
+	}
+
+	middleware.SetAuditNote(c, req.Alasan)
+	return c.JSON(fiber.Map{
+		"message":       "Token impersonasi berhasil dibuat",
+		"access_token":  token,
+		"expires_at":    expiresAt,
+		"impersonation": true,
+		"data": fiber.Map{
+			"id":   target.ID,
+			"nip":  target.NIP,
+			"nama": target.Nama,
+		},
+	})
+}
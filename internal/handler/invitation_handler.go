@@ -0,0 +1,292 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"my-flutter-backend/config"
+	"my-flutter-backend/internal/model"
+	"my-flutter-backend/internal/passwordauth"
+	"my-flutter-backend/internal/repository"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// --- UNDANGAN AKTIVASI AKUN ---
+// CreateASN / ImportASN tanpa password -> akun dibuat dalam status activation_pending dan pegawai
+// menerima link aktivasi di email untuk membuat password sendiri.
+
+const inviteMaxTTLHours = 720 // 30 hari
+
+func inviteTTL() time.Duration {
+	return time.Duration(config.GetEnvAsInt("INVITE_TTL_HOURS", 72)) * time.Hour
+}
+
+func inviteResendCooldown() time.Duration {
+	return time.Duration(config.GetEnvAsInt("INVITE_RESEND_COOLDOWN_SECONDS", 60)) * time.Second
+}
+
+// activationURL: Link aktivasi di email (halaman web / deep link aplikasi yang memanggil /api/activation)
+func activationURL(token string) string {
+	return config.GetEnv("ACTIVATION_URL", "http://localhost:3000/aktivasi") + "?token=" + url.QueryEscape(token)
+}
+
+func activationPendingResponse(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"error":              "Akun Anda belum diaktivasi. Buka link aktivasi yang dikirim ke email Anda.",
+		"activation_pending": true,
+	})
+}
+
+// invitationUnsupported: Alasan akun tidak bisa dibuat lewat undangan ("" = bisa)
+func invitationUnsupported(org *model.Organisasi, email string) string {
+	switch {
+	case org.SSOOnly:
+		return "Organisasi memakai SSO, pegawai login melalui SSO sehingga undangan aktivasi tidak diperlukan"
+	case !passwordauth.LocalManaged(&model.ASN{Organisasi: *org}):
+		return "Password organisasi dikelola direktori LDAP, undangan aktivasi tidak diperlukan"
+	case email == "":
+		return "Email wajib diisi untuk mengirim undangan aktivasi (atau isi password awal)"
+	}
+	return ""
+}
+
+// invitationStatus: Status undangan terakhir untuk laporan aktivasi
+func invitationStatus(inv *model.ASNInvitation) string {
+	switch {
+	case inv == nil:
+		return "BELUM_DIKIRIM"
+	case inv.RevokedAt != nil:
+		return "DIBATALKAN"
+	case !inv.EmailSent:
+		return "GAGAL_KIRIM"
+	case time.Now().After(inv.ExpiresAt):
+		return "KADALUWARSA"
+	}
+	return "MENUNGGU"
+}
+
+// sendInvitation: Buat undangan baru (undangan lama dibatalkan) lalu kirim link aktivasi ke email pegawai.
+// Undangan tetap tersimpan walau email gagal dikirim (email_sent = false) agar terlihat di laporan.
+func (h *ASNHandler) sendInvitation(asn *model.ASN, sentByID *uint, ttl time.Duration) (*model.ASNInvitation, error) {
+	token, err := generateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	inv := model.ASNInvitation{
+		ASNID:     asn.ID,
+		Email:     asn.Email,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+		SentByID:  sentByID,
+	}
+	if err := h.inviteRepo.Create(&inv); err != nil {
+		return nil, err
+	}
+
+	if err := sendActivationEmail(asn.Email, asn.Nama, asn.NIP, activationURL(token), inv.ExpiresAt); err != nil {
+		fmt.Printf("Error sending activation email: %v\n", err)
+		return &inv, err
+	}
+	inv.EmailSent = true
+	h.inviteRepo.Update(&inv)
+	return &inv, nil
+}
+
+func sendActivationEmail(toEmail, namaUser, nip, link string, expiresAt time.Time) error {
+	return sendEmail(toEmail, "Aktivasi Akun Presensi", fmt.Sprintf(`
+		<h3>Halo %s,</h3>
+		<p>Akun presensi Anda dengan NIP <b>%s</b> telah dibuat. Klik link berikut untuk membuat password dan mengaktifkan akun:</p>
+		<p><a href="%s">%s</a></p>
+		<p>Link ini berlaku sampai %s dan hanya bisa dipakai sekali. Jangan berikan kepada siapapun.</p>
+		<p>Jika Anda merasa tidak memerlukan akun ini, abaikan email ini.</p>
+	`, html.EscapeString(namaUser), html.EscapeString(nip), html.EscapeString(link), html.EscapeString(link), expiresAt.Format("02-01-2006 15:04")))
+}
+
+type InvitationRequest struct {
+	ExpiresInHours int `json:"expires_in_hours"` // Opsional, default INVITE_TTL_HOURS
+}
+
+// ResendInvitation: Kirim ulang undangan aktivasi (link lama otomatis tidak berlaku)
+func (h *ASNHandler) ResendInvitation(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
+	actorID := uint(c.Locals("user_id").(float64))
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	var req InvitationRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
+		}
+	}
+	ttl := inviteTTL()
+	if req.ExpiresInHours != 0 {
+		if req.ExpiresInHours < 1 || req.ExpiresInHours > inviteMaxTTLHours {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("expires_in_hours harus antara 1 dan %d", inviteMaxTTLHours)})
+		}
+		ttl = time.Duration(req.ExpiresInHours) * time.Hour
+	}
+
+	asn, err := h.repo.FindByIDInOrg(uint(id), orgID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pegawai tidak ditemukan"})
+	}
+	if !asn.ActivationPending {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Akun pegawai sudah aktif"})
+	}
+	org, err := h.repo.GetOrganisasiByID(asn.OrganisasiID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data organisasi"})
+	}
+	if msg := invitationUnsupported(org, asn.Email); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
+	// Cooldown kirim ulang (hanya jika email sebelumnya berhasil terkirim)
+	if last, err := h.inviteRepo.GetLatestByASN(asn.ID); err == nil && last.EmailSent {
+		if wait := last.CreatedAt.Add(inviteResendCooldown()).Sub(time.Now()); wait > 0 {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error":       fmt.Sprintf("Tunggu %d detik sebelum mengirim ulang undangan", int(wait.Seconds())+1),
+				"retry_after": int(wait.Seconds()) + 1,
+			})
+		}
+	}
+
+	inv, err := h.sendInvitation(asn, &actorID, ttl)
+	if inv == nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat undangan aktivasi"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengirim email undangan. Cek log server."})
+	}
+	return c.JSON(fiber.Map{"message": "Undangan aktivasi telah dikirim ke " + asn.Email, "data": inv})
+}
+
+// RevokeInvitation: Batalkan undangan aktif (misal email salah / dikirim ke orang lain)
+func (h *ASNHandler) RevokeInvitation(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	asn, err := h.repo.FindByIDInOrg(uint(id), orgID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pegawai tidak ditemukan"})
+	}
+	revoked, err := h.inviteRepo.RevokeActive(asn.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membatalkan undangan"})
+	}
+	if revoked == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Tidak ada undangan aktif"})
+	}
+	return c.JSON(fiber.Map{"message": "Undangan aktivasi dibatalkan"})
+}
+
+// GetPendingActivations: Laporan pegawai yang belum aktivasi akun. Filter ?status=BELUM_DIKIRIM/MENUNGGU/KADALUWARSA/GAGAL_KIRIM/DIBATALKAN
+func (h *ASNHandler) GetPendingActivations(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
+	filter := c.Query("status")
+
+	list, err := h.inviteRepo.GetPendingActivations(orgID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data aktivasi"})
+	}
+
+	data := []fiber.Map{}
+	summary := map[string]int{}
+	for _, p := range list {
+		status := invitationStatus(p.LastInvitation)
+		summary[status]++
+		if filter != "" && filter != status {
+			continue
+		}
+		data = append(data, fiber.Map{
+			"asn":              p.ASN,
+			"status":           status,
+			"last_invitation":  p.LastInvitation,
+			"invitation_count": p.InvitationCount,
+		})
+	}
+	return c.JSON(fiber.Map{"data": data, "total": len(list), "ringkasan": summary})
+}
+
+type ActivationRequest struct {
+	Token       string `json:"token"` // Dari link aktivasi di email
+	NewPassword string `json:"new_password"`
+}
+
+// validInvitation: Undangan masih bisa dipakai & akun masih menunggu aktivasi
+func (h *ASNHandler) validInvitation(token string) (*model.ASNInvitation, *model.ASN, bool) {
+	if token == "" {
+		return nil, nil, false
+	}
+	inv, err := h.inviteRepo.GetByTokenHash(hashToken(token))
+	if err != nil || inv.AcceptedAt != nil || inv.RevokedAt != nil || time.Now().After(inv.ExpiresAt) {
+		return nil, nil, false
+	}
+	asn, err := h.repo.FindByIDWithOrganisasi(inv.ASNID)
+	if err != nil || !asn.ActivationPending || !asn.IsActive {
+		return nil, nil, false
+	}
+	return inv, asn, true
+}
+
+func invalidInvitationResponse(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Link aktivasi tidak valid atau sudah kadaluwarsa. Hubungi Admin untuk mengirim ulang undangan."})
+}
+
+// CheckActivation: Dipanggil halaman aktivasi sebelum form password ditampilkan
+func (h *ASNHandler) CheckActivation(c *fiber.Ctx) error {
+	var req ActivationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
+	}
+	inv, asn, ok := h.validInvitation(req.Token)
+	if !ok {
+		return invalidInvitationResponse(c)
+	}
+
+	policy := passwordauth.PolicyFromOrganisasi(&asn.Organisasi)
+	return c.JSON(fiber.Map{
+		"nip":        asn.NIP,
+		"nama":       asn.Nama,
+		"organisasi": asn.Organisasi.NamaOrganisasi,
+		"expires_at": inv.ExpiresAt,
+		"password_policy": fiber.Map{
+			"min_length":     policy.MinLength,
+			"require_upper":  policy.RequireUpper,
+			"require_lower":  policy.RequireLower,
+			"require_digit":  policy.RequireDigit,
+			"require_symbol": policy.RequireSymbol,
+		},
+	})
+}
+
+// Activate: Pegawai membuat password pertama melalui link undangan
+func (h *ASNHandler) Activate(c *fiber.Ctx) error {
+	var req ActivationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
+	}
+	inv, asn, ok := h.validInvitation(req.Token)
+	if !ok {
+		return invalidInvitationResponse(c)
+	}
+
+	hashedPassword, err := h.newPasswordHash(&asn.Organisasi, asn, req.NewPassword)
+	if err != nil {
+		return passwordPolicyResponse(c, err)
+	}
+
+	if err := h.inviteRepo.Accept(inv, asn, hashedPassword); err != nil {
+		if errors.Is(err, repository.ErrInvitationUsed) {
+			return invalidInvitationResponse(c)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengaktifkan akun"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Akun berhasil diaktivasi. Silakan login dengan NIP dan password baru Anda."})
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"fmt"
+	"my-flutter-backend/internal/middleware"
 	"my-flutter-backend/internal/model"
 	"my-flutter-backend/internal/repository"
 	"strconv"
@@ -28,11 +29,29 @@ type CreateJadwalRequest struct {
 	Tanggal string `json:"tanggal"` // Format: YYYY-MM-DD
 }
 
+// jadwalTargetError: Pegawai & shift pada jadwal baru harus milik organisasi yang login (kosong = valid).
+// ID milik organisasi lain diperlakukan sama seperti tidak ada (404).
+func (h *JadwalHandler) jadwalTargetError(orgID uint, asnIDs []uint, shiftID uint) string {
+	if _, err := h.shiftRepo.GetByIDInOrg(shiftID, orgID); err != nil {
+		return "Shift tidak ditemukan"
+	}
+	for _, asnID := range asnIDs {
+		if _, err := h.asnRepo.FindByIDInOrg(asnID, orgID); err != nil {
+			return fmt.Sprintf("Pegawai dengan ID %d tidak ditemukan", asnID)
+		}
+	}
+	return ""
+}
+
 func (h *JadwalHandler) CreateJadwal(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
 	var req CreateJadwalRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
 	}
+	if msg := h.jadwalTargetError(orgID, []uint{req.ASNID}, req.ShiftID); msg != "" {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": msg})
+	}
 
 	jadwal := model.Jadwal{
 		ASNID:    req.ASNID,
@@ -45,6 +64,7 @@ func (h *JadwalHandler) CreateJadwal(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan jadwal"})
 	}
 
+	middleware.SetAuditEntityID(c, jadwal.ID)
 	return c.JSON(fiber.Map{
 		"message": "Jadwal berhasil dibuat",
 		"data":    jadwal,
@@ -65,6 +85,9 @@ func (h *JadwalHandler) GenerateJadwalBulanan(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
 	}
+	if msg := h.jadwalTargetError(orgID, req.ASNIDs, req.ShiftID); msg != "" {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": msg})
+	}
 
 	var listJadwal []model.Jadwal
 
@@ -131,10 +154,14 @@ type GenerateJadwalHarianRequest struct {
 }
 
 func (h *JadwalHandler) GenerateJadwalHarian(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
 	var req GenerateJadwalHarianRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
 	}
+	if msg := h.jadwalTargetError(orgID, req.ASNIDs, req.ShiftID); msg != "" {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": msg})
+	}
 
 	var listJadwal []model.Jadwal
 	for _, asnID := range req.ASNIDs {
@@ -328,6 +355,14 @@ func (h *JadwalHandler) GetJadwalHarian(c *fiber.Ctx) error {
 				status = "IZIN"
 			} else if k.StatusMasuk == "CUTI" {
 				status = "CUTI"
+			} else if k.StatusMasuk == "ALPHA" {
+				status = "ALPHA" // Dicatat sweep ALPHA
+			} else if k.StatusPulang == "TIDAK_ABSEN_PULANG" {
+				// Lupa check-out (ditutup auto-checkout): label sendiri, bukan TL/CP
+				status = "TIDAK_ABSEN_PULANG"
+				if k.PerizinanKehadiranID != nil {
+					status += " (Diizinkan)"
+				}
 			} else if k.StatusMasuk == "TERLAMBAT" || k.StatusPulang == "PULANG_CEPAT" {
 				status = "TL/CP"
 				if k.PerizinanKehadiranID != nil {
@@ -399,6 +434,13 @@ func (h *JadwalHandler) GetJadwalSaya(c *fiber.Ctx) error {
 					status = "IZIN"
 				} else if k.StatusMasuk == "CUTI" {
 					status = "CUTI"
+				} else if k.StatusMasuk == "ALPHA" {
+					status = "ALFA"
+				} else if k.StatusPulang == "TIDAK_ABSEN_PULANG" {
+					status = "TIDAK_ABSEN_PULANG"
+					if k.PerizinanKehadiranID != nil {
+						status += " (Diizinkan)"
+					}
 				} else if k.StatusMasuk == "TERLAMBAT" || k.StatusPulang == "PULANG_CEPAT" {
 					status = "TERLAMBAT"
 					if k.PerizinanKehadiranID != nil {
@@ -437,8 +479,9 @@ func (h *JadwalHandler) GetJadwalSaya(c *fiber.Ctx) error {
 }
 
 func (h *JadwalHandler) GetJadwalDetail(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
 	id, _ := strconv.Atoi(c.Params("id"))
-	jadwal, err := h.repo.GetByID(uint(id))
+	jadwal, err := h.repo.GetByIDInOrg(uint(id), orgID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Jadwal tidak ditemukan"})
 	}
@@ -451,19 +494,26 @@ type UpdateJadwalRequest struct {
 }
 
 func (h *JadwalHandler) UpdateJadwal(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
 	id, _ := strconv.Atoi(c.Params("id"))
 	var req UpdateJadwalRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
 	}
 
-	jadwal, err := h.repo.GetByID(uint(id))
+	jadwal, err := h.repo.GetByIDInOrg(uint(id), orgID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Jadwal tidak ditemukan"})
 	}
 
 	if req.ShiftID != 0 {
-		jadwal.ShiftID = req.ShiftID
+		// Shift harus milik organisasi yang sama
+		shift, err := h.shiftRepo.GetByIDInOrg(req.ShiftID, orgID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Shift tidak ditemukan"})
+		}
+		jadwal.ShiftID = shift.ID
+		jadwal.Shift = *shift
 	}
 	if req.IsActive != nil {
 		jadwal.IsActive = *req.IsActive
@@ -474,8 +524,12 @@ func (h *JadwalHandler) UpdateJadwal(c *fiber.Ctx) error {
 }
 
 func (h *JadwalHandler) DeleteJadwal(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
 	id, _ := strconv.Atoi(c.Params("id"))
-	if err := h.repo.Delete(uint(id)); err != nil {
+	if _, err := h.repo.GetByIDInOrg(uint(id), orgID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Jadwal tidak ditemukan"})
+	}
+	if err := h.repo.Delete(uint(id), orgID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus jadwal"})
 	}
 	return c.JSON(fiber.Map{"message": "Jadwal berhasil dihapus"})
@@ -539,12 +593,14 @@ func (h *JadwalHandler) GetDashboardStats(c *fiber.Ctx) error {
 	hadir := 0
 	tlCp := 0
 	tlCpIzin := 0
+	tap := 0
+	tapIzin := 0
 	izin := 0
 	cuti := 0
 	alfa := 0
 	belumAbsen := 0
 
-	statsHari := map[string]int{"hadir_tepat_waktu": 0, "tl_cp": 0, "tl_cp_diizinkan": 0, "izin": 0, "cuti": 0, "alfa": 0, "belum_absen": 0}
+	statsHari := map[string]int{"hadir_tepat_waktu": 0, "tl_cp": 0, "tl_cp_diizinkan": 0, "tap": 0, "tap_diizinkan": 0, "izin": 0, "cuti": 0, "alfa": 0, "belum_absen": 0}
 
 	var details []fiber.Map
 
@@ -563,6 +619,16 @@ func (h *JadwalHandler) GetDashboardStats(c *fiber.Ctx) error {
 				izin++
 			} else if k.StatusMasuk == "CUTI" {
 				cuti++
+			} else if k.StatusMasuk == "ALPHA" {
+				statusMasuk = "ALFA"
+				alfa++
+			} else if k.StatusPulang == "TIDAK_ABSEN_PULANG" {
+				// Dihitung terpisah dari TL/CP; status_pulang di detail tetap TIDAK_ABSEN_PULANG
+				if k.PerizinanKehadiranID != nil {
+					tapIzin++
+				} else {
+					tap++
+				}
 			} else if k.StatusMasuk == "TERLAMBAT" || k.StatusPulang == "PULANG_CEPAT" {
 				if k.PerizinanKehadiranID != nil {
 					tlCpIzin++
@@ -597,6 +663,14 @@ func (h *JadwalHandler) GetDashboardStats(c *fiber.Ctx) error {
 					statsHari["izin"]++
 				} else if k.StatusMasuk == "CUTI" {
 					statsHari["cuti"]++
+				} else if k.StatusMasuk == "ALPHA" {
+					statsHari["alfa"]++
+				} else if k.StatusPulang == "TIDAK_ABSEN_PULANG" {
+					if k.PerizinanKehadiranID != nil {
+						statsHari["tap_diizinkan"]++
+					} else {
+						statsHari["tap"]++
+					}
 				} else if k.StatusMasuk == "TERLAMBAT" || k.StatusPulang == "PULANG_CEPAT" {
 					if k.PerizinanKehadiranID != nil {
 						statsHari["tl_cp_diizinkan"]++
@@ -612,11 +686,11 @@ func (h *JadwalHandler) GetDashboardStats(c *fiber.Ctx) error {
 		}
 	}
 
-	// Hitung Persentase Kehadiran Bulanan (Hadir + TL/CP) / (Total Jadwal - Belum Absen)
+	// Hitung Persentase Kehadiran Bulanan (Hadir + TL/CP + TAP) / (Total Jadwal - Belum Absen)
 	totalSudahLewat := totalJadwal - belumAbsen
 	persentaseHadir := 0.0
 	if totalSudahLewat > 0 {
-		hadirCount := hadir + tlCp + tlCpIzin
+		hadirCount := hadir + tlCp + tlCpIzin + tap + tapIzin
 		persentaseHadir = (float64(hadirCount) / float64(totalSudahLewat)) * 100
 	}
 
@@ -630,6 +704,8 @@ func (h *JadwalHandler) GetDashboardStats(c *fiber.Ctx) error {
 			"hadir_tepat_waktu": hadir,
 			"tl_cp":             tlCp,
 			"tl_cp_diizinkan":   tlCpIzin,
+			"tap":               tap,
+			"tap_diizinkan":     tapIzin,
 			"izin":              izin,
 			"cuti":              cuti,
 			"alfa":              alfa,
@@ -3,8 +3,12 @@ package handler
 import (
 	"fmt"
 	"math"
+	"my-flutter-backend/internal/faceverify"
+	"my-flutter-backend/internal/geofence"
+	"my-flutter-backend/internal/jobs"
 	"my-flutter-backend/internal/model"
 	"my-flutter-backend/internal/repository"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -15,15 +19,18 @@ type KehadiranHandler struct {
 	asnRepo    repository.ASNRepository
 	jadwalRepo repository.JadwalRepository     // Tambah ini
 	orgRepo    repository.OrganisasiRepository // Tambah ini (Organisasi)
+	fotoRepo   repository.FotoKehadiranRepository
+	verifier   faceverify.FaceVerifier // nil = verifikasi wajah tidak tersedia di server
 }
 
-func NewKehadiranHandler(repo repository.KehadiranRepository, asnRepo repository.ASNRepository, jadwalRepo repository.JadwalRepository, orgRepo repository.OrganisasiRepository) *KehadiranHandler {
-	return &KehadiranHandler{repo: repo, asnRepo: asnRepo, jadwalRepo: jadwalRepo, orgRepo: orgRepo}
+func NewKehadiranHandler(repo repository.KehadiranRepository, asnRepo repository.ASNRepository, jadwalRepo repository.JadwalRepository, orgRepo repository.OrganisasiRepository, fotoRepo repository.FotoKehadiranRepository, verifier faceverify.FaceVerifier) *KehadiranHandler {
+	return &KehadiranHandler{repo: repo, asnRepo: asnRepo, jadwalRepo: jadwalRepo, orgRepo: orgRepo, fotoRepo: fotoRepo, verifier: verifier}
 }
 
+// CheckInRequest: JSON biasa, atau multipart/form-data jika menyertakan file "selfie"
 type CheckInRequest struct {
-	Latitude  float64 `json:"latitude"`
-	Longitude float64 `json:"longitude"`
+	Latitude  float64 `json:"latitude" form:"latitude"`
+	Longitude float64 `json:"longitude" form:"longitude"`
 }
 
 func (h *KehadiranHandler) CheckIn(c *fiber.Ctx) error {
@@ -52,53 +59,62 @@ func (h *KehadiranHandler) CheckIn(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Jadwal kerja hari ini belum ditentukan. Hubungi Admin."})
 	}
 
+	// Parse Jam Masuk dari Shift (Format "07:30")
+	jamMasukShift, _ := time.Parse("15:04", jadwal.Shift.JamMasuk)
+	// Gabungkan dengan tanggal hari ini agar bisa dibandingin
+	waktuMasukShift := time.Date(now.Year(), now.Month(), now.Day(), jamMasukShift.Hour(), jamMasukShift.Minute(), 0, 0, now.Location())
+
+	// Jendela Check-in (per shift)
+	if m := jadwal.Shift.CheckInMulaiMenit; m > 0 {
+		if mulai := waktuMasukShift.Add(-time.Duration(m) * time.Minute); now.Before(mulai) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("Check-in shift %s baru dibuka pukul %s", jadwal.Shift.NamaShift, mulai.Format("15:04"))})
+		}
+	}
+	if m := jadwal.Shift.CheckInBatasMenit; m > 0 {
+		if batas := waktuMasukShift.Add(time.Duration(m) * time.Minute); now.After(batas) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("Batas check-in shift %s pukul %s sudah lewat. Silakan ajukan izin/koreksi.", jadwal.Shift.NamaShift, batas.Format("15:04"))})
+		}
+	}
+
 	// 4. Ambil Semua Lokasi Kantor & Validasi Radius
 	org, err := h.orgRepo.GetByID(orgID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Data organisasi tidak ditemukan"})
 	}
 
-	statusLokasiMasuk := "INVALID"
-	minJarak := math.MaxFloat64
-	var validLokasiID *uint
-
 	if len(org.Lokasis) == 0 {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Belum ada lokasi kantor yang disetting"})
 	}
 
-	for i := range org.Lokasis {
-		loc := &org.Lokasis[i]
-		jarak := calculateDistance(req.Latitude, req.Longitude, loc.Latitude, loc.Longitude)
-
-		if jarak <= float64(loc.RadiusMeter) {
-			statusLokasiMasuk = "VALID"
-			validLokasiID = &loc.ID
-			minJarak = jarak
-			break // Found valid location, stop searching
-		}
+	asn, err := h.asnRepo.FindByID(asnID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Data pegawai tidak ditemukan"})
+	}
 
-		// Keep track of closest location even if invalid
-		if jarak < minJarak {
-			minJarak = jarak
-		}
+	match := cekLokasiASN(org.Lokasis, asn, req.Latitude, req.Longitude)
+	statusLokasiMasuk := "INVALID"
+	var validLokasiID *uint
+	if match.Valid {
+		statusLokasiMasuk = "VALID"
+		validLokasiID = &match.Lokasi.ID
 	}
 
 	// Use closest distance for reporting
-	jarak := minJarak
+	jarak := match.Jarak
 
 	// 5. Tentukan Status (HADIR / TERLAMBAT)
 	statusMasuk := "HADIR"
 
-	// Parse Jam Masuk dari Shift (Format "07:30")
-	jamMasukShift, _ := time.Parse("15:04", jadwal.Shift.JamMasuk)
-	// Gabungkan dengan tanggal hari ini agar bisa dibandingin
-	waktuMasukShift := time.Date(now.Year(), now.Month(), now.Day(), jamMasukShift.Hour(), jamMasukShift.Minute(), 0, 0, now.Location())
-
-	// Jika waktu sekarang > waktu shift, maka TERLAMBAT
-	if now.After(waktuMasukShift) {
+	// Jika waktu sekarang > waktu shift + toleransi, maka TERLAMBAT
+	if now.After(waktuMasukShift.Add(time.Duration(jadwal.Shift.ToleransiTerlambatMenit) * time.Minute)) {
 		statusMasuk = "TERLAMBAT"
 	}
 
+	foto, ok := prepareSelfie(c, org, asnID, "MASUK", req, now)
+	if !ok {
+		return nil
+	}
+
 	kehadiran := model.Kehadiran{
 		ASNID:             asnID,
 		JadwalID:          jadwal.ID,     // Simpan ID Jadwal
@@ -112,15 +128,31 @@ func (h *KehadiranHandler) CheckIn(c *fiber.Ctx) error {
 		Bulan:             now.Format("01"),
 	}
 
-	if err := h.repo.Create(kehadiran); err != nil {
+	// Verifikasi Wajah (jika diaktifkan organisasi & tersedia di server)
+	if org.FaceVerifyEnabled && h.verifier != nil {
+		if foto == nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Selfie wajib dilampirkan untuk verifikasi wajah (field: selfie)"})
+		}
+		if err := verifyCheckInFace(h.verifier, org, asn, foto, &kehadiran); err != nil {
+			removeSelfie(foto)
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Wajah pada selfie tidak cocok dengan foto referensi. Silakan ulangi atau hubungi admin."})
+		}
+	}
+
+	if err := h.repo.SaveWithFoto(&kehadiran, foto); err != nil {
+		removeSelfie(foto)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan absensi"})
 	}
 
 	return c.JSON(fiber.Map{
-		"message": "Check-in berhasil",
-		"status":  statusMasuk,
-		"waktu":   kehadiran.JamMasukReal,
-		"jarak":   jarak,
+		"message":           "Check-in berhasil",
+		"status":            statusMasuk,
+		"waktu":             kehadiran.JamMasukReal,
+		"jarak":             jarak,
+		"selfie":            selfieURL(foto),
+		"face_status":       kehadiran.FaceStatus,
+		"lokasi":            lokasiInfo(validLokasi(match)),
+		"keterangan_lokasi": match.Keterangan,
 	})
 }
 
@@ -155,6 +187,19 @@ func (h *KehadiranHandler) CheckOut(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Anda sedang Cuti/Izin, tidak perlu Check-out"})
 	}
 
+	if attendance.StatusMasuk == "ALPHA" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Anda tercatat tidak hadir (ALPHA) pada jadwal ini"})
+	}
+
+	// Sudah ditutup auto-checkout: perbaikan hanya lewat koreksi kehadiran
+	if attendance.StatusPulang == jobs.StatusTidakAbsenPulang {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":         "Batas check-out sudah lewat, kehadiran tercatat tidak absen pulang. Silakan ajukan koreksi.",
+			"status":        jobs.StatusTidakAbsenPulang,
+			"tanggal_absen": attendance.Tanggal,
+		})
+	}
+
 	if attendance.JamPulangReal != "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Anda sudah melakukan Check-out"})
 	}
@@ -176,25 +221,18 @@ func (h *KehadiranHandler) CheckOut(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Belum ada lokasi kantor yang disetting"})
 	}
 
-	statusLokasiPulang := "INVALID"
-	minJarak := math.MaxFloat64
-
-	for i := range org.Lokasis {
-		loc := &org.Lokasis[i]
-		jarak := calculateDistance(req.Latitude, req.Longitude, loc.Latitude, loc.Longitude)
-
-		if jarak <= float64(loc.RadiusMeter) {
-			statusLokasiPulang = "VALID"
-			minJarak = jarak
-			break
-		}
+	asn, err := h.asnRepo.FindByID(asnID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Data pegawai tidak ditemukan"})
+	}
 
-		if jarak < minJarak {
-			minJarak = jarak
-		}
+	match := cekLokasiASN(org.Lokasis, asn, req.Latitude, req.Longitude)
+	statusLokasiPulang := "INVALID"
+	if match.Valid {
+		statusLokasiPulang = "VALID"
 	}
 
-	jarak := minJarak
+	jarak := match.Jarak
 
 	// 5. Update Data Pulang
 	now := time.Now()
@@ -217,6 +255,30 @@ func (h *KehadiranHandler) CheckOut(c *fiber.Ctx) error {
 		waktuPulangShift = waktuPulangShift.AddDate(0, 0, 1) // Tambah 1 hari
 	}
 
+	// Jendela Check-out (per shift)
+	if m := jadwal.Shift.CheckOutMulaiMenit; m > 0 {
+		if mulai := waktuPulangShift.Add(-time.Duration(m) * time.Minute); now.Before(mulai) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("Check-out shift %s baru dibuka pukul %s", jadwal.Shift.NamaShift, mulai.Format("15:04"))})
+		}
+	}
+	if m := jadwal.Shift.CheckOutBatasMenit; m > 0 {
+		if batas := waktuPulangShift.Add(time.Duration(m) * time.Minute); now.After(batas) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":         fmt.Sprintf("Batas check-out shift %s pukul %s sudah lewat. Silakan ajukan koreksi kehadiran.", jadwal.Shift.NamaShift, batas.Format("15:04")),
+				"tanggal_absen": attendance.Tanggal,
+			})
+		}
+	}
+
+	// Batas auto-checkout (per shift, jam): lewat batas, check-out ditolak & kehadiran akan ditutup auto-checkout
+	if limit := jobs.CheckoutLimit(jadwal.Shift); limit > 0 && now.After(waktuPulangShift.Add(limit)) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":         fmt.Sprintf("Batas check-out (%s) sudah lewat. Silakan ajukan koreksi kehadiran.", waktuPulangShift.Add(limit).Format("2006-01-02 15:04")),
+			"status":        jobs.StatusTidakAbsenPulang,
+			"tanggal_absen": attendance.Tanggal,
+		})
+	}
+
 	// Bandingkan Now dengan Waktu Pulang Seharusnya
 	statusPulang := "PULANG"
 	if now.Before(waktuPulangShift) {
@@ -227,16 +289,25 @@ func (h *KehadiranHandler) CheckOut(c *fiber.Ctx) error {
 	// Validasi Radius Pulang
 	attendance.StatusLokasiPulang = statusLokasiPulang
 
-	if err := h.repo.Update(attendance); err != nil {
+	foto, ok := prepareSelfie(c, org, asnID, "PULANG", req, now)
+	if !ok {
+		return nil
+	}
+
+	if err := h.repo.SaveWithFoto(attendance, foto); err != nil {
+		removeSelfie(foto)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan data pulang"})
 	}
 
 	return c.JSON(fiber.Map{
-		"message":       "Check-out berhasil",
-		"status":        statusPulang,
-		"waktu":         attendance.JamPulangReal,
-		"jarak":         jarak,
-		"tanggal_absen": attendance.Tanggal,
+		"message":           "Check-out berhasil",
+		"status":            statusPulang,
+		"waktu":             attendance.JamPulangReal,
+		"jarak":             jarak,
+		"tanggal_absen":     attendance.Tanggal,
+		"selfie":            selfieURL(foto),
+		"lokasi":            lokasiInfo(validLokasi(match)),
+		"keterangan_lokasi": match.Keterangan,
 	})
 }
 
@@ -283,6 +354,7 @@ func (h *KehadiranHandler) GetRekap(c *fiber.Ctx) error {
 	terlambat := 0
 	izin := 0
 	cuti := 0
+	alpha := 0
 
 	for _, k := range data {
 		if k.StatusMasuk == "HADIR" {
@@ -297,6 +369,9 @@ func (h *KehadiranHandler) GetRekap(c *fiber.Ctx) error {
 		if k.StatusMasuk == "CUTI" {
 			cuti++
 		}
+		if k.StatusMasuk == "ALPHA" {
+			alpha++
+		}
 	}
 
 	return c.JSON(fiber.Map{
@@ -306,6 +381,7 @@ func (h *KehadiranHandler) GetRekap(c *fiber.Ctx) error {
 			"terlambat": terlambat,
 			"izin":      izin,
 			"cuti":      cuti,
+			"alpha":     alpha,
 			"detail":    data,
 		},
 	})
@@ -366,6 +442,7 @@ func (h *KehadiranHandler) GetTodayStatus(c *fiber.Ctx) error {
 
 func (h *KehadiranHandler) CheckLocationValidity(c *fiber.Ctx) error {
 	// 1. Ambil Data User
+	asnID := uint(c.Locals("user_id").(float64))
 	orgID := uint(c.Locals("organisasi_id").(float64))
 
 	var req CheckInRequest
@@ -373,6 +450,11 @@ func (h *KehadiranHandler) CheckLocationValidity(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
 	}
 
+	asn, err := h.asnRepo.FindByID(asnID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Data pegawai tidak ditemukan"})
+	}
+
 	// 2. Ambil Organisasi & Lokasi
 	org, err := h.orgRepo.GetByID(orgID)
 	if err != nil {
@@ -384,49 +466,145 @@ func (h *KehadiranHandler) CheckLocationValidity(c *fiber.Ctx) error {
 	}
 
 	// 3. Cek Lokasi (Multi-Location Logic)
+	match := cekLokasiASN(org.Lokasis, asn, req.Latitude, req.Longitude)
 	statusLokasi := "INVALID"
-	minJarak := math.MaxFloat64
-	var lokasiTerdekat interface{}
-
-	for i := range org.Lokasis {
-		loc := &org.Lokasis[i]
-		jarak := calculateDistance(req.Latitude, req.Longitude, loc.Latitude, loc.Longitude)
-
-		if jarak <= float64(loc.RadiusMeter) {
-			statusLokasi = "VALID"
-			minJarak = jarak
-			lokasiTerdekat = fiber.Map{
-				"id":           loc.ID,
-				"nama_lokasi":  loc.NamaLokasi,
-				"alamat":       loc.Alamat,
-				"latitude":     loc.Latitude,
-				"longitude":    loc.Longitude,
-				"radius_meter": loc.RadiusMeter,
-			}
-			break // Found valid location
-		}
-
-		if jarak < minJarak {
-			minJarak = jarak
-			lokasiTerdekat = fiber.Map{
-				"id":           loc.ID,
-				"nama_lokasi":  loc.NamaLokasi,
-				"alamat":       loc.Alamat,
-				"latitude":     loc.Latitude,
-				"longitude":    loc.Longitude,
-				"radius_meter": loc.RadiusMeter,
-			}
-		}
+	if match.Valid {
+		statusLokasi = "VALID"
 	}
 
 	return c.JSON(fiber.Map{
 		"message":         "Pengecekan lokasi berhasil",
 		"status_lokasi":   statusLokasi,
-		"jarak_terdekat":  minJarak,
-		"lokasi_terdekat": lokasiTerdekat,
+		"jarak_terdekat":  match.Jarak,
+		"lokasi_terdekat": lokasiInfo(match.Lokasi),
+		"keterangan":      match.Keterangan,
 	})
 }
 
+type lokasiCheck struct {
+	lokasiMatch
+	Keterangan string // Penjelasan jika INVALID (mis. berada di lokasi yang tidak diizinkan)
+}
+
+// cekLokasiASN: matchLokasi terbatas pada lokasi yang diizinkan untuk pegawai (aturan akses lokasi)
+func cekLokasiASN(lokasis []model.Lokasi, asn *model.ASN, lat, lng float64) lokasiCheck {
+	var diizinkan, lainnya []model.Lokasi
+	for _, loc := range lokasis {
+		if lokasiDiizinkan(&loc, asn) {
+			diizinkan = append(diizinkan, loc)
+		} else {
+			lainnya = append(lainnya, loc)
+		}
+	}
+
+	check := lokasiCheck{lokasiMatch: matchLokasi(diizinkan, lat, lng)}
+	if check.Valid {
+		return check
+	}
+	if len(diizinkan) == 0 {
+		check.Jarak = 0 // Tidak ada lokasi untuk diukur
+	}
+
+	nama := make([]string, 0, len(diizinkan))
+	for _, loc := range diizinkan {
+		nama = append(nama, loc.NamaLokasi)
+	}
+	tidakDiizinkan := matchLokasi(lainnya, lat, lng)
+	switch {
+	case tidakDiizinkan.Valid:
+		check.Keterangan = fmt.Sprintf("Anda berada di area %s, tetapi lokasi tersebut tidak diizinkan untuk absensi Anda.", tidakDiizinkan.Lokasi.NamaLokasi)
+		if len(nama) > 0 {
+			check.Keterangan += " Lokasi yang diizinkan: " + strings.Join(nama, ", ")
+		}
+	case len(diizinkan) == 0:
+		check.Keterangan = "Belum ada lokasi absen yang diizinkan untuk Anda. Hubungi admin."
+	default:
+		check.Keterangan = fmt.Sprintf("Di luar area lokasi absen (terdekat: %s, %.0f meter)", check.Lokasi.NamaLokasi, check.Jarak)
+	}
+	return check
+}
+
+// lokasiDiizinkan: Lokasi tanpa aturan akses terbuka untuk semua pegawai organisasi
+func lokasiDiizinkan(loc *model.Lokasi, asn *model.ASN) bool {
+	if len(loc.Akses) == 0 {
+		return true
+	}
+	for _, a := range loc.Akses {
+		switch a.Tipe {
+		case "ASN":
+			if a.ASNID != nil && *a.ASNID == asn.ID {
+				return true
+			}
+		case "BIDANG":
+			if asn.Bidang != "" && strings.EqualFold(strings.TrimSpace(asn.Bidang), a.Nilai) {
+				return true
+			}
+		case "JABATAN":
+			if asn.Jabatan != "" && strings.EqualFold(strings.TrimSpace(asn.Jabatan), a.Nilai) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func validLokasi(check lokasiCheck) *model.Lokasi {
+	if !check.Valid {
+		return nil
+	}
+	return check.Lokasi
+}
+
+type lokasiMatch struct {
+	Lokasi *model.Lokasi // Lokasi valid pertama, atau lokasi terdekat jika tidak ada yang valid
+	Jarak  float64       // Meter. Lokasi geofence: jarak ke tepi polygon (0 = di dalam)
+	Valid  bool
+}
+
+// matchLokasi: Cari lokasi kantor yang mencakup titik absen (polygon geofence jika ada, selain itu radius)
+func matchLokasi(lokasis []model.Lokasi, lat, lng float64) lokasiMatch {
+	best := lokasiMatch{Jarak: math.MaxFloat64}
+	for i := range lokasis {
+		loc := &lokasis[i]
+		jarak, valid := jarakLokasi(loc, lat, lng)
+		if valid {
+			return lokasiMatch{Lokasi: loc, Jarak: jarak, Valid: true}
+		}
+		// Keep track of closest location even if invalid
+		if jarak < best.Jarak {
+			best.Lokasi = loc
+			best.Jarak = jarak
+		}
+	}
+	return best
+}
+
+func jarakLokasi(loc *model.Lokasi, lat, lng float64) (float64, bool) {
+	if len(loc.Geofence) > 0 {
+		if g, err := geofence.Parse(loc.Geofence); err == nil {
+			return g.DistanceMeter(lat, lng), g.Contains(lat, lng)
+		}
+		// Geofence rusak (disimpan di luar API): jatuh ke radius
+	}
+	jarak := calculateDistance(lat, lng, loc.Latitude, loc.Longitude)
+	return jarak, jarak <= loc.RadiusMeter
+}
+
+func lokasiInfo(loc *model.Lokasi) interface{} {
+	if loc == nil {
+		return nil
+	}
+	return fiber.Map{
+		"id":           loc.ID,
+		"nama_lokasi":  loc.NamaLokasi,
+		"alamat":       loc.Alamat,
+		"latitude":     loc.Latitude,
+		"longitude":    loc.Longitude,
+		"radius_meter": loc.RadiusMeter,
+		"geofence":     loc.Geofence,
+	}
+}
+
 // Rumus Haversine untuk menghitung jarak dua titik koordinat (dalam meter)
 func calculateDistance(lat1, lon1, lat2, lon2 float64) float64 {
 	const R = 6371000 // Radius bumi dalam meter
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"my-flutter-backend/internal/model"
+	"my-flutter-backend/internal/repository"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+func newThrottleTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&model.LoginThrottle{}, &model.LoginLockoutEvent{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func newThrottleHandler(db *gorm.DB) *ASNHandler {
+	return NewASNHandler(repository.NewASNRepository(db), repository.NewSessionRepository(db), repository.NewOTPRepository(db),
+		repository.NewLoginThrottleRepository(db), repository.NewTwoFactorRepository(db), repository.NewSSORepository(db),
+		repository.NewDeviceRepository(db), repository.NewInvitationRepository(db), repository.NewRoleRepository(db), nil)
+}
+
+// Kegagalan paralel tidak boleh saling menimpa penghitung
+func TestRegisterLoginFailureConcurrent(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILED_PER_IP", "100")
+	db := newThrottleTestDB(t)
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1) // SQLite: statement diserialkan, tapi request tetap bisa saling menyela
+	h := newThrottleHandler(db)
+
+	const n = 30
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.registerLoginFailure(loginScopeIP, "10.0.0.1", "10.0.0.1")
+		}()
+	}
+	wg.Wait()
+
+	var throttle model.LoginThrottle
+	if err := db.Where("scope = ? AND `key` = ?", loginScopeIP, "10.0.0.1").First(&throttle).Error; err != nil {
+		t.Fatalf("throttle row: %v", err)
+	}
+	if throttle.FailedCount != n {
+		t.Fatalf("failed_count = %d, want %d", throttle.FailedCount, n)
+	}
+}
+
+func TestRegisterLoginFailureLocks(t *testing.T) {
+	db := newThrottleTestDB(t)
+	h := newThrottleHandler(db)
+
+	for i := 1; i < 5; i++ {
+		if until, remaining := h.registerLoginFailure(loginScopeNIP, "199001012020011001", "10.0.0.1"); until != nil || remaining != 5-i {
+			t.Fatalf("failure %d: until=%v remaining=%d", i, until, remaining)
+		}
+	}
+	until, _ := h.registerLoginFailure(loginScopeNIP, "199001012020011001", "10.0.0.1")
+	if until == nil {
+		t.Fatal("expected lock after 5 failures")
+	}
+
+	var throttle model.LoginThrottle
+	db.Where("scope = ? AND `key` = ?", loginScopeNIP, "199001012020011001").First(&throttle)
+	if throttle.LockLevel != 1 || throttle.FailedCount != 0 || throttle.LockedUntil == nil {
+		t.Fatalf("throttle after lock = %+v", throttle)
+	}
+	var events int64
+	db.Model(&model.LoginLockoutEvent{}).Where("event = ?", "LOCKED").Count(&events)
+	if events != 1 {
+		t.Fatalf("LOCKED events = %d, want 1", events)
+	}
+}
+
+// NIP yang tidak terdaftar hanya dihitung per IP (tidak membuat baris throttle NIP)
+func TestLoginUnknownNIPCountsIPOnly(t *testing.T) {
+	db := newThrottleTestDB(t)
+	h := newThrottleHandler(db)
+	org := model.Organisasi{NamaOrganisasi: "Org"}
+	mustCreate(t, db, &org)
+	hash, _ := bcrypt.GenerateFromPassword([]byte("rahasia123"), bcrypt.MinCost)
+	mustCreate(t, db, &model.ASN{NIP: "199001012020011001", Nama: "Pegawai", Password: string(hash), OrganisasiID: org.ID, IsActive: true})
+
+	app := fiber.New()
+	app.Post("/api/login", h.Login)
+
+	for i := 0; i < 3; i++ {
+		if status, body := doRequest(t, app, "POST", "/api/login", `{"nip":"999999999999999999","password":"x"}`); status != fiber.StatusUnauthorized {
+			t.Fatalf("unknown NIP: status %d: %s", status, body)
+		}
+	}
+	if status, body := doRequest(t, app, "POST", "/api/login", `{"nip":"199001012020011001","password":"salah"}`); status != fiber.StatusUnauthorized {
+		t.Fatalf("wrong password: status %d: %s", status, body)
+	}
+
+	var nipRows []model.LoginThrottle
+	db.Where("scope = ?", loginScopeNIP).Find(&nipRows)
+	if len(nipRows) != 1 || nipRows[0].Key != "199001012020011001" || nipRows[0].FailedCount != 1 {
+		t.Fatalf("NIP throttle rows = %+v, want only the registered NIP", nipRows)
+	}
+	var ip model.LoginThrottle
+	db.Where("scope = ?", loginScopeIP).First(&ip)
+	if ip.FailedCount != 4 {
+		t.Fatalf("IP failed_count = %d, want 4", ip.FailedCount)
+	}
+}
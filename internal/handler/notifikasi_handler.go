@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"my-flutter-backend/internal/repository"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type NotifikasiHandler struct {
+	repo repository.NotifikasiRepository
+}
+
+func NewNotifikasiHandler(repo repository.NotifikasiRepository) *NotifikasiHandler {
+	return &NotifikasiHandler{repo: repo}
+}
+
+// GetAll: 50 notifikasi terbaru milik pegawai + jumlah yang belum dibaca
+func (h *NotifikasiHandler) GetAll(c *fiber.Ctx) error {
+	asnID := uint(c.Locals("user_id").(float64))
+	list, err := h.repo.GetByASNID(asnID, 50)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil notifikasi"})
+	}
+	unread, _ := h.repo.CountUnread(asnID)
+	return c.JSON(fiber.Map{"data": list, "belum_dibaca": unread})
+}
+
+func (h *NotifikasiHandler) MarkRead(c *fiber.Ctx) error {
+	asnID := uint(c.Locals("user_id").(float64))
+	id, _ := strconv.Atoi(c.Params("id"))
+	if err := h.repo.MarkRead(uint(id), asnID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Notifikasi tidak ditemukan"})
+	}
+	return c.JSON(fiber.Map{"message": "Notifikasi ditandai sudah dibaca"})
+}
+
+func (h *NotifikasiHandler) MarkAllRead(c *fiber.Ctx) error {
+	asnID := uint(c.Locals("user_id").(float64))
+	if err := h.repo.MarkAllRead(asnID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memperbarui notifikasi"})
+	}
+	return c.JSON(fiber.Map{"message": "Semua notifikasi ditandai sudah dibaca"})
+}
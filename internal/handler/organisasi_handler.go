@@ -1,9 +1,18 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"my-flutter-backend/internal/faceverify"
+	"my-flutter-backend/internal/geofence"
+	"my-flutter-backend/internal/middleware"
 	"my-flutter-backend/internal/model"
+	"my-flutter-backend/internal/oidc"
+	"my-flutter-backend/internal/passwordauth"
 	"my-flutter-backend/internal/repository"
 	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -29,6 +38,162 @@ func (h *OrganisasiHandler) GetInfo(c *fiber.Ctx) error {
 type UpdateOrganisasiRequest struct {
 	NamaOrganisasi string `json:"nama_organisasi"`
 	EmailAdmin     string `json:"email_admin"`
+	SSOOnly        *bool  `json:"sso_only"`    // Opsional: wajibkan login SSO untuk pegawai organisasi ini
+	MaxDevices     *int   `json:"max_devices"` // Opsional: batas HP terikat per pegawai (1-10)
+
+	// Opsional: kebijakan password organisasi
+	PasswordMinLength     *int  `json:"password_min_length"`
+	PasswordRequireUpper  *bool `json:"password_require_upper"`
+	PasswordRequireLower  *bool `json:"password_require_lower"`
+	PasswordRequireDigit  *bool `json:"password_require_digit"`
+	PasswordRequireSymbol *bool `json:"password_require_symbol"`
+	PasswordHistory       *int  `json:"password_history"`
+	PasswordExpiryDays    *int  `json:"password_expiry_days"`
+
+	// Opsional: selfie bukti absen
+	SelfieWajib       *bool `json:"selfie_wajib"`
+	SelfieRetensiHari *int  `json:"selfie_retensi_hari"` // 0 = simpan selamanya, maksimal 3650 hari
+
+	// Opsional: verifikasi wajah selfie check-in
+	FaceVerifyEnabled   *bool    `json:"face_verify_enabled"`
+	FaceMatchThreshold  *float64 `json:"face_match_threshold"`  // 0 < ambang <= 1
+	FaceRejectThreshold *float64 `json:"face_reject_threshold"` // 0 = tidak pernah menolak, harus <= face_match_threshold
+
+	// Opsional, hanya pemegang kelola_organisasi: backend password & koneksi LDAP
+	PasswordBackends *string `json:"password_backends"` // Urutan fallback, misal "ldap,local"
+	LDAPURL          *string `json:"ldap_url"`
+	LDAPUserDN       *string `json:"ldap_user_dn"`
+	LDAPStartTLS     *bool   `json:"ldap_start_tls"`
+}
+
+func (req *UpdateOrganisasiRequest) hasPasswordBackendSettings() bool {
+	return req.PasswordBackends != nil || req.LDAPURL != nil || req.LDAPUserDN != nil || req.LDAPStartTLS != nil
+}
+
+var errSSONotConfigured = errors.New("SSO belum dikonfigurasi")
+
+// applySSOOnly: Ubah pengaturan SSO-only (hanya bisa diaktifkan jika SSO sudah dikonfigurasi di server)
+func applySSOOnly(org *model.Organisasi, ssoOnly *bool) error {
+	if ssoOnly == nil {
+		return nil
+	}
+	if *ssoOnly && !oidc.Enabled() {
+		return errSSONotConfigured
+	}
+	org.SSOOnly = *ssoOnly
+	return nil
+}
+
+// applyMaxDevices: Ubah batas device per pegawai (device yang sudah terikat tidak ikut dilepas)
+func applyMaxDevices(org *model.Organisasi, max *int) error {
+	if max == nil {
+		return nil
+	}
+	if *max < 1 || *max > maxDevicesLimit {
+		return fmt.Errorf("max_devices harus antara 1 dan %d", maxDevicesLimit)
+	}
+	org.MaxDevices = *max
+	return nil
+}
+
+const maxSelfieRetensiHari = 3650
+
+// applySelfiePolicy: Ubah kewajiban & retensi selfie. Retensi baru ikut berlaku untuk selfie lama saat purge berikutnya.
+func applySelfiePolicy(org *model.Organisasi, req *UpdateOrganisasiRequest) error {
+	if req.SelfieRetensiHari != nil {
+		if *req.SelfieRetensiHari < 0 || *req.SelfieRetensiHari > maxSelfieRetensiHari {
+			return fmt.Errorf("selfie_retensi_hari harus antara 0 dan %d", maxSelfieRetensiHari)
+		}
+		org.SelfieRetensiHari = *req.SelfieRetensiHari
+	}
+	if req.SelfieWajib != nil {
+		org.SelfieWajib = *req.SelfieWajib
+	}
+	return nil
+}
+
+var errFaceVerifierNotConfigured = errors.New("Verifikasi wajah belum dikonfigurasi di server (FACE_VERIFIER)")
+
+// applyFaceVerify: Ubah pengaturan verifikasi wajah (hanya bisa diaktifkan jika server punya verifier)
+func applyFaceVerify(org *model.Organisasi, req *UpdateOrganisasiRequest) error {
+	if req.FaceVerifyEnabled != nil {
+		if *req.FaceVerifyEnabled && !faceverify.Enabled() {
+			return errFaceVerifierNotConfigured
+		}
+		org.FaceVerifyEnabled = *req.FaceVerifyEnabled
+	}
+	if req.FaceMatchThreshold != nil {
+		org.FaceMatchThreshold = *req.FaceMatchThreshold
+	}
+	if req.FaceRejectThreshold != nil {
+		org.FaceRejectThreshold = *req.FaceRejectThreshold
+	}
+	if org.FaceMatchThreshold <= 0 || org.FaceMatchThreshold > 1 {
+		return errors.New("face_match_threshold harus lebih dari 0 dan maksimal 1")
+	}
+	if org.FaceRejectThreshold < 0 || org.FaceRejectThreshold > org.FaceMatchThreshold {
+		return errors.New("face_reject_threshold harus antara 0 dan face_match_threshold")
+	}
+	return nil
+}
+
+// applyPasswordPolicy: Ubah kebijakan password (berlaku untuk password baru, password lama tidak dipaksa ganti kecuali kadaluwarsa)
+func applyPasswordPolicy(org *model.Organisasi, req *UpdateOrganisasiRequest) error {
+	if req.PasswordMinLength != nil {
+		org.PasswordMinLength = *req.PasswordMinLength
+	}
+	if req.PasswordRequireUpper != nil {
+		org.PasswordRequireUpper = *req.PasswordRequireUpper
+	}
+	if req.PasswordRequireLower != nil {
+		org.PasswordRequireLower = *req.PasswordRequireLower
+	}
+	if req.PasswordRequireDigit != nil {
+		org.PasswordRequireDigit = *req.PasswordRequireDigit
+	}
+	if req.PasswordRequireSymbol != nil {
+		org.PasswordRequireSymbol = *req.PasswordRequireSymbol
+	}
+	if req.PasswordHistory != nil {
+		org.PasswordHistory = *req.PasswordHistory
+	}
+	if req.PasswordExpiryDays != nil {
+		org.PasswordExpiryDays = *req.PasswordExpiryDays
+	}
+
+	policy := passwordauth.PolicyFromOrganisasi(org)
+	if req.PasswordMinLength != nil {
+		policy.MinLength = *req.PasswordMinLength // Nilai dari admin, bukan default
+	}
+	return policy.ValidateSettings()
+}
+
+// applyPasswordBackends: Ubah backend password organisasi. LDAP wajib punya URL & template DN yang valid.
+func applyPasswordBackends(org *model.Organisasi, req *UpdateOrganisasiRequest) error {
+	if req.LDAPURL != nil {
+		org.LDAPURL = strings.TrimSpace(*req.LDAPURL)
+	}
+	if req.LDAPUserDN != nil {
+		org.LDAPUserDN = strings.TrimSpace(*req.LDAPUserDN)
+	}
+	if req.LDAPStartTLS != nil {
+		org.LDAPStartTLS = *req.LDAPStartTLS
+	}
+	if req.PasswordBackends != nil {
+		org.PasswordBackends = *req.PasswordBackends
+	}
+
+	order, err := passwordauth.ParseOrder(org.PasswordBackends)
+	if err != nil {
+		return err
+	}
+	org.PasswordBackends = strings.Join(order, ",")
+	for _, name := range order {
+		if name == passwordauth.BackendLDAP {
+			return passwordauth.LDAPConfigFromOrganisasi(org).Validate()
+		}
+	}
+	return nil
 }
 
 func (h *OrganisasiHandler) UpdateOrganisasi(c *fiber.Ctx) error {
@@ -49,6 +214,30 @@ func (h *OrganisasiHandler) UpdateOrganisasi(c *fiber.Ctx) error {
 		org.NamaOrganisasi = req.NamaOrganisasi
 	}
 	org.EmailAdmin = req.EmailAdmin // Email boleh kosong/diupdate
+	if err := applySSOOnly(org, req.SSOOnly); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Login SSO belum dikonfigurasi di server, SSO-only tidak bisa diaktifkan"})
+	}
+	if err := applyMaxDevices(org, req.MaxDevices); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := applyPasswordPolicy(org, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := applySelfiePolicy(org, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := applyFaceVerify(org, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if req.hasPasswordBackendSettings() {
+		// Server LDAP menentukan siapa yang bisa login -> tidak boleh diatur admin organisasi sendiri
+		if !middleware.HasPermission(c, "kelola_organisasi") {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Backend password hanya bisa diatur oleh pengelola organisasi (kelola_organisasi)"})
+		}
+		if err := applyPasswordBackends(org, &req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
 
 	if err := h.repo.Update(org); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal update organisasi"})
@@ -63,16 +252,40 @@ type UpdateLokasiRequest struct {
 	Latitude    float64 `json:"latitude"`
 	Longitude   float64 `json:"longitude"`
 	RadiusMeter float64 `json:"radius_meter"`
+	// Geofence: GeoJSON Polygon/MultiPolygon/Feature. Tidak dikirim = tetap (update), null = hapus polygon.
+	Geofence json.RawMessage `json:"geofence"`
+}
+
+// applyGeofence: Validasi & simpan geofence dalam bentuk geometry yang dinormalisasi
+func applyGeofence(lokasi *model.Lokasi, raw json.RawMessage) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	if string(raw) == "null" {
+		lokasi.Geofence = nil
+		return nil
+	}
+	g, err := geofence.Parse(raw)
+	if err != nil {
+		return err
+	}
+	normalized, err := g.JSON()
+	if err != nil {
+		return err
+	}
+	lokasi.Geofence = normalized
+	return nil
 }
 
 func (h *OrganisasiHandler) UpdateLokasi(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
 	id, _ := strconv.Atoi(c.Params("id"))
 	var req UpdateLokasiRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
 	}
 
-	lokasi, err := h.repo.GetLokasiByID(uint(id))
+	lokasi, err := h.repo.GetLokasiByIDInOrg(uint(id), orgID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Lokasi tidak ditemukan"})
 	}
@@ -82,6 +295,9 @@ func (h *OrganisasiHandler) UpdateLokasi(c *fiber.Ctx) error {
 	lokasi.Latitude = req.Latitude
 	lokasi.Longitude = req.Longitude
 	lokasi.RadiusMeter = req.RadiusMeter
+	if err := applyGeofence(lokasi, req.Geofence); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
 
 	if err := h.repo.UpdateLokasi(lokasi); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal update lokasi"})
@@ -105,18 +321,80 @@ func (h *OrganisasiHandler) AddLokasi(c *fiber.Ctx) error {
 		Longitude:    req.Longitude,
 		RadiusMeter:  req.RadiusMeter,
 	}
+	if err := applyGeofence(&lokasi, req.Geofence); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
 
 	if err := h.repo.CreateLokasi(&lokasi); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menambah lokasi"})
 	}
 
+	middleware.SetAuditEntityID(c, lokasi.ID)
 	return c.JSON(fiber.Map{"message": "Lokasi berhasil ditambahkan", "data": lokasi})
 }
 
+type LokasiAksesRequest struct {
+	Akses []struct {
+		Tipe  string `json:"tipe"` // ASN, BIDANG, JABATAN
+		ASNID *uint  `json:"asn_id"`
+		Nilai string `json:"nilai"`
+	} `json:"akses"`
+}
+
+// UpdateLokasiAkses: Atur pegawai yang boleh absen di lokasi ini (per ASN, bidang, atau jabatan).
+// akses kosong = semua pegawai organisasi (default).
+func (h *OrganisasiHandler) UpdateLokasiAkses(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
+	id, _ := strconv.Atoi(c.Params("id"))
+	var req LokasiAksesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
+	}
+
+	lokasi, err := h.repo.GetLokasiByIDInOrg(uint(id), orgID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Lokasi tidak ditemukan"})
+	}
+
+	akses := make([]model.LokasiAkses, 0, len(req.Akses))
+	for i, a := range req.Akses {
+		aturan := model.LokasiAkses{Tipe: strings.ToUpper(strings.TrimSpace(a.Tipe))}
+		switch aturan.Tipe {
+		case "ASN":
+			if a.ASNID == nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("Aturan ke-%d: asn_id wajib diisi", i+1)})
+			}
+			if _, err := h.asnRepo.FindByIDInOrg(*a.ASNID, orgID); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("Aturan ke-%d: pegawai tidak ditemukan", i+1)})
+			}
+			aturan.ASNID = a.ASNID
+		case "BIDANG", "JABATAN":
+			aturan.Nilai = strings.TrimSpace(a.Nilai)
+			if aturan.Nilai == "" {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("Aturan ke-%d: nilai wajib diisi", i+1)})
+			}
+		default:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("Aturan ke-%d: tipe harus ASN, BIDANG, atau JABATAN", i+1)})
+		}
+		akses = append(akses, aturan)
+	}
+
+	if err := h.repo.ReplaceLokasiAkses(lokasi.ID, akses); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan akses lokasi"})
+	}
+
+	lokasi.Akses = akses
+	return c.JSON(fiber.Map{"message": "Akses lokasi berhasil diperbarui", "data": lokasi})
+}
+
 func (h *OrganisasiHandler) DeleteLokasi(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
 	id, _ := strconv.Atoi(c.Params("id"))
 
-	if err := h.repo.DeleteLokasi(uint(id)); err != nil {
+	if _, err := h.repo.GetLokasiByIDInOrg(uint(id), orgID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Lokasi tidak ditemukan"})
+	}
+	if err := h.repo.DeleteLokasi(uint(id), orgID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus lokasi"})
 	}
 
@@ -145,6 +423,7 @@ func (h *OrganisasiHandler) CreateOrganisasi(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat organisasi"})
 	}
 
+	middleware.SetAuditEntityID(c, org.ID)
 	return c.JSON(fiber.Map{"message": "Organisasi berhasil dibuat", "data": org})
 }
 
@@ -173,6 +452,24 @@ func (h *OrganisasiHandler) UpdateOrganisasiByID(c *fiber.Ctx) error {
 		org.NamaOrganisasi = req.NamaOrganisasi
 	}
 	org.EmailAdmin = req.EmailAdmin
+	if err := applySSOOnly(org, req.SSOOnly); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Login SSO belum dikonfigurasi di server, SSO-only tidak bisa diaktifkan"})
+	}
+	if err := applyMaxDevices(org, req.MaxDevices); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := applyPasswordPolicy(org, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := applySelfiePolicy(org, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := applyFaceVerify(org, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := applyPasswordBackends(org, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
 
 	if err := h.repo.Update(org); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal update organisasi"})
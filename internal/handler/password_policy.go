@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"errors"
+	"my-flutter-backend/internal/model"
+	"my-flutter-backend/internal/passwordauth"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// --- KEBIJAKAN PASSWORD ORGANISASI ---
+// Berlaku untuk setiap password baru: CreateASN, ImportASN, ChangePassword, ResetUserPassword, ResetPasswordFinal.
+
+var errPasswordReused = errors.New("password pernah dipakai")
+
+// newPasswordHash: Validasi password baru (kebijakan organisasi + riwayat password) lalu hash bcrypt.
+// asn.ID == 0 untuk akun yang belum dibuat (riwayat tidak dicek).
+func (h *ASNHandler) newPasswordHash(org *model.Organisasi, asn *model.ASN, password string) (string, error) {
+	policy := passwordauth.PolicyFromOrganisasi(org)
+	if err := policy.Validate(password, asn.NIP); err != nil {
+		return "", err
+	}
+
+	if asn.ID != 0 && policy.History > 0 {
+		hashes, _ := h.repo.GetPasswordHistory(asn.ID, policy.History)
+		if len(hashes) == 0 && asn.Password != "" {
+			hashes = []string{asn.Password} // Akun lama yang belum punya riwayat
+		}
+		for _, hash := range hashes {
+			if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+				return "", errPasswordReused
+			}
+		}
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hashed), err
+}
+
+// passwordPolicyResponse: Respons untuk error dari newPasswordHash
+func passwordPolicyResponse(c *fiber.Ctx, err error) error {
+	var policyErr *passwordauth.PolicyError
+	switch {
+	case errors.As(err, &policyErr):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": policyErr.Error(), "violations": policyErr.Violations})
+	case errors.Is(err, errPasswordReused):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Password sudah pernah dipakai sebelumnya, gunakan password lain"})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengenkripsi password"})
+}
+
+// passwordChangeRequired: Flag must_change_password di respons login (akun baru, setelah reset admin, atau password kadaluwarsa).
+// Hanya untuk akun yang password-nya dikelola aplikasi (bukan SSO-only / direktori LDAP).
+func passwordChangeRequired(asn *model.ASN) (mustChange bool, expired bool) {
+	if ssoRequired(asn) || !passwordauth.LocalManaged(asn) {
+		return false, false
+	}
+	changedAt := asn.CreatedAt
+	if asn.PasswordChangedAt != nil {
+		changedAt = *asn.PasswordChangedAt
+	}
+	expired = passwordauth.PolicyFromOrganisasi(&asn.Organisasi).Expired(changedAt)
+	return asn.MustChangePassword || expired, expired
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"fmt"
+	"my-flutter-backend/internal/middleware"
 	"my-flutter-backend/internal/model"
 	"my-flutter-backend/internal/repository"
 	"os"
@@ -246,18 +247,18 @@ func (h *PerizinanHandler) ProcessApproval(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
 	}
 
-	// Ambil data izin
-	izin, err := h.repo.GetByID(req.PerizinanID)
+	// Ambil data izin (hanya pegawai satu organisasi)
+	orgID := uint(c.Locals("organisasi_id").(float64))
+	izin, err := h.repo.GetByIDInOrg(req.PerizinanID, orgID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Data perizinan tidak ditemukan"})
 	}
 
 	// Validasi: Pastikan yang approve adalah Atasan yang sesuai
 	nipUser := c.Locals("nip").(string)
-	roleUser := c.Locals("role").(string)
 
-	// Kita izinkan Admin untuk override (jaga-jaga), tapi utamanya harus Atasan yang bersangkutan
-	if izin.NIPAtasan != nipUser && roleUser != "Admin" {
+	// Pemegang permission override_approval boleh override (jaga-jaga), tapi utamanya harus Atasan yang bersangkutan
+	if izin.NIPAtasan != nipUser && !middleware.HasPermission(c, "override_approval") {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Anda bukan atasan dari pegawai ini"})
 	}
 
@@ -337,17 +338,17 @@ func (h *PerizinanHandler) ApproveCancel(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
 	}
 
-	// 1. Ambil data izin
-	izin, err := h.repo.GetByID(req.PerizinanID)
+	// 1. Ambil data izin (hanya pegawai satu organisasi)
+	orgID := uint(c.Locals("organisasi_id").(float64))
+	izin, err := h.repo.GetByIDInOrg(req.PerizinanID, orgID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Data perizinan tidak ditemukan"})
 	}
 
-	// 2. Validasi: Pastikan yang approve adalah Atasan atau Admin
+	// 2. Validasi: Pastikan yang approve adalah Atasan atau pemegang override_approval
 	nipUser := c.Locals("nip").(string)
-	roleUser := c.Locals("role").(string)
 
-	if izin.NIPAtasan != nipUser && roleUser != "Admin" {
+	if izin.NIPAtasan != nipUser && !middleware.HasPermission(c, "override_approval") {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Anda bukan atasan dari pegawai ini"})
 	}
 
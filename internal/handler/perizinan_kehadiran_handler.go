@@ -108,7 +108,8 @@ func (h *PerizinanKehadiranHandler) ProcessApproval(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
 	}
 
-	koreksi, err := h.repo.GetByID(req.KoreksiID)
+	orgID := uint(c.Locals("organisasi_id").(float64))
+	koreksi, err := h.repo.GetByIDInOrg(req.KoreksiID, orgID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Data tidak ditemukan"})
 	}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"fmt"
 	"my-flutter-backend/internal/model"
 	"my-flutter-backend/internal/repository"
 	"time"
@@ -12,13 +13,15 @@ type ReportHandler struct {
 	jadwalRepo    repository.JadwalRepository
 	kehadiranRepo repository.KehadiranRepository
 	asnRepo       repository.ASNRepository
+	fotoRepo      repository.FotoKehadiranRepository
 }
 
-func NewReportHandler(jadwalRepo repository.JadwalRepository, kehadiranRepo repository.KehadiranRepository, asnRepo repository.ASNRepository) *ReportHandler {
+func NewReportHandler(jadwalRepo repository.JadwalRepository, kehadiranRepo repository.KehadiranRepository, asnRepo repository.ASNRepository, fotoRepo repository.FotoKehadiranRepository) *ReportHandler {
 	return &ReportHandler{
 		jadwalRepo:    jadwalRepo,
 		kehadiranRepo: kehadiranRepo,
 		asnRepo:       asnRepo,
+		fotoRepo:      fotoRepo,
 	}
 }
 
@@ -75,7 +78,7 @@ func (h *ReportHandler) GetMonthlyRecap(c *fiber.Ctx) error {
 		}
 
 		// Counters
-		tl, cp, tk, cuti, izin := 0, 0, 0, 0, 0
+		tl, cp, tap, tk, cuti, izin := 0, 0, 0, 0, 0, 0
 		t1, t2, t3, t4 := 0, 0, 0, 0
 		totalJadwal := 0
 
@@ -110,8 +113,8 @@ func (h *ReportHandler) GetMonthlyRecap(c *fiber.Ctx) error {
 					hasIzinLokasi := k.PerizinanLokasiID != nil
 					isCutiOrIzin := k.StatusMasuk == "CUTI" || k.StatusMasuk == "IZIN"
 
-					if !isLokasiValid && !hasIzinLokasi && !isCutiOrIzin {
-						// Lokasi Invalid & Tidak Ada Izin & Bukan Cuti/Izin -> Hitung TK
+					if k.StatusMasuk == "ALPHA" || (!isLokasiValid && !hasIzinLokasi && !isCutiOrIzin) {
+						// ALPHA (sweep) atau Lokasi Invalid & Tidak Ada Izin & Bukan Cuti/Izin -> Hitung TK
 						code = "-"
 						tk++
 					} else {
@@ -122,15 +125,15 @@ func (h *ReportHandler) GetMonthlyRecap(c *fiber.Ctx) error {
 						} else if k.StatusMasuk == "IZIN" {
 							code = "I"
 							izin++
-						} else if k.StatusMasuk == "HADIR" || k.StatusMasuk == "TERLAMBAT" || k.StatusPulang == "PULANG_CEPAT" {
+						} else if k.StatusMasuk == "HADIR" || k.StatusMasuk == "TERLAMBAT" || k.StatusPulang == "PULANG_CEPAT" || k.StatusPulang == "TIDAK_ABSEN_PULANG" {
 							code = "H" // Tetap H di tabel
 
 							// Hitung TL / CP hanya jika TIDAK ADA IZIN STATUS (PerizinanKehadiranID == nil)
 							if k.PerizinanKehadiranID == nil {
-								if k.StatusMasuk == "TERLAMBAT" {
+								// Hitung Range Keterlambatan (masih dalam toleransi shift tidak dihitung TL)
+								minutesLate, late := calculateMinutesLate(jadwal.Shift, k.JamMasukReal)
+								if k.StatusMasuk == "TERLAMBAT" && late {
 									tl++
-									// Hitung Range Keterlambatan
-									minutesLate := calculateMinutesLate(jadwal.Shift.JamMasuk, k.JamMasukReal)
 									if minutesLate <= 30 {
 										t1++
 									} else if minutesLate <= 60 {
@@ -143,20 +146,16 @@ func (h *ReportHandler) GetMonthlyRecap(c *fiber.Ctx) error {
 								}
 								if k.StatusPulang == "PULANG_CEPAT" {
 									cp++
+								} else if k.StatusPulang == "TIDAK_ABSEN_PULANG" {
+									tap++ // Lupa check-out (ditutup auto-checkout), bukan pulang cepat
 								}
 							}
 						}
 					}
 				} else {
-					// Tidak ada absen tapi jadwal aktif -> TK (Tanpa Keterangan)
-					// Hanya jika tanggal sudah lewat
-					if dateStr < time.Now().Format("2006-01-02") {
-						code = "-"
-						tk++
-					} else {
-						// Future or Today (not passed yet) -> Empty White Cell
-						code = " "
-					}
+					// Belum ada baris kehadiran -> sel kosong. TK hanya dari baris ALPHA yang dibuat sweep
+					// (sweep sudah melewati hari libur, cuti/izin disetujui, dan shift yang belum selesai)
+					code = " "
 				}
 			}
 
@@ -166,7 +165,7 @@ func (h *ReportHandler) GetMonthlyRecap(c *fiber.Ctx) error {
 
 		row["daily"] = dailyCodes
 		row["stats"] = fiber.Map{
-			"tl": tl, "cp": cp, "tk": tk, "c": cuti, "i": izin,
+			"tl": tl, "cp": cp, "tap": tap, "tk": tk, "c": cuti, "i": izin,
 			"t1": t1, "t2": t2, "t3": t3, "t4": t4,
 			"total_kehadiran": totalJadwal - tk - cuti - izin,
 		}
@@ -239,6 +238,8 @@ func (h *ReportHandler) GetMonthlyRecapByAtasan(c *fiber.Ctx) error {
 		totalHadirTepatWaktu int
 		totalTlCp            int // Terlambat / Pulang Cepat (Tanpa Izin Status)
 		totalTlCpDiizinkan   int // Terlambat / Pulang Cepat (Dengan Izin Status)
+		totalTap             int // Tidak Absen Pulang / ditutup auto-checkout (Tanpa Izin Status)
+		totalTapDiizinkan    int // Tidak Absen Pulang (Dengan Izin Status)
 		totalIzin            int
 		totalCuti            int
 		totalAlfa            int
@@ -247,7 +248,6 @@ func (h *ReportHandler) GetMonthlyRecapByAtasan(c *fiber.Ctx) error {
 	)
 
 	daysInMonth := getDaysInMonth(bulan, tahun)
-	todayStr := time.Now().Format("2006-01-02")
 
 	for _, asn := range asns {
 		// Iterate through all days in the month for this ASN
@@ -277,8 +277,8 @@ func (h *ReportHandler) GetMonthlyRecapByAtasan(c *fiber.Ctx) error {
 					hasIzinLokasi := k.PerizinanLokasiID != nil
 					isCutiOrIzin := k.StatusMasuk == "CUTI" || k.StatusMasuk == "IZIN"
 
-					if !isLokasiValid && !hasIzinLokasi && !isCutiOrIzin {
-						// Invalid Lokasi & No Permit & Not Cuti/Izin -> Treat as TK (Alfa)
+					if k.StatusMasuk == "ALPHA" || (!isLokasiValid && !hasIzinLokasi && !isCutiOrIzin) {
+						// ALPHA (sweep) atau Invalid Lokasi & No Permit & Not Cuti/Izin -> Treat as TK (Alfa)
 						totalAlfa++
 					} else {
 						// Valid Attendance Logic
@@ -294,7 +294,14 @@ func (h *ReportHandler) GetMonthlyRecapByAtasan(c *fiber.Ctx) error {
 							// Cek apakah ada masalah kehadiran (Telat atau Pulang Cepat)
 							hasIssue := k.StatusMasuk == "TERLAMBAT" || k.StatusPulang == "PULANG_CEPAT"
 
-							if k.PerizinanKehadiranID != nil {
+							if k.StatusPulang == "TIDAK_ABSEN_PULANG" {
+								// Lupa check-out dihitung terpisah dari TL/CP
+								if k.PerizinanKehadiranID != nil {
+									totalTapDiizinkan++
+								} else {
+									totalTap++
+								}
+							} else if k.PerizinanKehadiranID != nil {
 								// Ada Izin Status
 								if hasIssue {
 									totalTlCpDiizinkan++
@@ -314,14 +321,8 @@ func (h *ReportHandler) GetMonthlyRecapByAtasan(c *fiber.Ctx) error {
 						}
 					}
 				} else {
-					// Tidak ada absen tapi jadwal aktif
-					if dateStr < todayStr {
-						// Date passed -> TK (Alfa)
-						totalAlfa++
-					} else {
-						// Future or Today -> Belum Absen
-						totalBelumAbsen++
-					}
+					// Belum ada baris kehadiran (alfa hanya dari baris ALPHA hasil sweep)
+					totalBelumAbsen++
 				}
 			}
 		}
@@ -334,6 +335,8 @@ func (h *ReportHandler) GetMonthlyRecapByAtasan(c *fiber.Ctx) error {
 				"hadir_tepat_waktu": totalHadirTepatWaktu,
 				"tl_cp":             totalTlCp,
 				"tl_cp_diizinkan":   totalTlCpDiizinkan,
+				"tap":               totalTap,
+				"tap_diizinkan":     totalTapDiizinkan,
 				"izin":              totalIzin,
 				"cuti":              totalCuti,
 				"alfa":              totalAlfa,
@@ -360,8 +363,17 @@ func (h *ReportHandler) GetDailyRecap(c *fiber.Ctx) error {
 	// 2. Ambil Kehadiran Hari Ini
 	kehadirans, _ := h.kehadiranRepo.GetByDateAndOrg(tanggal, orgID)
 	attendanceMap := make(map[uint]model.Kehadiran)
+	kehadiranIDs := make([]uint, 0, len(kehadirans))
 	for _, k := range kehadirans {
 		attendanceMap[k.JadwalID] = k
+		kehadiranIDs = append(kehadiranIDs, k.ID)
+	}
+
+	// 3. Selfie Bukti Absen (key: kehadiranID + jenis, foto terbaru menimpa yang lama)
+	fotos, _ := h.fotoRepo.GetByKehadiranIDs(kehadiranIDs)
+	fotoMap := make(map[string]*model.FotoKehadiran)
+	for i := range fotos {
+		fotoMap[fmt.Sprintf("%d_%s", fotos[i].KehadiranID, fotos[i].Jenis)] = &fotos[i]
 	}
 
 	var reportData []fiber.Map
@@ -372,17 +384,20 @@ func (h *ReportHandler) GetDailyRecap(c *fiber.Ctx) error {
 		// Di contoh PDF Harian, semua pegawai muncul.
 
 		row := fiber.Map{
-			"nip":        j.ASN.NIP,
-			"nama":       j.ASN.Nama,
-			"masuk":      "-",
-			"pulang":     "-",
-			"keterangan": "",
+			"nip":           j.ASN.NIP,
+			"nama":          j.ASN.Nama,
+			"masuk":         "-",
+			"pulang":        "-",
+			"keterangan":    "",
+			"selfie_masuk":  nil,
+			"selfie_pulang": nil,
 		}
 
 		if !j.IsActive {
 			// Jadwal Libur
 			row["keterangan"] = "Libur"
 		} else {
+			// Tidak ada absen -> keterangan kosong sampai sweep ALPHA mencatat TK
 			if k, exists := attendanceMap[j.ID]; exists {
 				// Cek Validitas Lokasi / Izin Lokasi
 				showMasuk := false
@@ -403,6 +418,11 @@ func (h *ReportHandler) GetDailyRecap(c *fiber.Ctx) error {
 				if showPulang {
 					row["pulang"] = formatTime(k.JamPulangReal)
 				}
+				row["selfie_masuk"] = selfieInfo(fotoMap[fmt.Sprintf("%d_MASUK", k.ID)])
+				row["selfie_pulang"] = selfieInfo(fotoMap[fmt.Sprintf("%d_PULANG", k.ID)])
+				row["face_status"] = k.FaceStatus
+				row["face_score"] = k.FaceScore
+				row["face_review_status"] = k.FaceReviewStatus
 
 				// Keterangan Logic
 				labelIzin := ""
@@ -414,18 +434,18 @@ func (h *ReportHandler) GetDailyRecap(c *fiber.Ctx) error {
 					row["keterangan"] = "Izin"
 				} else if k.StatusMasuk == "CUTI" {
 					row["keterangan"] = "Cuti"
+				} else if k.StatusMasuk == "ALPHA" {
+					row["keterangan"] = "TK"
 				} else if k.StatusMasuk == "TERLAMBAT" {
 					row["keterangan"] = "TL" + labelIzin
 				} else if k.StatusPulang == "PULANG_CEPAT" {
 					row["keterangan"] = "CP" + labelIzin
+				} else if k.StatusPulang == "TIDAK_ABSEN_PULANG" {
+					row["keterangan"] = "TAP" + labelIzin // Tidak Absen Pulang
 				}
 
 				// Izin Status override keterangan? Atau append?
 				// "TK" logic?
-			} else {
-				// Tidak ada absen -> TK (jika sudah lewat jamnya / tanggalnya)
-				// Asumsi report di generate sore/besoknya
-				row["keterangan"] = "TK"
 			}
 		}
 
@@ -441,6 +461,20 @@ func (h *ReportHandler) GetDailyRecap(c *fiber.Ctx) error {
 
 // Helper Functions
 
+// selfieInfo: Metadata selfie untuk laporan (url kosong jika file sudah dihapus retensi)
+func selfieInfo(foto *model.FotoKehadiran) fiber.Map {
+	if foto == nil {
+		return nil
+	}
+	return fiber.Map{
+		"url":        selfieURL(foto),
+		"diambil_at": foto.DiambilAt,
+		"latitude":   foto.Latitude,
+		"longitude":  foto.Longitude,
+		"dihapus_at": foto.DihapusAt,
+	}
+}
+
 func getDaysInMonth(monthStr, yearStr string) int {
 	year := parseYear(yearStr)
 	month := time.Month(parseMonth(monthStr))
@@ -457,19 +491,29 @@ func parseMonth(m string) int {
 	return int(t.Month())
 }
 
-func calculateMinutesLate(scheduleTime, actualTime string) int {
-	sched, _ := time.Parse("15:04", scheduleTime)
+// calculateMinutesLate: Menit keterlambatan dihitung dari jam masuk shift. late = false jika check-in masih
+// dalam toleransi terlambat shift (aturan yang sama dengan CheckIn).
+// Check-in yang jauh lebih awal dari jendela check-in dianggap lewat tengah malam (shift lintas hari, misal masuk 23:00, absen 00:10).
+func calculateMinutesLate(shift model.Shift, actualTime string) (int, bool) {
+	sched, _ := time.Parse("15:04", shift.JamMasuk)
 	act, _ := time.Parse("15:04:05", actualTime) // format jam masuk real biasanya ada detiknya
 
 	// Normalize date
 	schedBase := time.Date(2000, 1, 1, sched.Hour(), sched.Minute(), 0, 0, time.UTC)
 	actBase := time.Date(2000, 1, 1, act.Hour(), act.Minute(), act.Second(), 0, time.UTC)
 
-	if actBase.After(schedBase) {
-		diff := actBase.Sub(schedBase)
-		return int(diff.Minutes())
+	window := time.Duration(shift.CheckInMulaiMenit) * time.Minute
+	if window <= 0 {
+		window = 12 * time.Hour // Tanpa jendela check-in: pakai jam masuk terdekat
+	}
+	if actBase.Before(schedBase.Add(-window)) {
+		actBase = actBase.AddDate(0, 0, 1)
+	}
+
+	if !actBase.After(schedBase.Add(time.Duration(shift.ToleransiTerlambatMenit) * time.Minute)) {
+		return 0, false
 	}
-	return 0
+	return int(actBase.Sub(schedBase).Minutes()), true
 }
 
 func formatTime(t string) string {
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"encoding/json"
+	"my-flutter-backend/internal/model"
+	"my-flutter-backend/internal/repository"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TK di rekap bulanan hanya dari baris ALPHA hasil sweep, jadwal lampau tanpa kehadiran tidak ditebak sebagai TK
+func TestMonthlyRecapCountsTKFromAlphaOnly(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&model.Kehadiran{}, &model.FotoKehadiran{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	org := model.Organisasi{NamaOrganisasi: "Org A"}
+	mustCreate(t, db, &org)
+	shift := model.Shift{OrganisasiID: org.ID, NamaShift: "Pagi", JamMasuk: "07:30", JamPulang: "16:00"}
+	mustCreate(t, db, &shift)
+	asn := model.ASN{OrganisasiID: org.ID, Nama: "Pegawai", NIP: "P-1", IsActive: true}
+	mustCreate(t, db, &asn)
+
+	alpha := model.Jadwal{ASNID: asn.ID, ShiftID: shift.ID, Tanggal: "2025-03-03", IsActive: true}
+	mustCreate(t, db, &alpha)
+	mustCreate(t, db, &model.Kehadiran{ASNID: asn.ID, JadwalID: alpha.ID, Tanggal: "2025-03-03", StatusMasuk: "ALPHA", StatusPulang: "ALPHA", Tahun: "2025", Bulan: "03"})
+	// Misal hari libur / cuti yang dilewati sweep: tidak ada baris kehadiran
+	mustCreate(t, db, &model.Jadwal{ASNID: asn.ID, ShiftID: shift.ID, Tanggal: "2025-03-04", IsActive: true})
+
+	hdl := NewReportHandler(repository.NewJadwalRepository(db), repository.NewKehadiranRepository(db), repository.NewASNRepository(db), repository.NewFotoKehadiranRepository(db))
+	app := fiber.New()
+	app.Use(asOrg(asn.ID, org.ID))
+	app.Get("/api/admin/reports/monthly", hdl.GetMonthlyRecap)
+
+	status, body := doRequest(t, app, "GET", "/api/admin/reports/monthly?bulan=03&tahun=2025", "")
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d (body: %s)", status, body)
+	}
+	var resp struct {
+		Data []struct {
+			Daily map[string]string `json:"daily"`
+			Stats map[string]int    `json:"stats"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(body), &resp); err != nil || len(resp.Data) != 1 {
+		t.Fatalf("decode: %v (body: %s)", err, body)
+	}
+	row := resp.Data[0]
+	if row.Daily["03"] != "-" || row.Daily["04"] != " " {
+		t.Errorf("daily = %q/%q, want \"-\"/\" \"", row.Daily["03"], row.Daily["04"])
+	}
+	if row.Stats["tk"] != 1 {
+		t.Errorf("tk = %d, want 1", row.Stats["tk"])
+	}
+}
+
+func TestCalculateMinutesLate(t *testing.T) {
+	pagi := model.Shift{JamMasuk: "07:30", ToleransiTerlambatMenit: 15, CheckInMulaiMenit: 60}
+	malam := model.Shift{JamMasuk: "23:00", ToleransiTerlambatMenit: 15, CheckInMulaiMenit: 60}
+	malamTanpaJendela := model.Shift{JamMasuk: "23:00"}
+
+	cases := []struct {
+		name     string
+		shift    model.Shift
+		actual   string
+		wantMins int
+		wantLate bool
+	}{
+		{"Early", pagi, "06:45:00", 0, false},
+		{"WithinTolerance", pagi, "07:45:00", 0, false},
+		{"Late", pagi, "08:10:00", 40, true},
+		{"CrossDayEarly", malam, "22:30:00", 0, false},
+		{"CrossDayAfterMidnight", malam, "00:10:00", 70, true},
+		{"CrossDayNoWindow", malamTanpaJendela, "00:10:00", 70, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mins, late := calculateMinutesLate(tc.shift, tc.actual)
+			if mins != tc.wantMins || late != tc.wantLate {
+				t.Fatalf("calculateMinutesLate(%s, %s) = %d, %v; want %d, %v", tc.shift.JamMasuk, tc.actual, mins, late, tc.wantMins, tc.wantLate)
+			}
+		})
+	}
+}
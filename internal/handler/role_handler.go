@@ -1,11 +1,15 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
+	"my-flutter-backend/internal/middleware"
 	"my-flutter-backend/internal/model"
 	"my-flutter-backend/internal/repository"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 type RoleHandler struct {
@@ -22,10 +26,11 @@ func (h *RoleHandler) GetAll(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data role"})
 	}
 
-	// Filter: Sembunyikan "Super Admin"
+	// Filter: Sembunyikan role superuser (kecuali untuk sesama superuser)
+	isSuperuser := middleware.IsSuperuser(c)
 	var filteredRoles []model.Role
 	for _, r := range roles {
-		if r.NamaRole != "Super Admin" {
+		if !r.Superuser || isSuperuser {
 			filteredRoles = append(filteredRoles, r)
 		}
 	}
@@ -42,7 +47,7 @@ func (h *RoleHandler) GetAllPermissions(c *fiber.Ctx) error {
 	// Filter: Sembunyikan "kelola_organisasi"
 	var filteredPerms []model.Permission
 	for _, p := range perms {
-		if p.NamaPermission != "kelola_organisasi" {
+		if !superuserOnlyPermissions[p.NamaPermission] {
 			filteredPerms = append(filteredPerms, p)
 		}
 	}
@@ -50,10 +55,49 @@ func (h *RoleHandler) GetAllPermissions(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"data": filteredPerms})
 }
 
+// GetPermissionRoutes: Daftar endpoint yang dibuka oleh tiap permission (untuk halaman pengaturan role)
+// GET /api/admin/roles/permissions/routes
+func (h *RoleHandler) GetPermissionRoutes(c *fiber.Ctx) error {
+	perms, err := h.repo.GetAllPermissions()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data permission"})
+	}
+
+	type permissionRoutes struct {
+		ID             uint                         `json:"id"`
+		NamaPermission string                       `json:"nama_permission"`
+		Deskripsi      string                       `json:"deskripsi"`
+		Routes         []middleware.PermissionRoute `json:"routes"`
+	}
+
+	routesByPerm := middleware.PermissionRoutes()
+	isSuperuser := middleware.IsSuperuser(c)
+	result := []permissionRoutes{}
+	for _, p := range perms {
+		// Sama seperti GetAllPermissions: "kelola_organisasi" hanya untuk superuser
+		if superuserOnlyPermissions[p.NamaPermission] && !isSuperuser {
+			continue
+		}
+		routes := routesByPerm[p.NamaPermission]
+		if routes == nil {
+			// Permission yang dicek di dalam handler (misal override_approval) tidak punya route sendiri
+			routes = []middleware.PermissionRoute{}
+		}
+		result = append(result, permissionRoutes{
+			ID:             p.ID,
+			NamaPermission: p.NamaPermission,
+			Deskripsi:      p.Deskripsi,
+			Routes:         routes,
+		})
+	}
+
+	return c.JSON(fiber.Map{"data": result})
+}
+
 func (h *RoleHandler) GetDetail(c *fiber.Ctx) error {
 	id, _ := strconv.Atoi(c.Params("id"))
 	role, err := h.repo.GetByID(uint(id))
-	if err != nil {
+	if err != nil || (role.Superuser && !middleware.IsSuperuser(c)) {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Role tidak ditemukan"})
 	}
 	return c.JSON(fiber.Map{"data": role})
@@ -70,11 +114,16 @@ func (h *RoleHandler) Create(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
 	}
 
+	if status, msg := h.checkGrantablePermissions(c, req.PermissionIDs); msg != "" {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
 	role := model.Role{NamaRole: req.NamaRole}
 	if err := h.repo.Create(&role, req.PermissionIDs); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat role"})
 	}
 
+	middleware.SetAuditEntityID(c, role.ID)
 	return c.JSON(fiber.Map{"message": "Role berhasil dibuat"})
 }
 
@@ -86,22 +135,117 @@ func (h *RoleHandler) Update(c *fiber.Ctx) error {
 	}
 
 	role, err := h.repo.GetByID(uint(id))
-	if err != nil {
+	if err != nil || (role.Superuser && !middleware.IsSuperuser(c)) {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Role tidak ditemukan"})
 	}
 
+	if status, msg := h.checkGrantablePermissions(c, req.PermissionIDs); msg != "" {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
 	role.NamaRole = req.NamaRole
 	if err := h.repo.Update(role, req.PermissionIDs); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal update role"})
 	}
+	middleware.InvalidateRolePermissions(role.ID)
 
 	return c.JSON(fiber.Map{"message": "Role berhasil diupdate"})
 }
 
 func (h *RoleHandler) Delete(c *fiber.Ctx) error {
 	id, _ := strconv.Atoi(c.Params("id"))
+	role, err := h.repo.GetByID(uint(id))
+	if err != nil || (role.Superuser && !middleware.IsSuperuser(c)) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Role tidak ditemukan"})
+	}
 	if err := h.repo.Delete(uint(id)); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus role"})
 	}
+	middleware.InvalidateRolePermissions(uint(id))
 	return c.JSON(fiber.Map{"message": "Role berhasil dihapus"})
 }
+
+// --- PENCEGAHAN ESKALASI HAK AKSES ---
+
+// superuserOnlyPermissions: Permission lintas organisasi, hanya superuser yang boleh memberikannya ke role
+var superuserOnlyPermissions = map[string]bool{"kelola_organisasi": true}
+
+// grantError: Pesan penolakan jika user yang login memberikan permission ini (kosong = boleh).
+// Selain permission khusus superuser, non-superuser hanya boleh memberikan permission yang ia pegang sendiri.
+func grantError(c *fiber.Ctx, permission string) string {
+	if middleware.IsSuperuser(c) {
+		return ""
+	}
+	if superuserOnlyPermissions[permission] {
+		return fmt.Sprintf("Permission %s hanya dapat diberikan oleh Super Admin", permission)
+	}
+	if !middleware.HasPermission(c, permission) {
+		return fmt.Sprintf("Anda tidak dapat memberikan permission %s yang tidak Anda miliki", permission)
+	}
+	return ""
+}
+
+// checkGrantablePermissions: Validasi permission_ids pada create/update role
+func (h *RoleHandler) checkGrantablePermissions(c *fiber.Ctx, ids []uint) (int, string) {
+	perms, err := h.repo.GetAllPermissions()
+	if err != nil {
+		return fiber.StatusInternalServerError, "Gagal mengambil data permission"
+	}
+	names := make(map[uint]string, len(perms))
+	for _, p := range perms {
+		names[p.ID] = p.NamaPermission
+	}
+	for _, id := range ids {
+		name, ok := names[id]
+		if !ok {
+			return fiber.StatusBadRequest, fmt.Sprintf("Permission dengan ID %d tidak ditemukan", id)
+		}
+		if msg := grantError(c, name); msg != "" {
+			return fiber.StatusForbidden, msg
+		}
+	}
+	return 0, ""
+}
+
+// checkAssignableRole: Pegawai hanya boleh diberi role yang seluruh permission-nya dipegang user yang login
+// (role_id 0 = tanpa role)
+func checkAssignableRole(c *fiber.Ctx, roleRepo repository.RoleRepository, roleID uint) (int, string) {
+	if roleID == 0 {
+		return 0, ""
+	}
+	role, err := roleRepo.GetByID(roleID)
+	if err != nil || (role.Superuser && !middleware.IsSuperuser(c)) {
+		return fiber.StatusBadRequest, "Role tidak ditemukan"
+	}
+	for _, p := range role.Permissions {
+		if msg := grantError(c, p.NamaPermission); msg != "" {
+			return fiber.StatusForbidden, "Role " + role.NamaRole + " tidak dapat diberikan: " + msg
+		}
+	}
+	return 0, ""
+}
+
+// checkManageableASN: Reset password/2FA & ganti email hanya untuk pegawai yang role-nya saat ini tidak
+// melebihi hak akses user yang login (superuser atau permission yang tidak ia pegang), agar akun yang lebih
+// tinggi tidak bisa diambil alih lewat reset/lupa password.
+func checkManageableASN(c *fiber.Ctx, roleRepo repository.RoleRepository, asn *model.ASN) (int, string) {
+	if asn.RoleID == 0 || middleware.IsSuperuser(c) {
+		return 0, ""
+	}
+	role, err := roleRepo.GetByID(asn.RoleID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, "" // Role sudah dihapus -> tanpa hak akses
+	}
+	if err != nil {
+		return fiber.StatusInternalServerError, "Gagal memeriksa role pegawai"
+	}
+	if role.Superuser {
+		return fiber.StatusForbidden, "Akun Super Admin hanya dapat dikelola oleh Super Admin"
+	}
+	for _, p := range role.Permissions {
+		if grantError(c, p.NamaPermission) != "" {
+			return fiber.StatusForbidden, "Anda tidak dapat mengelola akun dengan hak akses lebih tinggi (" + role.NamaRole + ")"
+		}
+	}
+	return 0, ""
+}
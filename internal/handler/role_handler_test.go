@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"fmt"
+	"my-flutter-backend/internal/middleware"
+	"my-flutter-backend/internal/model"
+	"my-flutter-backend/internal/repository"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Admin (kelola_role + kelola_pegawai) tidak boleh menaikkan hak aksesnya sendiri lewat role
+func TestRolePermissionEscalationRejected(t *testing.T) {
+	db := newTestDB(t)
+	middleware.Init(db)
+
+	perm := func(name string) model.Permission {
+		p := model.Permission{NamaPermission: name}
+		mustCreate(t, db, &p)
+		return p
+	}
+	kelolaRole, kelolaPegawai := perm("kelola_role"), perm("kelola_pegawai")
+	kelolaOrg, reviewWajah := perm("kelola_organisasi"), perm("review_wajah")
+
+	org := model.Organisasi{NamaOrganisasi: "Org A"}
+	mustCreate(t, db, &org)
+	adminRole := model.Role{NamaRole: "Admin", Permissions: []model.Permission{kelolaRole, kelolaPegawai}}
+	mustCreate(t, db, &adminRole)
+	orgRole := model.Role{NamaRole: "Pengelola Organisasi", Permissions: []model.Permission{kelolaOrg}}
+	mustCreate(t, db, &orgRole)
+	pegawaiRole := model.Role{NamaRole: "Pegawai"}
+	mustCreate(t, db, &pegawaiRole)
+	superRole := model.Role{NamaRole: "Super Admin", Superuser: true}
+	mustCreate(t, db, &superRole)
+
+	admin := model.ASN{OrganisasiID: org.ID, RoleID: adminRole.ID, Nama: "Admin", NIP: "ADMIN", IsActive: true}
+	mustCreate(t, db, &admin)
+	pegawai := model.ASN{OrganisasiID: org.ID, RoleID: pegawaiRole.ID, Nama: "Pegawai", NIP: "P-1", IsActive: true}
+	mustCreate(t, db, &pegawai)
+	superAdmin := model.ASN{OrganisasiID: org.ID, RoleID: superRole.ID, Nama: "Super", NIP: "SUPER", Email: "super@dinas.go.id", IsActive: true}
+	mustCreate(t, db, &superAdmin)
+	pengelola := model.ASN{OrganisasiID: org.ID, RoleID: orgRole.ID, Nama: "Pengelola", NIP: "ORG-1", Email: "org@dinas.go.id", IsActive: true}
+	mustCreate(t, db, &pengelola)
+
+	roleRepo := repository.NewRoleRepository(db)
+	roleHdl := NewRoleHandler(roleRepo)
+	asnHdl := NewASNHandler(repository.NewASNRepository(db), repository.NewSessionRepository(db), repository.NewOTPRepository(db),
+		repository.NewLoginThrottleRepository(db), repository.NewTwoFactorRepository(db), repository.NewSSORepository(db),
+		repository.NewDeviceRepository(db), repository.NewInvitationRepository(db), roleRepo, nil)
+
+	app := fiber.New()
+	app.Use(asOrg(admin.ID, org.ID), func(c *fiber.Ctx) error {
+		c.Locals("role_id", float64(adminRole.ID))
+		return c.Next()
+	})
+	app.Post("/api/admin/roles", roleHdl.Create)
+	app.Put("/api/admin/roles/:id", roleHdl.Update)
+	app.Put("/api/admin/asn/:id", asnHdl.UpdateASN)
+	app.Put("/api/admin/asn/:id/reset-password", asnHdl.ResetUserPassword)
+	app.Delete("/api/admin/asn/:id/2fa", asnHdl.ResetTwoFactor)
+
+	cases := []struct {
+		name, method, path, body string
+		want                     int
+	}{
+		{"CreateRoleSuperuserOnly", "POST", "/api/admin/roles", fmt.Sprintf(`{"nama_role":"X","permission_ids":[%d]}`, kelolaOrg.ID), fiber.StatusForbidden},
+		{"UpdateOwnRoleSuperuserOnly", "PUT", fmt.Sprintf("/api/admin/roles/%d", adminRole.ID),
+			fmt.Sprintf(`{"nama_role":"Admin","permission_ids":[%d,%d,%d]}`, kelolaRole.ID, kelolaPegawai.ID, kelolaOrg.ID), fiber.StatusForbidden},
+		{"UpdateOwnRoleNotHeld", "PUT", fmt.Sprintf("/api/admin/roles/%d", adminRole.ID),
+			fmt.Sprintf(`{"nama_role":"Admin","permission_ids":[%d,%d,%d]}`, kelolaRole.ID, kelolaPegawai.ID, reviewWajah.ID), fiber.StatusForbidden},
+		{"AssignSelfHigherRole", "PUT", fmt.Sprintf("/api/admin/asn/%d", admin.ID),
+			fmt.Sprintf(`{"nama":"Admin","is_active":true,"role_id":%d}`, orgRole.ID), fiber.StatusForbidden},
+		// Akun dengan role lebih tinggi (superuser / permission yang tidak dipegang admin) tidak boleh diambil alih
+		{"ResetPasswordSuperuser", "PUT", fmt.Sprintf("/api/admin/asn/%d/reset-password", superAdmin.ID), `{"new_password":"Baru#12345678"}`, fiber.StatusForbidden},
+		{"ResetPasswordHigherRole", "PUT", fmt.Sprintf("/api/admin/asn/%d/reset-password", pengelola.ID), `{"new_password":"Baru#12345678"}`, fiber.StatusForbidden},
+		{"ResetTwoFactorSuperuser", "DELETE", fmt.Sprintf("/api/admin/asn/%d/2fa", superAdmin.ID), "", fiber.StatusForbidden},
+		{"ResetTwoFactorHigherRole", "DELETE", fmt.Sprintf("/api/admin/asn/%d/2fa", pengelola.ID), "", fiber.StatusForbidden},
+		{"ChangeEmailSuperuser", "PUT", fmt.Sprintf("/api/admin/asn/%d", superAdmin.ID),
+			fmt.Sprintf(`{"nama":"Super","is_active":true,"role_id":%d,"email":"attacker@x.id"}`, superRole.ID), fiber.StatusForbidden},
+		{"ChangeEmailHigherRole", "PUT", fmt.Sprintf("/api/admin/asn/%d", pengelola.ID),
+			fmt.Sprintf(`{"nama":"Pengelola","is_active":true,"role_id":%d,"email":"attacker@x.id"}`, orgRole.ID), fiber.StatusForbidden},
+		{"ChangeEmailLowerRole", "PUT", fmt.Sprintf("/api/admin/asn/%d", pegawai.ID),
+			fmt.Sprintf(`{"nama":"Pegawai","is_active":true,"role_id":%d,"email":"pegawai@dinas.go.id"}`, pegawaiRole.ID), fiber.StatusOK},
+		{"CreateRoleHeld", "POST", "/api/admin/roles", fmt.Sprintf(`{"nama_role":"Operator","permission_ids":[%d]}`, kelolaPegawai.ID), fiber.StatusOK},
+		{"AssignLowerRole", "PUT", fmt.Sprintf("/api/admin/asn/%d", pegawai.ID),
+			fmt.Sprintf(`{"nama":"Pegawai","is_active":true,"role_id":%d}`, adminRole.ID), fiber.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if status, body := doRequest(t, app, tc.method, tc.path, tc.body); status != tc.want {
+				t.Fatalf("status = %d, want %d (body: %s)", status, tc.want, body)
+			}
+		})
+	}
+
+	role, err := roleRepo.GetByID(adminRole.ID)
+	if err != nil || len(role.Permissions) != 2 {
+		t.Errorf("permission role Admin berubah: %+v, err=%v", role.Permissions, err)
+	}
+	var self model.ASN
+	if err := db.First(&self, admin.ID).Error; err != nil || self.RoleID != adminRole.ID {
+		t.Errorf("role admin berubah: %d, err=%v", self.RoleID, err)
+	}
+	for _, target := range []model.ASN{superAdmin, pengelola} {
+		var got model.ASN
+		if err := db.First(&got, target.ID).Error; err != nil || got.Email != target.Email || got.Password != target.Password {
+			t.Errorf("akun %s berubah: email=%q, err=%v", target.NIP, got.Email, err)
+		}
+	}
+}
+
+// organisasi_id di CreateASN hanya dipakai untuk pemegang kelola_organisasi (dicek lewat middleware.HasPermission)
+func TestCreateASNOrganisasiOverride(t *testing.T) {
+	db := newTestDB(t)
+	middleware.Init(db)
+
+	kelolaPegawai := model.Permission{NamaPermission: "kelola_pegawai"}
+	mustCreate(t, db, &kelolaPegawai)
+	kelolaOrg := model.Permission{NamaPermission: "kelola_organisasi"}
+	mustCreate(t, db, &kelolaOrg)
+	orgA := model.Organisasi{NamaOrganisasi: "Org A"}
+	mustCreate(t, db, &orgA)
+	orgB := model.Organisasi{NamaOrganisasi: "Org B"}
+	mustCreate(t, db, &orgB)
+	adminRole := model.Role{NamaRole: "Admin", Permissions: []model.Permission{kelolaPegawai}}
+	mustCreate(t, db, &adminRole)
+	pengelolaRole := model.Role{NamaRole: "Pengelola Organisasi", Permissions: []model.Permission{kelolaPegawai, kelolaOrg}}
+	mustCreate(t, db, &pengelolaRole)
+	pegawaiRole := model.Role{NamaRole: "Pegawai"}
+	mustCreate(t, db, &pegawaiRole)
+	admin := model.ASN{OrganisasiID: orgA.ID, RoleID: adminRole.ID, Nama: "Admin", NIP: "ADMIN", IsActive: true}
+	mustCreate(t, db, &admin)
+	pengelola := model.ASN{OrganisasiID: orgA.ID, RoleID: pengelolaRole.ID, Nama: "Pengelola", NIP: "ORG-1", IsActive: true}
+	mustCreate(t, db, &pengelola)
+
+	hdl := NewASNHandler(repository.NewASNRepository(db), repository.NewSessionRepository(db), repository.NewOTPRepository(db),
+		repository.NewLoginThrottleRepository(db), repository.NewTwoFactorRepository(db), repository.NewSSORepository(db),
+		repository.NewDeviceRepository(db), repository.NewInvitationRepository(db), repository.NewRoleRepository(db), nil)
+
+	cases := []struct {
+		name    string
+		caller  model.ASN
+		nip     string
+		wantOrg uint
+	}{
+		{"WithoutKelolaOrganisasi", admin, "P-1", orgA.ID},
+		{"WithKelolaOrganisasi", pengelola, "P-2", orgB.ID},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Use(asOrg(tc.caller.ID, tc.caller.OrganisasiID), func(c *fiber.Ctx) error {
+				c.Locals("role_id", float64(tc.caller.RoleID))
+				return c.Next()
+			})
+			app.Post("/api/admin/asn", hdl.CreateASN)
+
+			body := fmt.Sprintf(`{"nama":"Pegawai","nip":%q,"password":"Rahasia#2026xyz","role_id":%d,"organisasi_id":%d}`, tc.nip, pegawaiRole.ID, orgB.ID)
+			if status, resp := doRequest(t, app, "POST", "/api/admin/asn", body); status != fiber.StatusOK {
+				t.Fatalf("status = %d (body: %s)", status, resp)
+			}
+			var got model.ASN
+			if err := db.Where("nip = ?", tc.nip).First(&got).Error; err != nil || got.OrganisasiID != tc.wantOrg {
+				t.Fatalf("organisasi_id = %d, want %d (err=%v)", got.OrganisasiID, tc.wantOrg, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"my-flutter-backend/config"
+	"my-flutter-backend/internal/middleware"
+	"my-flutter-backend/internal/model"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// --- SELFIE BUKTI ABSEN ---
+// File disimpan di SELFIE_STORAGE_DIR (bukan ./uploads yang disajikan publik), hanya bisa diambil lewat
+// GET /api/kehadiran/:id/selfie/:jenis oleh pegawai ybs, atasan langsungnya, atau admin (view_rekap) organisasinya.
+
+var selfieExtensions = map[string]string{"image/jpeg": ".jpg", "image/png": ".png"}
+
+func selfieDir() string {
+	return config.GetEnv("SELFIE_STORAGE_DIR", "./storage/selfie")
+}
+
+func selfieMaxBytes() int64 {
+	return int64(config.GetEnvAsInt("SELFIE_MAX_KB", 2048)) * 1024
+}
+
+// selfieURL: Path API untuk mengambil selfie (kosong jika tidak ada / sudah dihapus retensi)
+func selfieURL(foto *model.FotoKehadiran) string {
+	if foto == nil || foto.DihapusAt != nil {
+		return ""
+	}
+	return fmt.Sprintf("/api/kehadiran/%d/selfie/%s", foto.KehadiranID, strings.ToLower(foto.Jenis))
+}
+
+// saveSelfie: Simpan file "selfie" dari form multipart. Mengembalikan nil (tanpa error) jika tidak ada file.
+// Tipe file dicek dari isinya (JPEG/PNG), bukan dari header yang dikirim klien.
+func saveSelfie(c *fiber.Ctx, asnID uint, jenis string, lat, lng float64, now time.Time) (*model.FotoKehadiran, error) {
+	file, err := c.FormFile("selfie")
+	if err != nil {
+		return nil, nil
+	}
+	if file.Size > selfieMaxBytes() {
+		return nil, fmt.Errorf("Ukuran selfie maksimal %d KB", selfieMaxBytes()/1024)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, errors.New("File selfie tidak bisa dibaca")
+	}
+	defer src.Close()
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(src, head)
+	contentType := http.DetectContentType(head[:n])
+	ext, ok := selfieExtensions[contentType]
+	if !ok {
+		return nil, errors.New("Selfie harus berupa foto JPEG atau PNG")
+	}
+
+	token, err := generateRandomToken(8)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(selfieDir(), now.Format("2006"), now.Format("01"))
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%d_%s_%d_%s%s", asnID, strings.ToLower(jenis), now.Unix(), token, ext))
+	if err := c.SaveFile(file, path); err != nil {
+		return nil, err
+	}
+
+	return &model.FotoKehadiran{
+		ASNID:       asnID,
+		Jenis:       jenis,
+		Path:        path,
+		ContentType: contentType,
+		DiambilAt:   now,
+		Latitude:    lat,
+		Longitude:   lng,
+	}, nil
+}
+
+// prepareSelfie: Validasi kewajiban selfie organisasi lalu simpan file. Respons error sudah ditulis jika ok = false.
+func prepareSelfie(c *fiber.Ctx, org *model.Organisasi, asnID uint, jenis string, req CheckInRequest, now time.Time) (*model.FotoKehadiran, bool) {
+	foto, err := saveSelfie(c, asnID, jenis, req.Latitude, req.Longitude, now)
+	if err != nil {
+		c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		return nil, false
+	}
+	if foto == nil && org.SelfieWajib {
+		c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Selfie wajib dilampirkan untuk absen (field: selfie)"})
+		return nil, false
+	}
+	return foto, true
+}
+
+// removeSelfie: Hapus file selfie jika penyimpanan kehadiran gagal
+func removeSelfie(foto *model.FotoKehadiran) {
+	if foto != nil && foto.Path != "" {
+		os.Remove(foto.Path)
+	}
+}
+
+// GetSelfie: Ambil file selfie absen masuk/pulang (pegawai ybs, atasan langsung, atau admin view_rekap/review_wajah organisasi)
+func (h *KehadiranHandler) GetSelfie(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+	jenis := strings.ToUpper(c.Params("jenis"))
+	if jenis != "MASUK" && jenis != "PULANG" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Jenis selfie harus masuk atau pulang"})
+	}
+
+	kehadiran, err := h.repo.GetByID(uint(id))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Data kehadiran tidak ditemukan"})
+	}
+	if !h.canViewSelfie(c, kehadiran) {
+		// Sama dengan tidak ditemukan agar ID kehadiran pegawai lain tidak bisa ditebak
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Data kehadiran tidak ditemukan"})
+	}
+
+	foto, err := h.fotoRepo.GetByKehadiran(kehadiran.ID, jenis)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Selfie tidak tersedia"})
+	}
+	if foto.DihapusAt != nil || foto.Path == "" {
+		return c.Status(fiber.StatusGone).JSON(fiber.Map{"error": "Selfie sudah dihapus karena masa retensi habis"})
+	}
+
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	return c.SendFile(foto.Path)
+}
+
+func (h *KehadiranHandler) canViewSelfie(c *fiber.Ctx, kehadiran *model.Kehadiran) bool {
+	userID := uint(c.Locals("user_id").(float64))
+	if kehadiran.ASNID == userID {
+		return true
+	}
+	owner, err := h.asnRepo.FindByID(kehadiran.ASNID)
+	if err != nil {
+		return false
+	}
+	if owner.AtasanID != nil && *owner.AtasanID == userID {
+		return true
+	}
+	orgID := uint(c.Locals("organisasi_id").(float64))
+	return owner.OrganisasiID == orgID && (middleware.HasPermission(c, "view_rekap") || middleware.HasPermission(c, "review_wajah"))
+}
@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"fmt"
+	"my-flutter-backend/internal/middleware"
+	"my-flutter-backend/internal/model"
+	"my-flutter-backend/internal/repository"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	apiKeyDefaultExpiryDays = 365
+	apiKeyMaxExpiryDays     = 730
+)
+
+// apiKeyScopes: Permission yang boleh diberikan ke service account (hanya baca).
+// Route yang dibuka harus memakai middleware.AuthWithAPIKey.
+var apiKeyScopes = []string{"view_rekap", "view_audit"}
+
+type ServiceAccountHandler struct {
+	repo repository.ServiceAccountRepository
+}
+
+func NewServiceAccountHandler(repo repository.ServiceAccountRepository) *ServiceAccountHandler {
+	return &ServiceAccountHandler{repo: repo}
+}
+
+type ServiceAccountRequest struct {
+	Nama          string   `json:"nama"`
+	Deskripsi     string   `json:"deskripsi"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"` // Default 365 hari, maksimal 730 hari
+}
+
+// validateScopes: Scope harus ada di daftar apiKeyScopes dan dimiliki admin pembuatnya (tidak bisa eskalasi)
+func validateScopes(c *fiber.Ctx, scopes []string) (string, error) {
+	if len(scopes) == 0 {
+		return "", fmt.Errorf("scopes wajib diisi (pilihan: %s)", strings.Join(apiKeyScopes, ", "))
+	}
+	seen := make(map[string]bool)
+	var valid []string
+	for _, s := range scopes {
+		s = strings.TrimSpace(s)
+		if seen[s] {
+			continue
+		}
+		allowed := false
+		for _, a := range apiKeyScopes {
+			if s == a {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return "", fmt.Errorf("scope %q tidak tersedia untuk API key (pilihan: %s)", s, strings.Join(apiKeyScopes, ", "))
+		}
+		if !middleware.HasPermission(c, s) {
+			return "", fmt.Errorf("Anda tidak memiliki izin %s sehingga tidak bisa memberikannya ke API key", s)
+		}
+		seen[s] = true
+		valid = append(valid, s)
+	}
+	return strings.Join(valid, ","), nil
+}
+
+func apiKeyExpiry(days int) (*time.Time, error) {
+	if days == 0 {
+		days = apiKeyDefaultExpiryDays
+	}
+	if days < 1 || days > apiKeyMaxExpiryDays {
+		return nil, fmt.Errorf("expires_in_days harus antara 1 dan %d", apiKeyMaxExpiryDays)
+	}
+	expiresAt := time.Now().AddDate(0, 0, days)
+	return &expiresAt, nil
+}
+
+// generateAPIKey: API key baru -> (key plaintext, prefix untuk identifikasi, hash untuk disimpan)
+func generateAPIKey() (string, string, string, error) {
+	secret, err := generateRandomToken(24)
+	if err != nil {
+		return "", "", "", err
+	}
+	key := middleware.APIKeyPrefix + secret
+	return key, key[:len(middleware.APIKeyPrefix)+8], middleware.HashAPIKey(key), nil
+}
+
+// GetAll: Daftar service account organisasi (tanpa API key)
+func (h *ServiceAccountHandler) GetAll(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
+	list, err := h.repo.GetAllByOrg(orgID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil service account"})
+	}
+	return c.JSON(fiber.Map{"data": list, "scope_tersedia": apiKeyScopes})
+}
+
+// Create: Buat service account baru. API key hanya ditampilkan sekali di respons ini.
+func (h *ServiceAccountHandler) Create(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
+	userID := uint(c.Locals("user_id").(float64))
+
+	var req ServiceAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
+	}
+	if strings.TrimSpace(req.Nama) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Nama service account wajib diisi"})
+	}
+	scopes, err := validateScopes(c, req.Scopes)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	expiresAt, err := apiKeyExpiry(req.ExpiresInDays)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	key, prefix, hash, err := generateAPIKey()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat API key"})
+	}
+
+	sa := model.ServiceAccount{
+		OrganisasiID: orgID,
+		Nama:         strings.TrimSpace(req.Nama),
+		Deskripsi:    req.Deskripsi,
+		Scopes:       scopes,
+		KeyPrefix:    prefix,
+		KeyHash:      hash,
+		ExpiresAt:    expiresAt,
+		CreatedByID:  userID,
+	}
+	if err := h.repo.Create(&sa); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan service account"})
+	}
+	middleware.SetAuditEntityID(c, sa.ID)
+
+	return c.JSON(fiber.Map{
+		"message": "Service account berhasil dibuat. Simpan API key sekarang, key tidak akan ditampilkan lagi.",
+		"data":    sa,
+		"api_key": key,
+	})
+}
+
+// Update: Ubah nama, deskripsi, dan scopes (API key tetap)
+func (h *ServiceAccountHandler) Update(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	var req ServiceAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
+	}
+
+	sa, err := h.repo.GetByIDInOrg(uint(id), orgID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Service account tidak ditemukan"})
+	}
+	if sa.RevokedAt != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Service account sudah dicabut"})
+	}
+
+	if nama := strings.TrimSpace(req.Nama); nama != "" {
+		sa.Nama = nama
+	}
+	sa.Deskripsi = req.Deskripsi
+	if req.Scopes != nil {
+		scopes, err := validateScopes(c, req.Scopes)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		sa.Scopes = scopes
+	}
+
+	if err := h.repo.Update(sa); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal update service account"})
+	}
+	return c.JSON(fiber.Map{"message": "Service account berhasil diupdate", "data": sa})
+}
+
+// Rotate: Terbitkan API key baru, key lama langsung tidak berlaku
+func (h *ServiceAccountHandler) Rotate(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	var req ServiceAccountRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
+		}
+	}
+	expiresAt, err := apiKeyExpiry(req.ExpiresInDays)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	sa, err := h.repo.GetByIDInOrg(uint(id), orgID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Service account tidak ditemukan"})
+	}
+	if sa.RevokedAt != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Service account sudah dicabut"})
+	}
+
+	key, prefix, hash, err := generateAPIKey()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat API key"})
+	}
+	sa.KeyPrefix = prefix
+	sa.KeyHash = hash
+	sa.ExpiresAt = expiresAt
+	if err := h.repo.Update(sa); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan API key"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "API key baru berhasil dibuat. Key lama tidak berlaku lagi.",
+		"data":    sa,
+		"api_key": key,
+	})
+}
+
+// Revoke: Cabut service account. Data tetap disimpan untuk jejak audit.
+func (h *ServiceAccountHandler) Revoke(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
+	userID := uint(c.Locals("user_id").(float64))
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	sa, err := h.repo.GetByIDInOrg(uint(id), orgID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Service account tidak ditemukan"})
+	}
+	if sa.RevokedAt != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Service account sudah dicabut"})
+	}
+
+	now := time.Now()
+	sa.RevokedAt = &now
+	sa.RevokedByID = &userID
+	if err := h.repo.Update(sa); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mencabut service account"})
+	}
+	return c.JSON(fiber.Map{"message": "Service account dicabut, API key tidak berlaku lagi"})
+}
@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"my-flutter-backend/internal/model"
+	"my-flutter-backend/internal/repository"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type SessionHandler struct {
+	repo         repository.SessionRepository
+	asnRepo      repository.ASNRepository
+	throttleRepo repository.LoginThrottleRepository
+}
+
+func NewSessionHandler(repo repository.SessionRepository, asnRepo repository.ASNRepository, throttleRepo repository.LoginThrottleRepository) *SessionHandler {
+	return &SessionHandler{repo: repo, asnRepo: asnRepo, throttleRepo: throttleRepo}
+}
+
+// currentSessionID: ID session dari access token (0 jika token lama tanpa sid)
+func currentSessionID(c *fiber.Ctx) uint {
+	if sid, ok := c.Locals("session_id").(float64); ok {
+		return uint(sid)
+	}
+	return 0
+}
+
+// Helper untuk format response list session
+func toSessionResponse(sessions []model.Session, currentID uint) []fiber.Map {
+	result := make([]fiber.Map, 0)
+	for _, s := range sessions {
+		brand, series := "", ""
+		if s.Device != nil {
+			brand = s.Device.Brand
+			series = s.Device.Series
+		}
+		result = append(result, fiber.Map{
+			"id":            s.ID,
+			"client":        s.Client,
+			"device_brand":  brand,
+			"device_series": series,
+			"ip_address":    s.IPAddress,
+			"user_agent":    s.UserAgent,
+			"login_at":      s.CreatedAt,
+			"last_seen_at":  s.LastSeenAt,
+			"expires_at":    s.ExpiresAt,
+			"is_current":    s.ID == currentID,
+		})
+	}
+	return result
+}
+
+// Logout: Cabut session yang sedang dipakai (refresh token tidak bisa dipakai lagi)
+func (h *SessionHandler) Logout(c *fiber.Ctx) error {
+	sid := currentSessionID(c)
+	if sid == 0 {
+		return c.JSON(fiber.Map{"message": "Logout berhasil"})
+	}
+
+	if err := h.repo.Revoke(sid, "LOGOUT"); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal logout"})
+	}
+	return c.JSON(fiber.Map{"message": "Logout berhasil"})
+}
+
+// GetMySessions: Daftar perangkat/browser tempat akun ini sedang login
+func (h *SessionHandler) GetMySessions(c *fiber.Ctx) error {
+	asnID := uint(c.Locals("user_id").(float64))
+
+	sessions, err := h.repo.GetActiveByASN(asnID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data sesi"})
+	}
+	return c.JSON(fiber.Map{"data": toSessionResponse(sessions, currentSessionID(c))})
+}
+
+// RevokeMySession: Cabut satu session milik sendiri
+func (h *SessionHandler) RevokeMySession(c *fiber.Ctx) error {
+	asnID := uint(c.Locals("user_id").(float64))
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	session, err := h.repo.GetByID(uint(id))
+	if err != nil || session.ASNID != asnID {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Sesi tidak ditemukan"})
+	}
+
+	if err := h.repo.Revoke(session.ID, "LOGOUT"); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mencabut sesi"})
+	}
+	return c.JSON(fiber.Map{"message": "Sesi berhasil dicabut"})
+}
+
+// RevokeAllMySessions: Logout dari semua perangkat (?keep_current=true untuk tetap login di perangkat ini)
+func (h *SessionHandler) RevokeAllMySessions(c *fiber.Ctx) error {
+	asnID := uint(c.Locals("user_id").(float64))
+
+	var err error
+	if c.QueryBool("keep_current") {
+		err = h.repo.RevokeAllByASNExcept(asnID, currentSessionID(c), "LOGOUT_ALL")
+	} else {
+		err = h.repo.RevokeAllByASN(asnID, "LOGOUT_ALL")
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mencabut sesi"})
+	}
+	return c.JSON(fiber.Map{"message": "Semua sesi berhasil dicabut"})
+}
+
+// --- ADMIN: Kelola Sesi Pegawai (Akun Dibobol / HP Hilang) ---
+
+// findASNInOrg: Pastikan pegawai target satu organisasi dengan admin
+func (h *SessionHandler) findASNInOrg(c *fiber.Ctx) (*model.ASN, error) {
+	orgID := uint(c.Locals("organisasi_id").(float64))
+	id, _ := strconv.Atoi(c.Params("id"))
+	return h.asnRepo.FindByIDInOrg(uint(id), orgID)
+}
+
+func (h *SessionHandler) GetASNSessions(c *fiber.Ctx) error {
+	asn, err := h.findASNInOrg(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pegawai tidak ditemukan"})
+	}
+
+	sessions, err := h.repo.GetActiveByASN(asn.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data sesi"})
+	}
+	return c.JSON(fiber.Map{"data": toSessionResponse(sessions, 0)})
+}
+
+func (h *SessionHandler) RevokeASNSession(c *fiber.Ctx) error {
+	asn, err := h.findASNInOrg(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pegawai tidak ditemukan"})
+	}
+
+	sessionID, _ := strconv.Atoi(c.Params("sessionId"))
+	session, err := h.repo.GetByID(uint(sessionID))
+	if err != nil || session.ASNID != asn.ID {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Sesi tidak ditemukan"})
+	}
+
+	if err := h.repo.Revoke(session.ID, "DICABUT_ADMIN"); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mencabut sesi"})
+	}
+	return c.JSON(fiber.Map{"message": "Sesi pegawai berhasil dicabut"})
+}
+
+func (h *SessionHandler) RevokeAllASNSessions(c *fiber.Ctx) error {
+	asn, err := h.findASNInOrg(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pegawai tidak ditemukan"})
+	}
+
+	if err := h.repo.RevokeAllByASN(asn.ID, "DICABUT_ADMIN"); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mencabut sesi"})
+	}
+	return c.JSON(fiber.Map{"message": "Semua sesi pegawai berhasil dicabut"})
+}
+
+// GetLoginLock: Status kunci login pegawai + riwayat penguncian
+func (h *SessionHandler) GetLoginLock(c *fiber.Ctx) error {
+	asn, err := h.findASNInOrg(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pegawai tidak ditemukan"})
+	}
+
+	isLocked := false
+	var lockedUntil *time.Time
+	if t, err := h.throttleRepo.Get(loginScopeNIP, asn.NIP); err == nil && t.LockedUntil != nil && t.LockedUntil.After(time.Now()) {
+		isLocked = true
+		lockedUntil = t.LockedUntil
+	}
+
+	events, err := h.throttleRepo.GetEvents(loginScopeNIP, asn.NIP, 20)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil riwayat penguncian"})
+	}
+
+	return c.JSON(fiber.Map{
+		"is_locked":    isLocked,
+		"locked_until": lockedUntil,
+		"events":       events,
+	})
+}
+
+// UnlockLogin: Admin membuka kunci login pegawai sebelum waktunya habis
+func (h *SessionHandler) UnlockLogin(c *fiber.Ctx) error {
+	asn, err := h.findASNInOrg(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pegawai tidak ditemukan"})
+	}
+
+	if err := h.throttleRepo.Reset(loginScopeNIP, asn.NIP); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuka kunci akun"})
+	}
+
+	actorID := uint(c.Locals("user_id").(float64))
+	h.throttleRepo.CreateEvent(&model.LoginLockoutEvent{
+		Scope:     loginScopeNIP,
+		Key:       asn.NIP,
+		Event:     "UNLOCKED",
+		IPAddress: c.IP(),
+		ActorID:   &actorID,
+	})
+
+	return c.JSON(fiber.Map{"message": "Kunci login pegawai berhasil dibuka"})
+}
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"fmt"
+	"my-flutter-backend/internal/middleware"
 	"my-flutter-backend/internal/model"
 	"my-flutter-backend/internal/repository"
 	"strconv"
@@ -17,6 +19,41 @@ func NewShiftHandler(repo repository.ShiftRepository, jadwalRepo repository.Jadw
 	return &ShiftHandler{repo: repo, jadwalRepo: jadwalRepo}
 }
 
+const (
+	maxBatasCheckoutJam = 24
+	maxJendelaMenit     = 720 // Jendela check-in/out maksimal 12 jam dari jam shift
+)
+
+// validateShiftRules: Toleransi & jendela check-in/out (menit) serta batas auto-checkout (jam).
+// 0 = tanpa toleransi (toleransi_terlambat_menit), tanpa batas (jendela), atau default (batas_checkout_jam).
+func validateShiftRules(shift model.Shift) error {
+	if shift.BatasCheckoutJam < 0 || shift.BatasCheckoutJam > maxBatasCheckoutJam {
+		return fmt.Errorf("batas_checkout_jam harus antara 0 dan %d", maxBatasCheckoutJam)
+	}
+	windows := []struct {
+		field string
+		menit int
+	}{
+		{"toleransi_terlambat_menit", shift.ToleransiTerlambatMenit},
+		{"check_in_mulai_menit", shift.CheckInMulaiMenit},
+		{"check_in_batas_menit", shift.CheckInBatasMenit},
+		{"check_out_mulai_menit", shift.CheckOutMulaiMenit},
+		{"check_out_batas_menit", shift.CheckOutBatasMenit},
+	}
+	for _, w := range windows {
+		if w.menit < 0 || w.menit > maxJendelaMenit {
+			return fmt.Errorf("%s harus antara 0 dan %d", w.field, maxJendelaMenit)
+		}
+	}
+	if shift.CheckInBatasMenit > 0 && shift.CheckInBatasMenit < shift.ToleransiTerlambatMenit {
+		return fmt.Errorf("check_in_batas_menit tidak boleh lebih kecil dari toleransi_terlambat_menit")
+	}
+	if shift.BatasCheckoutJam > 0 && shift.CheckOutBatasMenit > shift.BatasCheckoutJam*60 {
+		return fmt.Errorf("check_out_batas_menit tidak boleh melebihi batas_checkout_jam")
+	}
+	return nil
+}
+
 func (h *ShiftHandler) GetAll(c *fiber.Ctx) error {
 	orgID := uint(c.Locals("organisasi_id").(float64))
 	shifts, err := h.repo.GetAll(orgID)
@@ -34,21 +71,30 @@ func (h *ShiftHandler) Create(c *fiber.Ctx) error {
 	}
 
 	shift.OrganisasiID = orgID // Set Org ID
+	if err := validateShiftRules(shift); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
 
 	if err := h.repo.Create(&shift); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat shift"})
 	}
+	middleware.SetAuditEntityID(c, shift.ID)
 	return c.JSON(fiber.Map{"message": "Shift berhasil dibuat", "data": shift})
 }
 
 func (h *ShiftHandler) Update(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
 	id, _ := strconv.Atoi(c.Params("id"))
 	var req model.Shift
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
 	}
 
-	shift, err := h.repo.GetByID(uint(id))
+	if err := validateShiftRules(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	shift, err := h.repo.GetByIDInOrg(uint(id), orgID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Shift tidak ditemukan"})
 	}
@@ -56,6 +102,12 @@ func (h *ShiftHandler) Update(c *fiber.Ctx) error {
 	shift.NamaShift = req.NamaShift
 	shift.JamMasuk = req.JamMasuk
 	shift.JamPulang = req.JamPulang
+	shift.ToleransiTerlambatMenit = req.ToleransiTerlambatMenit
+	shift.CheckInMulaiMenit = req.CheckInMulaiMenit
+	shift.CheckInBatasMenit = req.CheckInBatasMenit
+	shift.CheckOutMulaiMenit = req.CheckOutMulaiMenit
+	shift.CheckOutBatasMenit = req.CheckOutBatasMenit
+	shift.BatasCheckoutJam = req.BatasCheckoutJam
 
 	if err := h.repo.Update(shift); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal update shift"})
@@ -64,15 +116,20 @@ func (h *ShiftHandler) Update(c *fiber.Ctx) error {
 }
 
 func (h *ShiftHandler) Delete(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
 	id, _ := strconv.Atoi(c.Params("id"))
 
+	if _, err := h.repo.GetByIDInOrg(uint(id), orgID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Shift tidak ditemukan"})
+	}
+
 	// Validasi: Cek apakah shift sedang digunakan di jadwal
 	count, _ := h.jadwalRepo.CountByShiftID(uint(id))
 	if count > 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Shift tidak bisa dihapus karena sedang digunakan dalam jadwal"})
 	}
 
-	if err := h.repo.Delete(uint(id)); err != nil {
+	if err := h.repo.Delete(uint(id), orgID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus shift"})
 	}
 	return c.JSON(fiber.Map{"message": "Shift berhasil dihapus"})
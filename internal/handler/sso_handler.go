@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"my-flutter-backend/config"
+	"my-flutter-backend/internal/model"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// --- LOGIN SSO (OpenID Connect, authorization code + PKCE) ---
+// Alur:
+//  1. Client (web/mobile) memanggil POST /api/sso/oidc/start -> dapat authorization_url & state
+//  2. User login di IdP, IdP redirect ke OIDC_REDIRECT_URI milik client dengan ?code=&state=
+//  3. Client meneruskan code & state ke POST /api/sso/oidc/callback -> token seperti Login/WebLogin
+//
+// Pemetaan akun: claim "sub" yang sudah terhubung ke ASN, atau claim NIP (OIDC_NIP_CLAIM) saat login SSO pertama.
+
+var errSSOAccountNotFound = errors.New("akun SSO tidak terdaftar")
+
+// ssoStateTTL: Batas waktu user menyelesaikan login di IdP (ENV SSO_STATE_TTL_MINUTES, default 10)
+func ssoStateTTL() time.Duration {
+	return time.Duration(config.GetEnvAsInt("SSO_STATE_TTL_MINUTES", 10)) * time.Minute
+}
+
+// ssoRequired: Organisasi mewajibkan SSO -> login password ditolak.
+// Role superuser tetap boleh memakai password sebagai akses darurat saat IdP bermasalah.
+func ssoRequired(asn *model.ASN) bool {
+	return asn.Organisasi.SSOOnly && !asn.Role.Superuser
+}
+
+type SSOStartRequest struct {
+	Client        string `json:"client"`         // MOBILE / WEB (default WEB)
+	DeviceID      string `json:"device_id"`      // Khusus MOBILE: UUID perangkat untuk device binding
+	Brand         string `json:"brand"`          // Merk HP
+	Series        string `json:"series"`         // Tipe HP
+	FirebaseToken string `json:"firebase_token"` // Untuk Notifikasi
+}
+
+// StartSSO: Buat state/nonce/PKCE lalu kembalikan URL halaman login IdP
+func (h *ASNHandler) StartSSO(c *fiber.Ctx) error {
+	if h.sso == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Login SSO belum dikonfigurasi"})
+	}
+
+	var req SSOStartRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format data salah"})
+	}
+	client := strings.ToUpper(req.Client)
+	if client == "" {
+		client = "WEB"
+	}
+	if client != "WEB" && client != "MOBILE" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "client harus WEB atau MOBILE"})
+	}
+
+	state, errState := generateRandomToken(32)
+	nonce, errNonce := generateRandomToken(16)
+	verifier, errVerifier := generateRandomToken(32)
+	if errState != nil || errNonce != nil || errVerifier != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memulai login SSO"})
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	authURL := h.sso.AuthCodeURL(state, nonce, challenge)
+	if authURL == "" {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Penyedia SSO tidak dapat dihubungi"})
+	}
+
+	ttl := ssoStateTTL()
+	loginState := model.SSOLoginState{
+		StateHash:    hashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		Client:       client,
+		ExpiresAt:    time.Now().Add(ttl),
+	}
+	if client == "MOBILE" {
+		loginState.DeviceUUID = req.DeviceID
+		loginState.Brand = req.Brand
+		loginState.Series = req.Series
+		loginState.FirebaseToken = req.FirebaseToken
+	}
+	if err := h.ssoRepo.CreateState(&loginState); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memulai login SSO"})
+	}
+
+	return c.JSON(fiber.Map{
+		"authorization_url": authURL,
+		"state":             state,
+		"expires_in":        int(ttl.Seconds()),
+	})
+}
+
+type SSOCallbackRequest struct {
+	State string `json:"state"`
+	Code  string `json:"code"`
+	Error string `json:"error"` // Diteruskan dari IdP (misal access_denied)
+}
+
+// SSOCallback: Tukar authorization code, petakan identitas ke ASN, lalu terbitkan token
+func (h *ASNHandler) SSOCallback(c *fiber.Ctx) error {
+	if h.sso == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Login SSO belum dikonfigurasi"})
+	}
+
+	var req SSOCallbackRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format data salah"})
+	}
+
+	// 1. State hanya berlaku sekali & sebelum kadaluwarsa
+	loginState, err := h.ssoRepo.ConsumeState(hashToken(req.State))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Sesi login SSO tidak valid atau kadaluwarsa. Silakan ulangi."})
+	}
+	if req.Error != "" || req.Code == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Login SSO dibatalkan"})
+	}
+
+	// 2. Tukar code -> identitas terverifikasi (ID token + nonce + PKCE)
+	identity, err := h.sso.Exchange(c.Context(), req.Code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Login SSO gagal: " + err.Error()})
+	}
+
+	// 3. Petakan ke ASN
+	asn, err := h.findSSOAccount(identity.Subject, identity.NIP)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Akun SSO Anda tidak terdaftar sebagai pegawai. Hubungi Admin."})
+	}
+	if !asn.IsActive {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Akun Anda dinonaktifkan. Silakan hubungi Admin."})
+	}
+	// Login SSO tidak mengaktivasi akun undangan: aktivasi tetap lewat link email (sama seperti Login/WebLogin)
+	if asn.ActivationPending {
+		return activationPendingResponse(c)
+	}
+
+	// 4a. Mobile: Device binding tetap berlaku
+	if loginState.Client == "MOBILE" {
+		deviceID, rejected := h.bindDevice(asn, loginState.DeviceUUID, loginState.Brand, loginState.Series, loginState.FirebaseToken)
+		if rejected != nil {
+			return c.Status(fiber.StatusForbidden).JSON(rejected)
+		}
+		return h.completeMobileLogin(c, asn, deviceID)
+	}
+
+	// 4b. Web: Aturan 2FA sama seperti WebLogin
+	tf, err := h.twoFactorRepo.GetByASN(asn.ID)
+	enrolled := err == nil && tf.ConfirmedAt != nil
+	if enrolled || requiresTwoFactor(asn) {
+		return h.twoFactorChallenge(c, asn, enrolled)
+	}
+	return h.completeWebLogin(c, asn, nil)
+}
+
+// findSSOAccount: Cari ASN berdasarkan subject IdP; jika belum terhubung, cocokkan claim NIP
+// lalu simpan subject-nya agar login berikutnya tidak bergantung pada claim NIP lagi.
+func (h *ASNHandler) findSSOAccount(subject, nip string) (*model.ASN, error) {
+	if asn, err := h.repo.FindBySSOSubject(subject); err == nil {
+		return asn, nil
+	}
+	if nip == "" {
+		return nil, errSSOAccountNotFound
+	}
+
+	asn, err := h.repo.FindByNIP(nip)
+	if err != nil {
+		return nil, errSSOAccountNotFound
+	}
+	// NIP sudah terhubung ke akun IdP lain -> tolak (cegah pengambilalihan akun)
+	if asn.SSOSubject != nil && *asn.SSOSubject != subject {
+		return nil, errSSOAccountNotFound
+	}
+	if asn.SSOSubject == nil {
+		if err := h.repo.SetSSOSubject(asn.ID, subject); err != nil {
+			return nil, err
+		}
+	}
+	return asn, nil
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"my-flutter-backend/config"
+	"my-flutter-backend/internal/idpmock"
+	"my-flutter-backend/internal/model"
+	"my-flutter-backend/internal/oidc"
+	"my-flutter-backend/internal/repository"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Alur SSO lengkap melawan mock IdP (cmd/mock-idp): login berhasil, state/nonce tidak cocok, akun belum aktivasi, organisasi SSO-only
+func TestSSOLoginWithMockIdP(t *testing.T) {
+	ks, err := config.ParseJWTKeys("test:secret-untuk-test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := config.JWT
+	config.JWT = ks
+	t.Cleanup(func() { config.JWT = prev })
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp, err := idpmock.New("http://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	idpApp := idp.App()
+	go idpApp.Listener(ln)
+	t.Cleanup(func() { idpApp.Shutdown() })
+
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&model.SSOLoginState{}, &model.TwoFactor{}, &model.RecoveryCode{}, &model.LoginThrottle{}, &model.LoginLockoutEvent{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	org := model.Organisasi{NamaOrganisasi: "Org A"}
+	mustCreate(t, db, &org)
+	ssoOrg := model.Organisasi{NamaOrganisasi: "Org SSO", SSOOnly: true}
+	mustCreate(t, db, &ssoOrg)
+	role := model.Role{NamaRole: "Pegawai"}
+	mustCreate(t, db, &role)
+	hash, _ := bcrypt.GenerateFromPassword([]byte("rahasia123"), bcrypt.MinCost)
+	pegawai := model.ASN{OrganisasiID: org.ID, RoleID: role.ID, Nama: "Pegawai", NIP: "P-1", Password: string(hash), IsActive: true}
+	mustCreate(t, db, &pegawai)
+	undangan := model.ASN{OrganisasiID: org.ID, RoleID: role.ID, Nama: "Undangan", NIP: "P-2", IsActive: true, ActivationPending: true}
+	mustCreate(t, db, &undangan)
+	ssoOnly := model.ASN{OrganisasiID: ssoOrg.ID, RoleID: role.ID, Nama: "Pegawai SSO", NIP: "S-1", Password: string(hash), IsActive: true}
+	mustCreate(t, db, &ssoOnly)
+
+	redirectURI := "http://localhost:5173/sso/callback"
+	provider := oidc.NewClient(oidc.Config{Issuer: idp.Issuer, ClientID: "absensi", ClientSecret: "rahasia", RedirectURI: redirectURI, Scopes: []string{"openid"}})
+	hdl := NewASNHandler(repository.NewASNRepository(db), repository.NewSessionRepository(db), repository.NewOTPRepository(db),
+		repository.NewLoginThrottleRepository(db), repository.NewTwoFactorRepository(db), repository.NewSSORepository(db),
+		repository.NewDeviceRepository(db), repository.NewInvitationRepository(db), repository.NewRoleRepository(db), provider)
+	app := fiber.New()
+	app.Post("/api/login", hdl.Login)
+	app.Post("/api/sso/oidc/start", hdl.StartSSO)
+	app.Post("/api/sso/oidc/callback", hdl.SSOCallback)
+
+	// loginAtIdP: Mulai SSO lalu login di mock IdP sebagai nip, kembalikan code & state dari redirect
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	loginAtIdP := func(t *testing.T, nip string) (code, state string) {
+		t.Helper()
+		status, body := doRequest(t, app, "POST", "/api/sso/oidc/start", `{"client":"WEB"}`)
+		if status != fiber.StatusOK {
+			t.Fatalf("start: status %d: %s", status, body)
+		}
+		var start struct {
+			AuthorizationURL string `json:"authorization_url"`
+			State            string `json:"state"`
+		}
+		json.Unmarshal([]byte(body), &start)
+
+		resp, err := noRedirect.Get(start.AuthorizationURL + "&login_hint=" + url.QueryEscape(nip))
+		if err != nil {
+			t.Fatalf("authorize: %v", err)
+		}
+		resp.Body.Close()
+		location, err := url.Parse(resp.Header.Get("Location"))
+		if err != nil || resp.StatusCode != http.StatusFound || !strings.HasPrefix(location.String(), redirectURI) {
+			t.Fatalf("authorize: status %d, location %q", resp.StatusCode, resp.Header.Get("Location"))
+		}
+		if location.Query().Get("state") != start.State {
+			t.Fatalf("state redirect = %q, want %q", location.Query().Get("state"), start.State)
+		}
+		return location.Query().Get("code"), start.State
+	}
+	callback := func(t *testing.T, code, state string) (int, string) {
+		t.Helper()
+		return doRequest(t, app, "POST", "/api/sso/oidc/callback", fmt.Sprintf(`{"code":%q,"state":%q}`, code, state))
+	}
+
+	t.Run("HappyPath", func(t *testing.T) {
+		code, state := loginAtIdP(t, pegawai.NIP)
+		status, body := callback(t, code, state)
+		if status != fiber.StatusOK || !strings.Contains(body, `"refresh_token"`) {
+			t.Fatalf("status %d: %s", status, body)
+		}
+		var got model.ASN
+		db.First(&got, pegawai.ID)
+		if got.SSOSubject == nil || *got.SSOSubject != "mock-"+pegawai.NIP {
+			t.Errorf("sso_subject = %v, want mock-%s", got.SSOSubject, pegawai.NIP)
+		}
+	})
+
+	t.Run("StateMismatch", func(t *testing.T) {
+		code, _ := loginAtIdP(t, pegawai.NIP)
+		if status, body := callback(t, code, "state-lain"); status != fiber.StatusBadRequest {
+			t.Fatalf("status %d, want 400: %s", status, body)
+		}
+	})
+
+	t.Run("StateReused", func(t *testing.T) {
+		code, state := loginAtIdP(t, pegawai.NIP)
+		if status, body := callback(t, code, state); status != fiber.StatusOK {
+			t.Fatalf("first callback: status %d: %s", status, body)
+		}
+		if status, body := callback(t, code, state); status != fiber.StatusBadRequest {
+			t.Fatalf("reused state: status %d, want 400: %s", status, body)
+		}
+	})
+
+	t.Run("NonceMismatch", func(t *testing.T) {
+		code, state := loginAtIdP(t, pegawai.NIP)
+		// Nonce yang disimpan backend berbeda dengan nonce di ID token (misal ID token dari sesi login lain)
+		db.Model(&model.SSOLoginState{}).Where("state_hash = ?", hashToken(state)).Update("nonce", "nonce-lain")
+		status, body := callback(t, code, state)
+		if status != fiber.StatusUnauthorized || !strings.Contains(body, "nonce") {
+			t.Fatalf("status %d, want 401 nonce: %s", status, body)
+		}
+	})
+
+	t.Run("ActivationPending", func(t *testing.T) {
+		code, state := loginAtIdP(t, undangan.NIP)
+		status, body := callback(t, code, state)
+		if status != fiber.StatusForbidden || !strings.Contains(body, `"activation_pending":true`) {
+			t.Fatalf("status %d, want 403 activation_pending: %s", status, body)
+		}
+	})
+
+	t.Run("SSOOnlyRejectsPassword", func(t *testing.T) {
+		status, body := doRequest(t, app, "POST", "/api/login", fmt.Sprintf(`{"nip":%q,"password":"rahasia123","device_id":"hp-1"}`, ssoOnly.NIP))
+		if status != fiber.StatusForbidden || !strings.Contains(body, `"sso_required":true`) {
+			t.Fatalf("password login: status %d, want 403 sso_required: %s", status, body)
+		}
+		code, state := loginAtIdP(t, ssoOnly.NIP)
+		if status, body := callback(t, code, state); status != fiber.StatusOK {
+			t.Fatalf("SSO login: status %d: %s", status, body)
+		}
+	})
+}
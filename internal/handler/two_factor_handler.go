@@ -0,0 +1,464 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"my-flutter-backend/config"
+	"my-flutter-backend/internal/model"
+	"my-flutter-backend/internal/passwordauth"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// --- FITUR 2FA (TOTP) UNTUK LOGIN WEB ---
+
+const (
+	totpPeriod            = 30 // Detik per kode (standar Google Authenticator)
+	totpDigits            = 6
+	recoveryCodeCount     = 10
+	twoFactorChallengeTTL = 5 * time.Minute
+)
+
+var errTwoFactorActive = errors.New("2FA sudah aktif")
+
+// requiresTwoFactor: 2FA wajib untuk role superuser dan role yang memegang permission sensitif
+// (default kelola_organisasi, edit_jadwal, kelola_pegawai & kelola_role, bisa diubah lewat ENV TWO_FACTOR_REQUIRED_PERMISSIONS)
+func requiresTwoFactor(asn *model.ASN) bool {
+	if asn.Role.Superuser {
+		return true // Superuser memegang semua permission meski tidak tercatat di daftar permission role
+	}
+	required := strings.Split(config.GetEnv("TWO_FACTOR_REQUIRED_PERMISSIONS", "kelola_organisasi,edit_jadwal,kelola_pegawai,kelola_role"), ",")
+	for _, p := range asn.Role.Permissions {
+		for _, r := range required {
+			if p.NamaPermission == strings.TrimSpace(r) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// --- Step 2 WebLogin (pakai challenge_token dari /api/web-login) ---
+
+type TwoFactorChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`          // Kode 6 digit dari aplikasi authenticator
+	RecoveryCode   string `json:"recovery_code"` // Alternatif jika authenticator hilang
+}
+
+// VerifyWebLogin2FA: Verifikasi kode TOTP / recovery code lalu terbitkan token
+func (h *ASNHandler) VerifyWebLogin2FA(c *fiber.Ctx) error {
+	var req TwoFactorChallengeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format data salah"})
+	}
+
+	asn, err := h.parseTwoFactorChallenge(req.ChallengeToken)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Sesi verifikasi 2FA tidak valid atau kadaluwarsa, silakan login ulang"})
+	}
+	if lockedUntil := h.loginLockedUntil(asn.NIP, c.IP()); lockedUntil != nil {
+		return loginLockedResponse(c, *lockedUntil)
+	}
+
+	tf, err := h.twoFactorRepo.GetByASN(asn.ID)
+	if err != nil || tf.ConfirmedAt == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "2FA belum diaktifkan, silakan lakukan pendaftaran authenticator"})
+	}
+
+	extra := fiber.Map{}
+	if req.RecoveryCode != "" {
+		used, err := h.twoFactorRepo.UseRecoveryCode(asn.ID, hashToken(normalizeRecoveryCode(req.RecoveryCode)))
+		if err != nil || !used {
+			return h.loginFailed(c, asn.NIP, true, "Recovery code salah atau sudah dipakai")
+		}
+		remaining, _ := h.twoFactorRepo.CountUnusedRecoveryCodes(asn.ID)
+		extra["recovery_codes_remaining"] = remaining
+	} else if !h.verifyTOTP(tf, req.Code) {
+		return h.loginFailed(c, asn.NIP, true, "Kode 2FA salah")
+	}
+
+	h.throttleRepo.Reset(loginScopeNIP, asn.NIP)
+	return h.completeWebLogin(c, asn, extra)
+}
+
+// SetupWebLogin2FA: Pendaftaran authenticator saat login (untuk role yang wajib 2FA tapi belum punya)
+func (h *ASNHandler) SetupWebLogin2FA(c *fiber.Ctx) error {
+	var req TwoFactorChallengeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format data salah"})
+	}
+
+	asn, err := h.parseTwoFactorChallenge(req.ChallengeToken)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Sesi verifikasi 2FA tidak valid atau kadaluwarsa, silakan login ulang"})
+	}
+	return h.beginTwoFactorSetup(c, asn)
+}
+
+// ConfirmWebLogin2FA: Konfirmasi kode pertama, aktifkan 2FA, lalu langsung login
+func (h *ASNHandler) ConfirmWebLogin2FA(c *fiber.Ctx) error {
+	var req TwoFactorChallengeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format data salah"})
+	}
+
+	asn, err := h.parseTwoFactorChallenge(req.ChallengeToken)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Sesi verifikasi 2FA tidak valid atau kadaluwarsa, silakan login ulang"})
+	}
+	if lockedUntil := h.loginLockedUntil(asn.NIP, c.IP()); lockedUntil != nil {
+		return loginLockedResponse(c, *lockedUntil)
+	}
+
+	codes, err := h.confirmTwoFactor(asn.ID, req.Code)
+	if err != nil {
+		if errors.Is(err, errTwoFactorActive) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "2FA sudah aktif, gunakan verifikasi kode"})
+		}
+		return h.loginFailed(c, asn.NIP, true, "Kode 2FA salah")
+	}
+
+	h.throttleRepo.Reset(loginScopeNIP, asn.NIP)
+	return h.completeWebLogin(c, asn, fiber.Map{"recovery_codes": codes})
+}
+
+// --- Kelola 2FA Akun Sendiri (Protected) ---
+
+func (h *ASNHandler) GetTwoFactorStatus(c *fiber.Ctx) error {
+	asnID := uint(c.Locals("user_id").(float64))
+	asn, err := h.repo.FindByID(asnID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User tidak ditemukan"})
+	}
+
+	enabled := false
+	if tf, err := h.twoFactorRepo.GetByASN(asnID); err == nil && tf.ConfirmedAt != nil {
+		enabled = true
+	}
+	remaining, _ := h.twoFactorRepo.CountUnusedRecoveryCodes(asnID)
+
+	return c.JSON(fiber.Map{
+		"enabled":                  enabled,
+		"required":                 requiresTwoFactor(asn),
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+func (h *ASNHandler) SetupTwoFactor(c *fiber.Ctx) error {
+	asn, err := h.repo.FindByID(uint(c.Locals("user_id").(float64)))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User tidak ditemukan"})
+	}
+	return h.beginTwoFactorSetup(c, asn)
+}
+
+type TwoFactorCodeRequest struct {
+	Code     string `json:"code"`
+	Password string `json:"password"` // Wajib untuk menonaktifkan 2FA
+}
+
+func (h *ASNHandler) ConfirmTwoFactor(c *fiber.Ctx) error {
+	asnID := uint(c.Locals("user_id").(float64))
+	var req TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
+	}
+
+	codes, err := h.confirmTwoFactor(asnID, req.Code)
+	if err != nil {
+		if errors.Is(err, errTwoFactorActive) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "2FA sudah aktif"})
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Kode 2FA salah"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":        "2FA berhasil diaktifkan. Simpan recovery code di tempat aman.",
+		"recovery_codes": codes,
+	})
+}
+
+// RegenerateRecoveryCodes: Buat ulang recovery code (yang lama tidak berlaku)
+func (h *ASNHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	asnID := uint(c.Locals("user_id").(float64))
+	var req TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
+	}
+
+	tf, err := h.twoFactorRepo.GetByASN(asnID)
+	if err != nil || tf.ConfirmedAt == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "2FA belum diaktifkan"})
+	}
+	if !h.verifyTOTP(tf, req.Code) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Kode 2FA salah"})
+	}
+
+	codes, err := h.issueRecoveryCodes(asnID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat recovery code"})
+	}
+	return c.JSON(fiber.Map{"recovery_codes": codes})
+}
+
+func (h *ASNHandler) DisableTwoFactor(c *fiber.Ctx) error {
+	asnID := uint(c.Locals("user_id").(float64))
+	var req TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
+	}
+
+	asn, err := h.repo.FindByIDWithOrganisasi(asnID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User tidak ditemukan"})
+	}
+	if requiresTwoFactor(asn) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "2FA wajib untuk role Anda dan tidak bisa dinonaktifkan"})
+	}
+	if err := passwordauth.Verify(asn, req.Password); err != nil {
+		if errors.Is(err, passwordauth.ErrUnavailable) {
+			return passwordBackendUnavailable(c, err)
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Password salah"})
+	}
+
+	tf, err := h.twoFactorRepo.GetByASN(asnID)
+	if err != nil || tf.ConfirmedAt == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "2FA belum diaktifkan"})
+	}
+	if !h.verifyTOTP(tf, req.Code) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Kode 2FA salah"})
+	}
+
+	if err := h.twoFactorRepo.DeleteByASN(asnID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menonaktifkan 2FA"})
+	}
+	return c.JSON(fiber.Map{"message": "2FA berhasil dinonaktifkan"})
+}
+
+// ResetTwoFactor (Admin): Hapus 2FA pegawai yang kehilangan authenticator & recovery code.
+// Pegawai akan diminta mendaftar ulang saat login berikutnya (jika role-nya wajib 2FA).
+func (h *ASNHandler) ResetTwoFactor(c *fiber.Ctx) error {
+	orgID := uint(c.Locals("organisasi_id").(float64))
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	asn, err := h.repo.FindByIDInOrg(uint(id), orgID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pegawai tidak ditemukan"})
+	}
+	if status, msg := checkManageableASN(c, h.roleRepo, asn); msg != "" {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	if err := h.twoFactorRepo.DeleteByASN(asn.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal reset 2FA"})
+	}
+	h.sessionRepo.RevokeAllByASN(asn.ID, "2FA_RESET")
+
+	return c.JSON(fiber.Map{"message": "2FA pegawai berhasil di-reset"})
+}
+
+// --- Helper 2FA ---
+
+// twoFactorChallenge: Password benar, tapi token baru diterbitkan setelah faktor kedua diverifikasi
+func (h *ASNHandler) twoFactorChallenge(c *fiber.Ctx, asn *model.ASN, enrolled bool) error {
+	now := time.Now()
+	challenge, err := config.JWT.Sign(jwt.MapClaims{
+		"user_id": asn.ID,
+		"nip":     asn.NIP,
+		"type":    "2fa_challenge",
+		"iat":     now.Unix(),
+		"exp":     now.Add(twoFactorChallengeTTL).Unix(),
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat token"})
+	}
+
+	message := "Masukkan kode dari aplikasi authenticator"
+	if !enrolled {
+		message = "Role Anda wajib menggunakan 2FA. Silakan daftarkan aplikasi authenticator."
+	}
+
+	return c.JSON(fiber.Map{
+		"message":             message,
+		"two_factor_required": true,
+		"setup_required":      !enrolled,
+		"challenge_token":     challenge,
+		"expires_in":          int(twoFactorChallengeTTL.Seconds()),
+	})
+}
+
+func (h *ASNHandler) parseTwoFactorChallenge(tokenString string) (*model.ASN, error) {
+	token, err := config.JWT.Parse(tokenString)
+	if err != nil || !token.Valid {
+		return nil, errors.New("challenge tidak valid")
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	nip, _ := claims["nip"].(string)
+	if claims["type"] != "2fa_challenge" || nip == "" {
+		return nil, errors.New("challenge tidak valid")
+	}
+
+	asn, err := h.repo.FindByNIP(nip)
+	if err != nil || !asn.IsActive {
+		return nil, errors.New("akun tidak valid")
+	}
+	// Challenge tidak berlaku lagi jika password diubah setelah challenge diterbitkan
+	iat, _ := claims["iat"].(float64)
+	if asn.PasswordChangedAt != nil && asn.PasswordChangedAt.Unix() > int64(iat) {
+		return nil, errors.New("challenge kadaluwarsa")
+	}
+	return asn, nil
+}
+
+// beginTwoFactorSetup: Buat secret baru (belum aktif sampai dikonfirmasi) + URI untuk QR Code
+func (h *ASNHandler) beginTwoFactorSetup(c *fiber.Ctx, asn *model.ASN) error {
+	tf, err := h.twoFactorRepo.GetByASN(asn.ID)
+	if err != nil {
+		tf = &model.TwoFactor{ASNID: asn.ID}
+	} else if tf.ConfirmedAt != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "2FA sudah aktif"})
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat secret 2FA"})
+	}
+	tf.Secret = secret
+	tf.LastUsedStep = 0
+	if err := h.twoFactorRepo.Save(tf); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan secret 2FA"})
+	}
+
+	return c.JSON(fiber.Map{
+		"secret":           secret,
+		"provisioning_uri": totpProvisioningURI(asn.NIP, secret), // Tampilkan sebagai QR Code di frontend
+	})
+}
+
+// confirmTwoFactor: Aktifkan 2FA jika kode pertama benar, lalu terbitkan recovery code
+func (h *ASNHandler) confirmTwoFactor(asnID uint, code string) ([]string, error) {
+	tf, err := h.twoFactorRepo.GetByASN(asnID)
+	if err != nil || tf.Secret == "" {
+		return nil, errors.New("2FA belum didaftarkan")
+	}
+	if tf.ConfirmedAt != nil {
+		return nil, errTwoFactorActive
+	}
+	if !h.verifyTOTP(tf, code) {
+		return nil, errors.New("kode salah")
+	}
+
+	now := time.Now()
+	tf.ConfirmedAt = &now
+	if err := h.twoFactorRepo.Save(tf); err != nil {
+		return nil, err
+	}
+	return h.issueRecoveryCodes(asnID)
+}
+
+func (h *ASNHandler) verifyTOTP(tf *model.TwoFactor, code string) bool {
+	step, ok := validateTOTP(tf.Secret, code, tf.LastUsedStep, time.Now())
+	if !ok {
+		return false
+	}
+	used, err := h.twoFactorRepo.UseStep(tf.ID, step)
+	if err != nil || !used {
+		return false
+	}
+	tf.LastUsedStep = step
+	return true
+}
+
+func (h *ASNHandler) issueRecoveryCodes(asnID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(base32.StdEncoding.EncodeToString(b)) // 8 karakter
+		codes = append(codes, raw[:4]+"-"+raw[4:])
+		hashes = append(hashes, hashToken(raw))
+	}
+	if err := h.twoFactorRepo.ReplaceRecoveryCodes(asnID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// --- TOTP (RFC 6238, HMAC-SHA1, 6 digit, 30 detik) ---
+
+func generateTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b), nil
+}
+
+func totpCode(secret string, step int64) (string, error) {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000), nil
+}
+
+// validateTOTP menerima kode dari time-step sebelum/sesudah (toleransi jam HP) yang belum pernah dipakai
+func validateTOTP(secret, code string, lastUsedStep int64, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	current := now.Unix() / totpPeriod
+	for _, step := range []int64{current - 1, current, current + 1} {
+		if step <= lastUsedStep {
+			continue
+		}
+		expected, err := totpCode(secret, step)
+		if err == nil && hmac.Equal([]byte(expected), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func totpProvisioningURI(accountName, secret string) string {
+	issuer := config.GetEnv("TOTP_ISSUER", "ASN E-Absensi")
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", strconv.Itoa(totpDigits))
+	params.Set("period", strconv.Itoa(totpPeriod))
+	label := url.PathEscape(issuer + ":" + accountName)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
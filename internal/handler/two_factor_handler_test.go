@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"my-flutter-backend/internal/model"
+	"testing"
+)
+
+func TestRequiresTwoFactor(t *testing.T) {
+	cases := []struct {
+		name string
+		role model.Role
+		want bool
+	}{
+		{"SuperuserWithoutPermissions", model.Role{Superuser: true}, true},
+		{"SensitivePermission", model.Role{Permissions: []model.Permission{{NamaPermission: "kelola_pegawai"}}}, true},
+		{"RegularPermission", model.Role{Permissions: []model.Permission{{NamaPermission: "approve_cuti"}}}, false},
+		{"NoRole", model.Role{}, false},
+	}
+	for _, tc := range cases {
+		if got := requiresTwoFactor(&model.ASN{Role: tc.role}); got != tc.want {
+			t.Errorf("%s: requiresTwoFactor = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
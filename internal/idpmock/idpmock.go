@@ -0,0 +1,200 @@
+// Package idpmock: OpenID Connect provider sederhana untuk development & uji coba login SSO.
+// Dipakai cmd/mock-idp dan test login SSO di internal/handler. JANGAN dipakai di production
+// (tanpa password, kunci dibuat ulang setiap start).
+//
+// Halaman /authorize menampilkan form isian NIP; atau langsung lewati form dengan ?login_hint=<NIP>.
+// Subject (sub) dibuat deterministik dari NIP ("mock-<NIP>"), claim NIP dikirim sebagai "nip".
+package idpmock
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"math/big"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	keyID       = "mock-idp"
+	tokenLength = 16
+)
+
+type authCode struct {
+	nip           string
+	clientID      string
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+	expiresAt     time.Time
+}
+
+// Server: Issuer harus sama dengan URL yang dipakai backend (OIDC_ISSUER)
+type Server struct {
+	Issuer string
+
+	signingKey *rsa.PrivateKey
+	mu         sync.Mutex
+	codes      map[string]authCode
+	accessNIPs map[string]string // access_token -> NIP (untuk /userinfo)
+}
+
+// New: Buat IdP dengan kunci RSA baru
+func New(issuer string) (*Server, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{Issuer: issuer, signingKey: key, codes: map[string]authCode{}, accessNIPs: map[string]string{}}, nil
+}
+
+// App: Endpoint discovery, JWKS, authorize, token & userinfo
+func (s *Server) App() *fiber.App {
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/.well-known/openid-configuration", s.discovery)
+	app.Get("/jwks", s.jwks)
+	app.Get("/authorize", s.authorize)
+	app.Post("/token", s.token)
+	app.Get("/userinfo", s.userinfo)
+	return app
+}
+
+func (s *Server) discovery(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"issuer":                                s.Issuer,
+		"authorization_endpoint":                s.Issuer + "/authorize",
+		"token_endpoint":                        s.Issuer + "/token",
+		"userinfo_endpoint":                     s.Issuer + "/userinfo",
+		"jwks_uri":                              s.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (s *Server) jwks(c *fiber.Ctx) error {
+	pub := s.signingKey.PublicKey
+	return c.JSON(fiber.Map{"keys": []fiber.Map{{
+		"kty": "RSA",
+		"kid": keyID,
+		"use": "sig",
+		"alg": "RS256",
+		"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}}})
+}
+
+// authorize: Tanpa login_hint -> form NIP. Dengan login_hint -> langsung redirect membawa code.
+func (s *Server) authorize(c *fiber.Ctx) error {
+	nip := c.Query("login_hint")
+	if nip == "" {
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		hidden := ""
+		for _, key := range []string{"client_id", "redirect_uri", "state", "nonce", "code_challenge", "code_challenge_method", "scope", "response_type"} {
+			hidden += fmt.Sprintf(`<input type="hidden" name="%s" value="%s">`, key, html.EscapeString(c.Query(key)))
+		}
+		return c.SendString(`<html><body><h3>Mock IdP</h3><form method="get" action="/authorize">` + hidden +
+			`NIP: <input name="login_hint" autofocus> <button type="submit">Login</button></form></body></html>`)
+	}
+
+	redirectURI := c.Query("redirect_uri")
+	target, err := url.Parse(redirectURI)
+	if err != nil || redirectURI == "" {
+		return c.Status(fiber.StatusBadRequest).SendString("redirect_uri tidak valid")
+	}
+	if c.Query("code_challenge_method") != "S256" || c.Query("code_challenge") == "" {
+		return c.Status(fiber.StatusBadRequest).SendString("PKCE S256 wajib")
+	}
+
+	code := randomString()
+	s.mu.Lock()
+	s.codes[code] = authCode{
+		nip:           nip,
+		clientID:      c.Query("client_id"),
+		redirectURI:   redirectURI,
+		nonce:         c.Query("nonce"),
+		codeChallenge: c.Query("code_challenge"),
+		expiresAt:     time.Now().Add(time.Minute),
+	}
+	s.mu.Unlock()
+
+	q := target.Query()
+	q.Set("code", code)
+	q.Set("state", c.Query("state"))
+	target.RawQuery = q.Encode()
+	return c.Redirect(target.String(), fiber.StatusFound)
+}
+
+func (s *Server) token(c *fiber.Ctx) error {
+	code := c.FormValue("code")
+	s.mu.Lock()
+	ac, ok := s.codes[code]
+	delete(s.codes, code) // Code hanya berlaku sekali
+	s.mu.Unlock()
+
+	if !ok || time.Now().After(ac.expiresAt) || c.FormValue("redirect_uri") != ac.redirectURI {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_grant"})
+	}
+	sum := sha256.Sum256([]byte(c.FormValue("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != ac.codeChallenge {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_grant", "error_description": "PKCE tidak cocok"})
+	}
+
+	now := time.Now()
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   s.Issuer,
+		"sub":   "mock-" + ac.nip,
+		"aud":   ac.clientID,
+		"nonce": ac.nonce,
+		"nip":   ac.nip,
+		"name":  "Pegawai " + ac.nip,
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+	})
+	idToken.Header["kid"] = keyID
+	signed, err := idToken.SignedString(s.signingKey)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "server_error"})
+	}
+
+	accessToken := randomString()
+	s.mu.Lock()
+	s.accessNIPs[accessToken] = ac.nip
+	s.mu.Unlock()
+
+	return c.JSON(fiber.Map{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+}
+
+func (s *Server) userinfo(c *fiber.Ctx) error {
+	accessToken := c.Get(fiber.HeaderAuthorization)
+	if len(accessToken) > 7 {
+		accessToken = accessToken[7:] // "Bearer "
+	}
+	s.mu.Lock()
+	nip, ok := s.accessNIPs[accessToken]
+	s.mu.Unlock()
+	if !ok {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	return c.JSON(fiber.Map{"sub": "mock-" + nip, "nip": nip, "name": "Pegawai " + nip})
+}
+
+func randomString() string {
+	b := make([]byte, tokenLength)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,165 @@
+package jobs
+
+import (
+	"log"
+	"my-flutter-backend/config"
+	"my-flutter-backend/internal/model"
+	"my-flutter-backend/internal/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// --- SWEEP ALPHA (TIDAK HADIR) ---
+// Setelah jam pulang shift lewat (termasuk shift lintas hari), jadwal aktif yang tidak punya data kehadiran
+// sama sekali dicatat sebagai kehadiran ALPHA. Pegawai yang cuti/izin disetujui atau organisasinya libur dilewati.
+// Aman dijalankan berulang: jadwal yang sudah punya baris kehadiran (termasuk ALPHA) tidak diproses lagi.
+
+func alphaSweepInterval() time.Duration {
+	return time.Duration(config.GetEnvAsInt("ALPHA_SWEEP_INTERVAL_MINUTES", 15)) * time.Minute
+}
+
+// alphaSweepGrace: Jeda setelah jam pulang shift sebelum pegawai dianggap tidak hadir
+func alphaSweepGrace() time.Duration {
+	return time.Duration(config.GetEnvAsInt("ALPHA_SWEEP_GRACE_MINUTES", 60)) * time.Minute
+}
+
+// alphaSweepLookbackDays: Sweep berkala ikut memeriksa N hari ke belakang (menutup celah saat server mati)
+func alphaSweepLookbackDays() int {
+	return config.GetEnvAsInt("ALPHA_SWEEP_LOOKBACK_DAYS", 3)
+}
+
+// StartAlphaSweep: Jalankan sweep ALPHA berkala di background. ALPHA_SWEEP_INTERVAL_MINUTES=0 menonaktifkan.
+func StartAlphaSweep(db *gorm.DB) {
+	interval := alphaSweepInterval()
+	if interval <= 0 {
+		log.Println("Sweep ALPHA nonaktif (ALPHA_SWEEP_INTERVAL_MINUTES=0)")
+		return
+	}
+
+	go func() {
+		for {
+			now := time.Now()
+			from := now.AddDate(0, 0, -alphaSweepLookbackDays()).Format("2006-01-02")
+			to := now.Format("2006-01-02")
+
+			created, err := RunAlphaSweep(db, from, to, 0)
+			if err != nil {
+				log.Printf("Sweep ALPHA gagal: %v", err)
+			} else if created > 0 {
+				log.Printf("Sweep ALPHA %s s/d %s: %d kehadiran ALPHA dibuat", from, to, created)
+			}
+
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// RunAlphaSweep: SweepAlpha dengan lock antar-instance. Jika sweep lain sedang berjalan, tidak melakukan apa-apa.
+func RunAlphaSweep(db *gorm.DB, from, to string, orgID uint) (int, error) {
+	created := 0
+	_, err := withLock(db, "alpha_sweep", func() error {
+		var err error
+		created, err = SweepAlpha(db, from, to, orgID, time.Now())
+		return err
+	})
+	return created, err
+}
+
+// SweepAlpha: Buat kehadiran ALPHA untuk jadwal tanggal from..to (YYYY-MM-DD) yang shift-nya sudah selesai
+// sebelum now. orgID 0 = semua organisasi. Mengembalikan jumlah baris ALPHA yang dibuat.
+func SweepAlpha(db *gorm.DB, from, to string, orgID uint, now time.Time) (int, error) {
+	kehadiranRepo := repository.NewKehadiranRepository(db)
+	perizinanRepo := repository.NewPerizinanRepository(db)
+
+	candidates, err := kehadiranRepo.GetAlphaCandidates(from, to, orgID)
+	if err != nil || len(candidates) == 0 {
+		return 0, err
+	}
+
+	// Izin/cuti disetujui per pegawai
+	asnIDs := make([]uint, 0, len(candidates))
+	seen := make(map[uint]bool)
+	for _, j := range candidates {
+		if !seen[j.ASNID] {
+			seen[j.ASNID] = true
+			asnIDs = append(asnIDs, j.ASNID)
+		}
+	}
+	leaves, err := perizinanRepo.GetApprovedByASNIDs(asnIDs)
+	if err != nil {
+		return 0, err
+	}
+	leaveByASN := make(map[uint][]model.PerizinanCuti)
+	for _, l := range leaves {
+		leaveByASN[l.ASNID] = append(leaveByASN[l.ASNID], l)
+	}
+
+	var rows []model.Kehadiran
+	for _, j := range candidates {
+		end, ok := ShiftEnd(j.Tanggal, j.Shift)
+		if !ok || now.Before(end.Add(alphaSweepGrace())) {
+			continue // Shift belum selesai / jam shift tidak valid
+		}
+		if onLeave(leaveByASN[j.ASNID], j.Tanggal) {
+			continue
+		}
+
+		tanggal, _ := time.ParseInLocation("2006-01-02", j.Tanggal, time.Local)
+		rows = append(rows, model.Kehadiran{
+			ASNID:        j.ASNID,
+			JadwalID:     j.ID,
+			Tanggal:      j.Tanggal,
+			StatusMasuk:  "ALPHA",
+			StatusPulang: "ALPHA",
+			Tahun:        tanggal.Format("2006"),
+			Bulan:        tanggal.Format("01"),
+		})
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	if err := kehadiranRepo.CreateMany(rows); err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+// ShiftEnd: Waktu selesai shift untuk jadwal tanggal tsb. Jam pulang <= jam masuk berarti shift lintas hari
+// (pulang keesokan harinya).
+func ShiftEnd(tanggal string, shift model.Shift) (time.Time, bool) {
+	date, err := time.ParseInLocation("2006-01-02", tanggal, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	masuk, errMasuk := time.Parse("15:04", shift.JamMasuk)
+	pulang, errPulang := time.Parse("15:04", shift.JamPulang)
+	if errMasuk != nil || errPulang != nil {
+		return time.Time{}, false
+	}
+
+	end := time.Date(date.Year(), date.Month(), date.Day(), pulang.Hour(), pulang.Minute(), 0, 0, time.Local)
+	if !pulang.After(masuk) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end, true
+}
+
+// onLeave: Tanggal termasuk dalam salah satu izin/cuti (format tanggal perizinan bisa tanpa nol di depan, misal 2026-1-9)
+func onLeave(leaves []model.PerizinanCuti, tanggal string) bool {
+	date, err := time.Parse("2006-1-2", tanggal)
+	if err != nil {
+		return false
+	}
+	for _, l := range leaves {
+		start, errStart := time.Parse("2006-1-2", l.TanggalMulai)
+		end, errEnd := time.Parse("2006-1-2", l.TanggalSelesai)
+		if errStart != nil || errEnd != nil {
+			continue
+		}
+		if !date.Before(start) && !date.After(end) {
+			return true
+		}
+	}
+	return false
+}
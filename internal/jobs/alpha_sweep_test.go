@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"my-flutter-backend/internal/model"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newSweepTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:alpha_sweep?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	if err := db.AutoMigrate(
+		&model.Organisasi{}, &model.Role{}, &model.Permission{}, &model.ASN{}, &model.Shift{}, &model.Jadwal{},
+		&model.Kehadiran{}, &model.HariLibur{}, &model.PerizinanCuti{},
+	); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func mustCreate(t *testing.T, db *gorm.DB, value interface{}) {
+	t.Helper()
+	if err := db.Create(value).Error; err != nil {
+		t.Fatalf("create %T: %v", value, err)
+	}
+}
+
+// Sweep berulang tidak menggandakan ALPHA; libur, cuti disetujui, shift lintas hari yang belum selesai dilewati
+func TestSweepAlpha(t *testing.T) {
+	t.Setenv("ALPHA_SWEEP_GRACE_MINUTES", "60")
+	db := newSweepTestDB(t)
+
+	org := model.Organisasi{NamaOrganisasi: "Org A"}
+	mustCreate(t, db, &org)
+	mustCreate(t, db, &model.HariLibur{OrganisasiID: org.ID, Tanggal: "2026-03-03", Keterangan: "Libur Nasional"})
+	pagi := model.Shift{OrganisasiID: org.ID, NamaShift: "Pagi", JamMasuk: "07:30", JamPulang: "16:00"}
+	mustCreate(t, db, &pagi)
+	malam := model.Shift{OrganisasiID: org.ID, NamaShift: "Malam", JamMasuk: "23:00", JamPulang: "07:00"}
+	mustCreate(t, db, &malam)
+
+	bolos := model.ASN{OrganisasiID: org.ID, Nama: "Bolos", NIP: "P-1", IsActive: true}
+	mustCreate(t, db, &bolos)
+	cuti := model.ASN{OrganisasiID: org.ID, Nama: "Cuti", NIP: "P-2", IsActive: true}
+	mustCreate(t, db, &cuti)
+	jaga := model.ASN{OrganisasiID: org.ID, Nama: "Jaga Malam", NIP: "P-3", IsActive: true}
+	mustCreate(t, db, &jaga)
+	mustCreate(t, db, &model.PerizinanCuti{ASNID: cuti.ID, Tipe: "CUTI", TanggalMulai: "2026-3-2", TanggalSelesai: "2026-3-3", Status: "DISETUJUI"})
+
+	jadwal := func(asn model.ASN, shift model.Shift, tanggal string) model.Jadwal {
+		j := model.Jadwal{ASNID: asn.ID, ShiftID: shift.ID, Tanggal: tanggal, IsActive: true}
+		mustCreate(t, db, &j)
+		return j
+	}
+	bolosSenin := jadwal(bolos, pagi, "2026-03-02")
+	jadwal(bolos, pagi, "2026-03-03") // Hari libur
+	hadir := jadwal(bolos, pagi, "2026-03-04")
+	mustCreate(t, db, &model.Kehadiran{ASNID: bolos.ID, JadwalID: hadir.ID, Tanggal: "2026-03-04", StatusMasuk: "HADIR"})
+	jadwal(cuti, pagi, "2026-03-02") // Cuti disetujui
+	malamRabu := jadwal(jaga, malam, "2026-03-04")
+
+	runs := []struct {
+		name string
+		now  time.Time
+		want int
+	}{
+		// Shift malam 04/03 baru selesai 05/03 07:00 (+ grace 60 menit)
+		{"BeforeCrossDayShiftEnds", time.Date(2026, 3, 5, 7, 30, 0, 0, time.Local), 1},
+		{"RepeatIsNoop", time.Date(2026, 3, 5, 7, 30, 0, 0, time.Local), 0},
+		{"AfterCrossDayShiftEnds", time.Date(2026, 3, 5, 8, 30, 0, 0, time.Local), 1},
+		{"RepeatAfterIsNoop", time.Date(2026, 3, 5, 8, 30, 0, 0, time.Local), 0},
+	}
+	for _, run := range runs {
+		created, err := SweepAlpha(db, "2026-03-01", "2026-03-05", 0, run.now)
+		if err != nil {
+			t.Fatalf("%s: sweep: %v", run.name, err)
+		}
+		if created != run.want {
+			t.Fatalf("%s: created = %d, want %d", run.name, created, run.want)
+		}
+	}
+
+	var alpha []model.Kehadiran
+	db.Where("status_masuk = ?", "ALPHA").Order("jadwal_id").Find(&alpha)
+	if len(alpha) != 2 || alpha[0].JadwalID != bolosSenin.ID || alpha[1].JadwalID != malamRabu.ID {
+		t.Fatalf("ALPHA rows = %+v, want jadwal %d and %d", alpha, bolosSenin.ID, malamRabu.ID)
+	}
+	if alpha[1].Tanggal != "2026-03-04" || alpha[1].Bulan != "03" || alpha[1].Tahun != "2026" {
+		t.Errorf("ALPHA shift malam dicatat pada tanggal jadwal: %+v", alpha[1])
+	}
+}
@@ -0,0 +1,116 @@
+package jobs
+
+import (
+	"fmt"
+	"log"
+	"my-flutter-backend/config"
+	"my-flutter-backend/internal/model"
+	"my-flutter-backend/internal/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// --- AUTO-CHECKOUT (LUPA ABSEN PULANG) ---
+// Kehadiran yang sudah check-in tapi belum check-out sampai jam pulang shift + batas check-out ditutup dengan
+// status pulang TIDAK_ABSEN_PULANG, lalu pegawai diberi notifikasi agar mengajukan koreksi.
+
+const StatusTidakAbsenPulang = "TIDAK_ABSEN_PULANG"
+
+func autoCheckoutInterval() time.Duration {
+	return time.Duration(config.GetEnvAsInt("AUTO_CHECKOUT_INTERVAL_MINUTES", 15)) * time.Minute
+}
+
+// CheckoutLimit: Batas check-out setelah jam pulang shift. Pakai BatasCheckoutJam shift, jika 0 pakai
+// AUTO_CHECKOUT_HOURS (default 6). 0 = tanpa batas (tidak ada auto-checkout).
+func CheckoutLimit(shift model.Shift) time.Duration {
+	hours := shift.BatasCheckoutJam
+	if hours <= 0 {
+		hours = config.GetEnvAsInt("AUTO_CHECKOUT_HOURS", 6)
+	}
+	if hours <= 0 {
+		return 0
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// StartAutoCheckout: Jalankan auto-checkout berkala di background. AUTO_CHECKOUT_INTERVAL_MINUTES=0 menonaktifkan.
+func StartAutoCheckout(db *gorm.DB) {
+	interval := autoCheckoutInterval()
+	if interval <= 0 {
+		log.Println("Auto-checkout nonaktif (AUTO_CHECKOUT_INTERVAL_MINUTES=0)")
+		return
+	}
+
+	go func() {
+		for {
+			now := time.Now()
+			// Shift lintas hari + batas check-out bisa melewati 1 hari, jadi ikut cek beberapa hari ke belakang
+			from := now.AddDate(0, 0, -alphaSweepLookbackDays()).Format("2006-01-02")
+			to := now.Format("2006-01-02")
+
+			closed, err := RunAutoCheckout(db, from, to, 0)
+			if err != nil {
+				log.Printf("Auto-checkout gagal: %v", err)
+			} else if closed > 0 {
+				log.Printf("Auto-checkout %s s/d %s: %d kehadiran ditutup %s", from, to, closed, StatusTidakAbsenPulang)
+			}
+
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// RunAutoCheckout: AutoCheckout dengan lock antar-instance
+func RunAutoCheckout(db *gorm.DB, from, to string, orgID uint) (int, error) {
+	closed := 0
+	_, err := withLock(db, "auto_checkout", func() error {
+		var err error
+		closed, err = AutoCheckout(db, from, to, orgID, time.Now())
+		return err
+	})
+	return closed, err
+}
+
+// AutoCheckout: Tutup kehadiran tanggal from..to yang belum check-out setelah batas check-out shift lewat.
+// Mengembalikan jumlah kehadiran yang ditutup.
+func AutoCheckout(db *gorm.DB, from, to string, orgID uint, now time.Time) (int, error) {
+	kehadiranRepo := repository.NewKehadiranRepository(db)
+	notifRepo := repository.NewNotifikasiRepository(db)
+
+	open, err := kehadiranRepo.GetOpenCheckouts(from, to, orgID)
+	if err != nil {
+		return 0, err
+	}
+
+	closed := 0
+	for _, o := range open {
+		limit := CheckoutLimit(o.Shift)
+		end, ok := ShiftEnd(o.Kehadiran.Tanggal, o.Shift)
+		if limit <= 0 || !ok || now.Before(end.Add(limit)) {
+			continue
+		}
+
+		// Update bersyarat: jika pegawai check-out tepat sebelum ini, baris tidak disentuh
+		updated, err := kehadiranRepo.CloseOpenCheckout(o.Kehadiran.ID, StatusTidakAbsenPulang)
+		if err != nil {
+			return closed, err
+		}
+		if !updated {
+			continue
+		}
+		closed++
+
+		notif := model.Notifikasi{
+			ASNID:   o.Kehadiran.ASNID,
+			Tipe:    StatusTidakAbsenPulang,
+			Judul:   "Tidak absen pulang",
+			Pesan:   fmt.Sprintf("Anda belum check-out untuk shift %s tanggal %s sampai batas waktu, sehingga tercatat TIDAK ABSEN PULANG. Ajukan koreksi kehadiran jika Anda bekerja sampai jam pulang.", o.Shift.NamaShift, o.Kehadiran.Tanggal),
+			Tanggal: o.Kehadiran.Tanggal,
+		}
+		if err := notifRepo.Create(&notif); err != nil {
+			log.Printf("Auto-checkout: gagal membuat notifikasi kehadiran %d: %v", o.Kehadiran.ID, err)
+		}
+	}
+	return closed, nil
+}
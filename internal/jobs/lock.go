@@ -0,0 +1,34 @@
+package jobs
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// withLock: Jalankan fn hanya jika lock MySQL (GET_LOCK) berhasil didapat, agar job berkala tidak
+// berjalan bersamaan saat aplikasi dijalankan di beberapa instance. ran = false jika lock dipegang instance lain.
+func withLock(db *gorm.DB, name string, fn func() error) (ran bool, err error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return false, err
+	}
+	ctx := context.Background()
+	// GET_LOCK terikat ke koneksi, jadi lock & release harus di koneksi yang sama
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	var got int
+	if err := conn.QueryRowContext(ctx, "SELECT COALESCE(GET_LOCK(?, 0), 0)", name).Scan(&got); err != nil {
+		return false, err
+	}
+	if got != 1 {
+		return false, nil
+	}
+	defer conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", name)
+
+	return true, fn()
+}
@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"log"
+	"my-flutter-backend/config"
+	"my-flutter-backend/internal/repository"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// --- PURGE SELFIE ---
+// File selfie yang umurnya melewati selfie_retensi_hari organisasi pegawai dihapus dari disk. Baris foto_kehadirans
+// tetap disimpan (waktu & koordinat) dengan dihapus_at terisi sebagai jejak.
+
+const selfiePurgeBatch = 500
+
+func selfiePurgeInterval() time.Duration {
+	return time.Duration(config.GetEnvAsInt("SELFIE_PURGE_INTERVAL_MINUTES", 60)) * time.Minute
+}
+
+// StartSelfiePurge: Jalankan purge selfie berkala di background. SELFIE_PURGE_INTERVAL_MINUTES=0 menonaktifkan.
+func StartSelfiePurge(db *gorm.DB) {
+	interval := selfiePurgeInterval()
+	if interval <= 0 {
+		log.Println("Purge selfie nonaktif (SELFIE_PURGE_INTERVAL_MINUTES=0)")
+		return
+	}
+
+	go func() {
+		for {
+			purged, err := RunSelfiePurge(db)
+			if err != nil {
+				log.Printf("Purge selfie gagal: %v", err)
+			} else if purged > 0 {
+				log.Printf("Purge selfie: %d file dihapus (retensi)", purged)
+			}
+
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// RunSelfiePurge: PurgeSelfies dengan lock antar-instance
+func RunSelfiePurge(db *gorm.DB) (int, error) {
+	purged := 0
+	_, err := withLock(db, "selfie_purge", func() error {
+		var err error
+		purged, err = PurgeSelfies(db, time.Now())
+		return err
+	})
+	return purged, err
+}
+
+// PurgeSelfies: Hapus file selfie yang melewati masa retensi per batch sampai habis. Mengembalikan jumlah file.
+func PurgeSelfies(db *gorm.DB, now time.Time) (int, error) {
+	fotoRepo := repository.NewFotoKehadiranRepository(db)
+
+	purged := 0
+	for {
+		expired, err := fotoRepo.GetExpired(now, selfiePurgeBatch)
+		if err != nil || len(expired) == 0 {
+			return purged, err
+		}
+		for _, f := range expired {
+			if f.Path != "" {
+				if err := os.Remove(f.Path); err != nil && !os.IsNotExist(err) {
+					return purged, err // Jangan tandai dihapus jika file masih ada
+				}
+			}
+			if err := fotoRepo.MarkPurged(f.ID, now); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+	}
+}
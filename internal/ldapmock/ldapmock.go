@@ -0,0 +1,172 @@
+// Package ldapmock: Server LDAP minimal (hanya simple bind) untuk development & uji coba backend password LDAP.
+// Dipakai cmd/mock-ldap dan test internal/passwordauth. JANGAN dipakai di production (tanpa TLS).
+package ldapmock
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strings"
+)
+
+const (
+	resultSuccess            = 0
+	resultProtocolError      = 2
+	resultInvalidCredentials = 49
+	resultUnwillingToPerform = 53
+)
+
+// Server: Users berisi DN -> password. Logger opsional (nil = tanpa log).
+type Server struct {
+	Users  map[string]string
+	Logger *log.Logger
+}
+
+// New: Buat server dari daftar NIP -> password dengan template DN berplaceholder {nip}
+func New(template string, passwords map[string]string) *Server {
+	users := make(map[string]string, len(passwords))
+	for nip, password := range passwords {
+		users[strings.ReplaceAll(template, "{nip}", nip)] = password
+	}
+	return &Server{Users: users}
+}
+
+// Serve: Terima koneksi sampai listener ditutup
+func (s *Server) Serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.serve(conn)
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		tag, msg, err := readTLV(r)
+		if err != nil || tag != 0x30 {
+			return
+		}
+		_, id, rest, err := parseTLV(msg)
+		if err != nil {
+			return
+		}
+		op, body, _, err := parseTLV(rest)
+		if err != nil {
+			return
+		}
+
+		switch op {
+		case 0x60: // BindRequest
+			code := s.bind(body)
+			if s.Logger != nil {
+				s.Logger.Printf("bind -> %d", code)
+			}
+			conn.Write(tlv(0x30, append(tlv(0x02, id), tlv(0x61, result(code, ""))...)))
+		case 0x42: // UnbindRequest
+			return
+		case 0x77: // ExtendedRequest (StartTLS tidak didukung mock)
+			conn.Write(tlv(0x30, append(tlv(0x02, id), tlv(0x78, result(resultProtocolError, "StartTLS tidak didukung mock"))...)))
+		default:
+			return
+		}
+	}
+}
+
+// bind: { version, name, simple [0] password }
+func (s *Server) bind(body []byte) int {
+	_, _, rest, err := parseTLV(body) // version
+	if err != nil {
+		return resultProtocolError
+	}
+	_, dn, rest, err := parseTLV(rest)
+	if err != nil {
+		return resultProtocolError
+	}
+	tag, password, _, err := parseTLV(rest)
+	if err != nil || tag != 0x80 {
+		return resultProtocolError
+	}
+	if len(password) == 0 {
+		return resultUnwillingToPerform // Unauthenticated bind ditolak
+	}
+	if expected, ok := s.Users[string(dn)]; ok && expected == string(password) {
+		return resultSuccess
+	}
+	return resultInvalidCredentials
+}
+func result(code int, message string) []byte {
+	out := tlv(0x0a, []byte{byte(code)})
+	out = append(out, tlv(0x04, nil)...)
+	return append(out, tlv(0x04, []byte(message))...)
+}
+
+func tlv(tag byte, value []byte) []byte {
+	n := len(value)
+	var length []byte
+	if n < 0x80 {
+		length = []byte{byte(n)}
+	} else {
+		for ; n > 0; n >>= 8 {
+			length = append([]byte{byte(n)}, length...)
+		}
+		length = append([]byte{0x80 | byte(len(length))}, length...)
+	}
+	out := append([]byte{tag}, length...)
+	return append(out, value...)
+}
+
+func readTLV(r *bufio.Reader) (byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	length := int(header[1])
+	if header[1]&0x80 != 0 {
+		n := int(header[1] & 0x7f)
+		if n == 0 || n > 4 {
+			return 0, nil, errors.New("panjang BER tidak didukung")
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return 0, nil, err
+		}
+		length = 0
+		for _, b := range buf {
+			length = length<<8 | int(b)
+		}
+	}
+	body := make([]byte, length)
+	_, err := io.ReadFull(r, body)
+	return header[0], body, err
+}
+
+func parseTLV(buf []byte) (byte, []byte, []byte, error) {
+	if len(buf) < 2 {
+		return 0, nil, nil, errors.New("BER terpotong")
+	}
+	length, offset := int(buf[1]), 2
+	if buf[1]&0x80 != 0 {
+		n := int(buf[1] & 0x7f)
+		if n == 0 || n > 4 || len(buf) < 2+n {
+			return 0, nil, nil, errors.New("panjang BER tidak valid")
+		}
+		length = 0
+		for _, b := range buf[2 : 2+n] {
+			length = length<<8 | int(b)
+		}
+		offset += n
+	}
+	if len(buf) < offset+length {
+		return 0, nil, nil, errors.New("BER terpotong")
+	}
+	return buf[0], buf[offset : offset+length], buf[offset+length:], nil
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"strings"
+
+	"my-flutter-backend/internal/model"
+	"my-flutter-backend/internal/repository"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// --- API KEY (SERVICE ACCOUNT) ---
+// Integrasi mesin (payroll, e-Kinerja) memakai API key milik service account organisasi.
+// Request API key hanya membawa organisasi_id (tanpa user_id / role), dan lolos Permission
+// hanya untuk permission yang tercantum di scopes service account tsb.
+
+// APIKeyPrefix: Awalan API key, membedakannya dari JWT di header Authorization
+const APIKeyPrefix = "sk_"
+
+const serviceAccountKey = "service_account"
+
+// HashAPIKey: API key hanya disimpan dalam bentuk SHA-256
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// AuthWithAPIKey: Varian Auth untuk route yang juga dibuka bagi service account.
+// API key dikirim lewat header "X-API-Key" atau "Authorization: Bearer sk_...". Tanpa API key -> Auth (JWT) biasa.
+func AuthWithAPIKey(c *fiber.Ctx) error {
+	key := c.Get("X-API-Key")
+	if bearer := strings.TrimPrefix(c.Get("Authorization"), "Bearer "); key == "" && strings.HasPrefix(bearer, APIKeyPrefix) {
+		key = bearer
+	}
+	if key == "" {
+		return Auth(c)
+	}
+
+	repo := repository.NewServiceAccountRepository(DB)
+	sa, err := repo.FindActiveByKeyHash(HashAPIKey(key))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "API key tidak valid, kadaluwarsa, atau sudah dicabut"})
+	}
+	if err := repo.TouchLastUsed(sa.ID, c.IP()); err != nil {
+		log.Printf("Gagal mencatat pemakaian API key %s: %v", sa.KeyPrefix, err)
+	}
+
+	c.Locals(serviceAccountKey, sa)
+	c.Locals("organisasi_id", float64(sa.OrganisasiID))
+	return c.Next()
+}
+
+// CurrentServiceAccount: Service account jika request memakai API key (nil jika login pegawai)
+func CurrentServiceAccount(c *fiber.Ctx) *model.ServiceAccount {
+	sa, _ := c.Locals(serviceAccountKey).(*model.ServiceAccount)
+	return sa
+}
+
+// ScopeList: Pecah kolom scopes (dipisah koma) menjadi daftar permission
+func ScopeList(scopes string) []string {
+	var list []string
+	for _, s := range strings.Split(scopes, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+func serviceAccountHasScope(sa *model.ServiceAccount, permission string) bool {
+	for _, s := range ScopeList(sa.Scopes) {
+		if s == permission {
+			return true
+		}
+	}
+	return false
+}
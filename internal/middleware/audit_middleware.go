@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log"
+	"reflect"
+	"strconv"
+
+	"my-flutter-backend/internal/model"
+	"my-flutter-backend/internal/repository"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AuditTarget: Entitas yang dicatat oleh middleware Audit
+type AuditTarget struct {
+	Entity   string      // Nama entitas di audit log, misal "asn", "jadwal", "role"
+	Model    interface{} // Contoh struct model (misal model.ASN{}) untuk memuat snapshot before/after
+	Preloads []string    // Relasi yang ikut disimpan di snapshot (misal "Permissions" untuk role)
+	IDParam  string      // Param route berisi ID entitas (default "id")
+	IDField  string      // Atau: field body JSON berisi ID entitas (misal "perizinan_id" untuk approval)
+	OwnOrg   bool        // Atau: entitas adalah organisasi milik aktor (PUT /api/admin/organisasi)
+}
+
+const (
+	auditEntityIDKey = "audit_entity_id"
+	auditNoteKey     = "audit_note"
+)
+
+// SetAuditEntityID: Dipanggil handler Create agar ID entitas baru tercatat di audit log
+func SetAuditEntityID(c *fiber.Ctx, id uint) {
+	c.Locals(auditEntityIDKey, id)
+}
+
+// SetAuditNote: Keterangan tambahan untuk entri audit (misal alasan impersonasi)
+func SetAuditNote(c *fiber.Ctx, note string) {
+	c.Locals(auditNoteKey, note)
+}
+
+// Audit: Catat mutasi ke tabel audit_logs. Snapshot entitas diambil sebelum dan sesudah handler
+// berjalan, lalu disimpan beserta diff-nya. Hanya request yang berhasil (status < 400) yang dicatat.
+// Dipasang di route SETELAH Auth/Permission agar aktor sudah diketahui.
+func Audit(action string, target AuditTarget) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		entityID := auditEntityID(c, target)
+		before := auditSnapshot(target, entityID)
+
+		err := c.Next()
+		if err != nil || c.Response().StatusCode() >= fiber.StatusBadRequest {
+			return err
+		}
+
+		if id, ok := c.Locals(auditEntityIDKey).(uint); ok {
+			entityID = id
+		}
+		after := auditSnapshot(target, entityID)
+
+		entry := model.AuditLog{
+			Action:     action,
+			EntityType: target.Entity,
+			EntityID:   entityID,
+			Before:     before,
+			After:      after,
+			Diff:       auditDiff(before, after),
+			Method:     c.Method(),
+			Path:       c.OriginalURL(),
+			StatusCode: c.Response().StatusCode(),
+			IPAddress:  c.IP(),
+			UserAgent:  c.Get(fiber.HeaderUserAgent),
+		}
+		if note, ok := c.Locals(auditNoteKey).(string); ok {
+			entry.Keterangan = note
+		}
+		if id, ok := c.Locals("user_id").(float64); ok {
+			entry.ActorID = uint(id)
+		}
+		if nip, ok := c.Locals("nip").(string); ok {
+			entry.ActorNIP = nip
+		}
+		if orgID, ok := c.Locals("organisasi_id").(float64); ok {
+			entry.OrganisasiID = uint(orgID)
+		}
+
+		// Gagal menulis audit tidak membatalkan mutasi yang sudah terjadi, tapi wajib terlihat di log server
+		if dbErr := repository.NewAuditLogRepository(DB).Create(&entry); dbErr != nil {
+			log.Printf("Gagal menulis audit log (%s %s #%d): %v", action, target.Entity, entityID, dbErr)
+		}
+		return nil
+	}
+}
+
+func auditEntityID(c *fiber.Ctx, target AuditTarget) uint {
+	if target.OwnOrg {
+		if orgID, ok := c.Locals("organisasi_id").(float64); ok {
+			return uint(orgID)
+		}
+		return 0
+	}
+	if target.IDField != "" {
+		var body map[string]interface{}
+		if err := json.Unmarshal(c.Body(), &body); err == nil {
+			if id, ok := body[target.IDField].(float64); ok && id > 0 {
+				return uint(id)
+			}
+		}
+		return 0
+	}
+
+	param := target.IDParam
+	if param == "" {
+		param = "id"
+	}
+	id, _ := strconv.Atoi(c.Params(param))
+	if id < 0 {
+		return 0
+	}
+	return uint(id)
+}
+
+// auditSnapshot: Muat entitas dalam bentuk JSON (field ber-tag json:"-" seperti password otomatis tidak ikut)
+func auditSnapshot(target AuditTarget, id uint) json.RawMessage {
+	if target.Model == nil || id == 0 {
+		return nil
+	}
+
+	obj := reflect.New(reflect.TypeOf(target.Model)).Interface()
+	query := DB
+	for _, p := range target.Preloads {
+		query = query.Preload(p)
+	}
+	if err := query.First(obj, id).Error; err != nil {
+		return nil // Belum ada (create gagal) atau sudah terhapus (delete)
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// auditDiff: Field level diff antara snapshot before dan after
+func auditDiff(before, after json.RawMessage) json.RawMessage {
+	var b, a map[string]interface{}
+	_ = json.Unmarshal(before, &b)
+	_ = json.Unmarshal(after, &a)
+
+	changes := make(map[string]map[string]interface{})
+	collect := func(key string) {
+		if key == "UpdatedAt" || key == "updated_at" {
+			return
+		}
+		if _, done := changes[key]; done || reflect.DeepEqual(b[key], a[key]) {
+			return
+		}
+		changes[key] = map[string]interface{}{"before": b[key], "after": a[key]}
+	}
+	for key := range b {
+		collect(key)
+	}
+	for key := range a {
+		collect(key)
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(changes)
+	return data
+}
@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"my-flutter-backend/config"
+	"my-flutter-backend/internal/repository"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
@@ -18,13 +20,8 @@ func Auth(c *fiber.Ctx) error {
 	tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
 
 	// 2. Parse dan Validasi Token
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fiber.ErrUnauthorized
-		}
-		// Pastikan key ini SAMA PERSIS dengan yang ada di asn_handler.go
-		return []byte("rahasia_negara"), nil
-	})
+	// Kunci dipilih berdasarkan header "kid" (lihat config.LoadJWTKeys)
+	token, err := config.JWT.Parse(tokenString)
 
 	if err != nil || !token.Valid {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid atau kadaluwarsa"})
@@ -32,10 +29,43 @@ func Auth(c *fiber.Ctx) error {
 
 	// 3. Simpan data user (Claims) ke Context agar bisa dipakai di Handler
 	claims := token.Claims.(jwt.MapClaims)
+	// Token bertipe khusus (refresh / 2fa_challenge) tidak boleh dipakai sebagai access token
+	if tokenType, _ := claims["type"].(string); tokenType != "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid atau kadaluwarsa"})
+	}
+	// Session sudah dicabut (logout / revoke): access token ikut tidak berlaku walau belum kadaluwarsa
+	if sid, ok := claims["sid"].(float64); ok {
+		active, err := repository.NewSessionRepository(DB).IsActive(uint(sid))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memvalidasi session"})
+		}
+		if !active {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Session sudah berakhir, silakan login kembali"})
+		}
+	}
 	c.Locals("user_id", claims["user_id"])
 	c.Locals("nip", claims["nip"])
 	c.Locals("role", claims["role"]) // Simpan Role ke Context
+	c.Locals("role_id", claims["role_id"])
 	c.Locals("organisasi_id", claims["organisasi_id"])
+	c.Locals("session_id", claims["sid"])
+	c.Locals("need_2fa", claims["need_2fa"] == true)
+
+	// Token impersonasi: hanya untuk melihat (GET/HEAD), setiap request (termasuk yang ditolak) dicatat ke
+	// audit log sekali saja walau Auth terpasang di group & route
+	if _, done := c.Locals(impersonatorKey).(*Impersonator); !done {
+		setImpersonator(c, claims)
+		if imp := CurrentImpersonator(c); imp != nil {
+			var err error
+			if c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead {
+				err = c.Next()
+			} else {
+				err = c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Token impersonasi hanya untuk melihat, perubahan data tidak diizinkan"})
+			}
+			logImpersonatedRequest(c, imp)
+			return err
+		}
+	}
 
 	return c.Next()
 }
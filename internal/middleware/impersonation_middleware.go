@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"log"
+
+	"my-flutter-backend/internal/model"
+	"my-flutter-backend/internal/repository"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// --- IMPERSONASI (Super Admin melihat aplikasi sebagai pegawai) ---
+// Token impersonasi berisi identitas pegawai target + claim "act" berisi Super Admin yang melakukan impersonasi.
+// Token ini: tidak punya session/refresh token, hanya boleh request GET/HEAD (ditolak di Auth),
+// tidak bisa mengakses route admin (Permission), diblokir dari route bertanda NoImpersonation
+// (lapis kedua jika route terpasang tanpa Auth di group), dan setiap request-nya tercatat di audit log.
+
+const impersonatorKey = "impersonator"
+
+// Impersonator: Aktor asli di balik token impersonasi
+type Impersonator struct {
+	UserID uint
+	NIP    string
+}
+
+// setImpersonator: Dipanggil Auth jika token punya claim "act"
+func setImpersonator(c *fiber.Ctx, claims jwt.MapClaims) {
+	act, ok := claims["act"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	imp := &Impersonator{}
+	if id, ok := act["user_id"].(float64); ok {
+		imp.UserID = uint(id)
+	}
+	imp.NIP, _ = act["nip"].(string)
+	c.Locals(impersonatorKey, imp)
+}
+
+// CurrentImpersonator: Aktor asli jika request memakai token impersonasi (nil jika tidak)
+func CurrentImpersonator(c *fiber.Ctx) *Impersonator {
+	imp, _ := c.Locals(impersonatorKey).(*Impersonator)
+	return imp
+}
+
+func IsImpersonated(c *fiber.Ctx) bool {
+	return CurrentImpersonator(c) != nil
+}
+
+// NoImpersonation: Tolak aksi yang tidak boleh dilakukan atas nama pegawai (absen, ganti password, dst)
+func NoImpersonation(c *fiber.Ctx) error {
+	if IsImpersonated(c) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Aksi ini tidak diizinkan saat impersonasi"})
+	}
+	return c.Next()
+}
+
+// Superuser: Route khusus role superuser (Super Admin)
+func Superuser(c *fiber.Ctx) error {
+	if IsImpersonated(c) || !IsSuperuser(c) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Akses ditolak: Khusus Super Admin"})
+	}
+	return c.Next()
+}
+
+// logImpersonatedRequest: Catat setiap request yang memakai token impersonasi ke audit log
+func logImpersonatedRequest(c *fiber.Ctx, imp *Impersonator) {
+	entry := model.AuditLog{
+		ActorID:    imp.UserID,
+		ActorNIP:   imp.NIP,
+		Action:     "IMPERSONATED_REQUEST",
+		EntityType: "asn",
+		Method:     c.Method(),
+		Path:       c.OriginalURL(),
+		StatusCode: c.Response().StatusCode(),
+		IPAddress:  c.IP(),
+		UserAgent:  c.Get(fiber.HeaderUserAgent),
+	}
+	if id, ok := c.Locals("user_id").(float64); ok {
+		entry.EntityID = uint(id)
+	}
+	// Organisasi pegawai target, agar admin organisasi tsb ikut bisa melihat jejaknya
+	if orgID, ok := c.Locals("organisasi_id").(float64); ok {
+		entry.OrganisasiID = uint(orgID)
+	}
+
+	if err := repository.NewAuditLogRepository(DB).Create(&entry); err != nil {
+		log.Printf("Gagal menulis audit impersonasi (%s %s): %v", c.Method(), c.OriginalURL(), err)
+	}
+}
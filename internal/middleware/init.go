@@ -6,4 +6,9 @@ var DB *gorm.DB
 
 func Init(db *gorm.DB) {
 	DB = db
+
+	// Cache permission milik koneksi DB sebelumnya tidak berlaku lagi
+	permissionCacheMu.Lock()
+	permissionCache = make(map[uint]cachedRole)
+	permissionCacheMu.Unlock()
 }
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"errors"
+	"my-flutter-backend/config"
+	"my-flutter-backend/internal/model"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Cache permission per Role ID (in-process) agar middleware.Permission tidak query DB di setiap request.
+// Cache dibuang saat role diubah/dihapus (lihat InvalidateRolePermissions). TTL tetap dipakai sebagai
+// pengaman jika aplikasi berjalan di beberapa instance (perubahan dari instance lain terlihat setelah TTL).
+type cachedRole struct {
+	superuser   bool
+	permissions map[string]bool
+	loadedAt    time.Time
+}
+
+var (
+	permissionCache   = make(map[uint]cachedRole)
+	permissionCacheMu sync.RWMutex
+)
+
+func permissionCacheTTL() time.Duration {
+	return time.Duration(config.GetEnvAsInt("PERMISSION_CACHE_TTL_SECONDS", 300)) * time.Second
+}
+
+// rolePermissions mengembalikan set permission (dan flag superuser) milik role, dari cache atau DB
+func rolePermissions(roleID uint) (cachedRole, error) {
+	permissionCacheMu.RLock()
+	cached, ok := permissionCache[roleID]
+	permissionCacheMu.RUnlock()
+	if ok && time.Since(cached.loadedAt) < permissionCacheTTL() {
+		return cached, nil
+	}
+
+	var role model.Role
+	if err := DB.Preload("Permissions").First(&role, roleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return cachedRole{}, errInvalidRole // Role sudah dihapus
+		}
+		return cachedRole{}, err
+	}
+
+	perms := make(map[string]bool, len(role.Permissions))
+	for _, p := range role.Permissions {
+		perms[p.NamaPermission] = true
+	}
+	cached = cachedRole{superuser: role.Superuser, permissions: perms, loadedAt: time.Now()}
+
+	permissionCacheMu.Lock()
+	permissionCache[roleID] = cached
+	permissionCacheMu.Unlock()
+
+	return cached, nil
+}
+
+var errInvalidRole = errors.New("role tidak valid")
+
+// currentRole: Ambil permission role user yang login (role_id dari token, cache per Role ID)
+func currentRole(c *fiber.Ctx) (cachedRole, error) {
+	if id, ok := c.Locals("role_id").(float64); ok {
+		return rolePermissions(uint(id))
+	}
+
+	// Token lama tanpa role_id: cari ID role berdasarkan nama
+	userRole, ok := c.Locals("role").(string)
+	if !ok {
+		return cachedRole{}, errInvalidRole
+	}
+	roleID, err := roleIDByName(userRole)
+	if err != nil {
+		return cachedRole{}, err
+	}
+	return rolePermissions(roleID)
+}
+
+// roleIDByName: Fallback untuk token lama yang belum membawa role_id
+func roleIDByName(name string) (uint, error) {
+	var role model.Role
+	err := DB.Select("id").Where("nama_role = ?", name).First(&role).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, errInvalidRole
+	}
+	return role.ID, err
+}
+
+// InvalidateRolePermissions dipanggil setelah permission role berubah atau role dihapus
+func InvalidateRolePermissions(roleID uint) {
+	permissionCacheMu.Lock()
+	delete(permissionCache, roleID)
+	permissionCacheMu.Unlock()
+}
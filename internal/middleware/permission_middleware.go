@@ -1,44 +1,74 @@
 package middleware
 
 import (
-	"my-flutter-backend/internal/model"
-
 	"github.com/gofiber/fiber/v2"
 )
 
 func Permission(requiredPermission string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		// 1. Ambil Role user hari Context (Diset di Auht middleware)
-		userRole, ok := c.Locals("role").(string)
-		if !ok {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Akses ditolak: Role tidak valid"})
+		// 0. Token impersonasi hanya untuk melihat aplikasi pegawai, bukan fitur admin
+		if IsImpersonated(c) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Akses ditolak: Token impersonasi tidak bisa mengakses fitur admin"})
 		}
 
-		// 2. Jika Super Admin, Bypass Permission Check (Opsional, tapi aman)
-		if userRole == "Super Admin" {
-			return c.Next()
+		// Token login mobile (tanpa 2FA) milik akun yang wajib 2FA: fitur admin hanya lewat login web + 2FA
+		if lacksTwoFactor(c) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Akses ditolak: Fitur admin memerlukan login web dengan 2FA", "two_factor_required": true})
 		}
 
-		// 3. Cek Permission ke Database
-		var role model.Role
-		// Preload Permissions agar bisa dicek
-		if err := DB.Preload("Permissions").Where("nama_role = ?", userRole).First(&role).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memvalidasi permission"})
+		// API key service account: hanya permission yang tercantum di scopes
+		if sa := CurrentServiceAccount(c); sa != nil {
+			if !serviceAccountHasScope(sa, requiredPermission) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Akses ditolak: API key tidak memiliki scope " + requiredPermission})
+			}
+			return c.Next()
 		}
 
-		// 4. Loop permissions role ini
-		isAllowed := false
-		for _, p := range role.Permissions {
-			if p.NamaPermission == requiredPermission {
-				isAllowed = true
-				break
+		// 1. Ambil Role user dari Context (Diset di Auth middleware)
+		role, err := currentRole(c)
+		if err != nil {
+			if err == errInvalidRole {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Akses ditolak: Role tidak valid"})
 			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memvalidasi permission"})
 		}
 
-		if !isAllowed {
+		// 2. Role superuser lolos semua cek, selain itu harus punya permission yang dibutuhkan
+		if !role.superuser && !role.permissions[requiredPermission] {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Akses ditolak: Anda tidak memiliki izin " + requiredPermission})
 		}
 
 		return c.Next()
 	}
 }
+
+// HasPermission: Cek permission user yang login di dalam handler (misal untuk override approval).
+// Role superuser selalu dianggap punya semua permission.
+func HasPermission(c *fiber.Ctx, permission string) bool {
+	if IsImpersonated(c) || lacksTwoFactor(c) {
+		return false
+	}
+	if sa := CurrentServiceAccount(c); sa != nil {
+		return serviceAccountHasScope(sa, permission)
+	}
+	role, err := currentRole(c)
+	if err != nil {
+		return false
+	}
+	return role.superuser || role.permissions[permission]
+}
+
+// IsSuperuser: Cek apakah role user yang login bertanda superuser
+func IsSuperuser(c *fiber.Ctx) bool {
+	if IsImpersonated(c) || lacksTwoFactor(c) || CurrentServiceAccount(c) != nil {
+		return false
+	}
+	role, err := currentRole(c)
+	return err == nil && role.superuser
+}
+
+// lacksTwoFactor: Access token tanpa 2FA untuk akun yang wajib 2FA (claim need_2fa, diset Auth)
+func lacksTwoFactor(c *fiber.Ctx) bool {
+	need, _ := c.Locals("need_2fa").(bool)
+	return need
+}
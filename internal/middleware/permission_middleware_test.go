@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"my-flutter-backend/internal/model"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Role yang sudah dihapus (token lama masih membawa role_id / nama role) ditolak 403, bukan 500
+func TestPermissionDeletedRole(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:permission_deleted_role?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	if err := db.AutoMigrate(&model.Role{}, &model.Permission{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	Init(db)
+
+	perm := model.Permission{NamaPermission: "kelola_pegawai"}
+	role := model.Role{NamaRole: "Admin", Permissions: []model.Permission{perm}}
+	if err := db.Create(&role).Error; err != nil {
+		t.Fatal(err)
+	}
+	deleted := model.Role{NamaRole: "Lama", Permissions: []model.Permission{perm}}
+	if err := db.Create(&deleted).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(&deleted).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		role func(c *fiber.Ctx)
+		want int
+	}{
+		{"ActiveRole", func(c *fiber.Ctx) { c.Locals("role_id", float64(role.ID)) }, fiber.StatusOK},
+		{"DeletedRoleID", func(c *fiber.Ctx) { c.Locals("role_id", float64(deleted.ID)) }, fiber.StatusForbidden},
+		{"DeletedRoleName", func(c *fiber.Ctx) { c.Locals("role", "Lama") }, fiber.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/admin", func(c *fiber.Ctx) error {
+				tc.role(c)
+				return c.Next()
+			}, Permission("kelola_pegawai"), func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/admin", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tc.want {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tc.want)
+			}
+		})
+	}
+}
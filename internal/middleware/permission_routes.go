@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"sort"
+	"sync"
+)
+
+// PermissionRoute: Satu endpoint yang dibuka oleh sebuah permission
+type PermissionRoute struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+// Katalog permission -> route, diisi saat Setup*Routes berjalan (lihat routes.withPermission)
+var (
+	permissionRoutesMu sync.RWMutex
+	permissionRoutes   = make(map[string][]PermissionRoute)
+)
+
+// RegisterPermissionRoute: Catat bahwa endpoint method+path dibuka oleh permission tertentu
+func RegisterPermissionRoute(permission, method, path string) {
+	permissionRoutesMu.Lock()
+	defer permissionRoutesMu.Unlock()
+	permissionRoutes[permission] = append(permissionRoutes[permission], PermissionRoute{Method: method, Path: path})
+}
+
+// PermissionRoutes: Salinan katalog permission -> route, diurutkan per path
+func PermissionRoutes() map[string][]PermissionRoute {
+	permissionRoutesMu.RLock()
+	defer permissionRoutesMu.RUnlock()
+
+	result := make(map[string][]PermissionRoute, len(permissionRoutes))
+	for perm, routes := range permissionRoutes {
+		copied := append([]PermissionRoute(nil), routes...)
+		sort.SliceStable(copied, func(i, j int) bool {
+			if copied[i].Path != copied[j].Path {
+				return copied[i].Path < copied[j].Path
+			}
+			return copied[i].Method < copied[j].Method
+		})
+		result[perm] = copied
+	}
+	return result
+}
@@ -6,7 +6,7 @@ func Role(allowedRoles ...string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Ambil role user dari context (diset di Auth middleware)
 		userRole, ok := c.Locals("role").(string)
-		if !ok {
+		if !ok || lacksTwoFactor(c) {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Akses ditolak: Role tidak valid"})
 		}
 
@@ -1,28 +1,47 @@
 package model
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type ASN struct {
 	gorm.Model
-	AtasanID     *uint      `json:"atasan_id"` // Self-reference
-	OrganisasiID uint       `json:"organisasi_id"`
-	RoleID       uint       `json:"role_id"`
-	Nama         string     `json:"nama"`
-	NIP          string     `json:"nip" gorm:"column:nip;unique;not null"`
-	Password     string     `json:"-"`
-	Email        string     `json:"email"`
-	NoHP         string     `json:"no_hp"`
-	Foto         string     `json:"foto"`
-	Jabatan      string     `json:"jabatan"`
-	Bidang       string     `json:"bidang"`
-	IsActive     bool       `json:"is_active" gorm:"default:true"`
-	
+	AtasanID     *uint  `json:"atasan_id"` // Self-reference
+	OrganisasiID uint   `json:"organisasi_id"`
+	RoleID       uint   `json:"role_id"`
+	Nama         string `json:"nama"`
+	NIP          string `json:"nip" gorm:"column:nip;unique;not null"`
+	Password     string `json:"-"`
+	Email        string `json:"email"`
+	NoHP         string `json:"no_hp"`
+	Foto         string `json:"foto"`
+	Jabatan      string `json:"jabatan"`
+	Bidang       string `json:"bidang"`
+	IsActive     bool   `json:"is_active" gorm:"default:true"`
+
+	PasswordChangedAt *time.Time `json:"-"`                             // Refresh token yang terbit sebelum waktu ini ditolak
+	SSOSubject        *string    `json:"-" gorm:"size:191;uniqueIndex"` // Claim "sub" IdP, terisi saat login SSO pertama
+
+	MustChangePassword bool   `json:"must_change_password" gorm:"default:false"` // Wajib ganti password (akun baru / setelah reset admin)
+	ActivationPending  bool   `json:"activation_pending" gorm:"default:false"`   // Akun undangan: password belum dibuat pegawai
+	FotoReferensi      string `json:"foto_referensi"`                            // Pembanding verifikasi wajah, hanya diatur admin (bukan Foto profil)
+
 	// Relasi
-	Atasan    *ASN       `json:"atasan" gorm:"foreignKey:AtasanID"`
-	Bawahan   []ASN      `json:"bawahan" gorm:"foreignKey:AtasanID"`
-	Devices   []Device   `json:"devices"`
-	Jadwal    []Jadwal   `json:"jadwal"`
-	Kehadiran []Kehadiran `json:"kehadiran"`
-	Role       Role       `gorm:"foreignKey:RoleID"`
-    Organisasi Organisasi `gorm:"foreignKey:OrganisasiID"`
-}
\ No newline at end of file
+	Atasan     *ASN        `json:"atasan" gorm:"foreignKey:AtasanID"`
+	Bawahan    []ASN       `json:"bawahan" gorm:"foreignKey:AtasanID"`
+	Devices    []Device    `json:"devices"`
+	Jadwal     []Jadwal    `json:"jadwal"`
+	Kehadiran  []Kehadiran `json:"kehadiran"`
+	Role       Role        `gorm:"foreignKey:RoleID"`
+	Organisasi Organisasi  `gorm:"foreignKey:OrganisasiID"`
+}
+
+// PasswordHistory: Hash password yang pernah dipakai, untuk mencegah pemakaian ulang N password terakhir
+type PasswordHistory struct {
+	ID        uint      `gorm:"primaryKey"`
+	ASNID     uint      `gorm:"index"`
+	Hash      string    `gorm:"not null"`
+	CreatedAt time.Time `gorm:"index"`
+}
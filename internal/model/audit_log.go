@@ -0,0 +1,28 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditLog: Jejak setiap mutasi administratif (untuk pemeriksaan Inspektorat).
+// Tabel ini append-only: tidak ada update maupun soft delete.
+type AuditLog struct {
+	ID           uint            `json:"id" gorm:"primarykey"`
+	CreatedAt    time.Time       `json:"created_at" gorm:"index"`
+	ActorID      uint            `json:"actor_id"`
+	ActorNIP     string          `json:"actor_nip" gorm:"size:30;index"`
+	OrganisasiID uint            `json:"organisasi_id" gorm:"index"`
+	Action       string          `json:"action" gorm:"size:30;index"`      // CREATE / UPDATE / DELETE / APPROVE / IMPORT / ...
+	EntityType   string          `json:"entity_type" gorm:"size:30;index"` // asn / jadwal / shift / lokasi / role / hari_libur / ...
+	EntityID     uint            `json:"entity_id" gorm:"index"`           // 0 untuk operasi massal (import, generate, hapus per tanggal)
+	Before       json.RawMessage `json:"before" gorm:"type:json"`
+	After        json.RawMessage `json:"after" gorm:"type:json"`
+	Diff         json.RawMessage `json:"diff" gorm:"type:json"` // {"field": {"before": .., "after": ..}}
+	Keterangan   string          `json:"keterangan"`            // Misal alasan impersonasi
+	Method       string          `json:"method" gorm:"size:10"`
+	Path         string          `json:"path"`
+	StatusCode   int             `json:"status_code"`
+	IPAddress    string          `json:"ip_address" gorm:"size:45"`
+	UserAgent    string          `json:"user_agent"`
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DeviceChangeRequest: Permintaan pegawai mendaftarkan HP baru saat batas device organisasi sudah penuh.
+// Diajukan dari layar login HP baru (device_change_token), disetujui atasan atau admin (kelola_pegawai).
+type DeviceChangeRequest struct {
+	gorm.Model
+	ASNID           uint       `json:"asn_id" gorm:"index"`
+	NIPAtasan       string     `json:"nip_atasan" gorm:"index"`
+	UUID            string     `json:"uuid"` // UUID HP baru
+	Brand           string     `json:"brand"`
+	Series          string     `json:"series"`
+	FirebaseToken   string     `json:"-"`
+	ReplaceDeviceID *uint      `json:"replace_device_id"` // Device lama yang dilepas (opsional, default device terlama)
+	Alasan          string     `json:"alasan"`
+	Status          string     `json:"status" gorm:"default:MENUNGGU"` // MENUNGGU / DISETUJUI / DITOLAK
+	ProcessedByID   *uint      `json:"processed_by_id"`
+	ProcessedAt     *time.Time `json:"processed_at"`
+	Catatan         string     `json:"catatan"` // Catatan penyetuju
+
+	// Relasi
+	ASN ASN `gorm:"foreignKey:ASNID" json:"asn"`
+}
+
+// DeviceHistory: Riwayat setiap device yang pernah terikat ke akun (tidak ikut terhapus saat reset device)
+type DeviceHistory struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	ASNID         uint       `json:"asn_id" gorm:"index"`
+	DeviceID      uint       `json:"device_id" gorm:"index"`
+	UUID          string     `json:"uuid"`
+	Brand         string     `json:"brand"`
+	Series        string     `json:"series"`
+	BoundAt       time.Time  `json:"bound_at"`
+	BoundVia      string     `json:"bound_via"` // LOGIN / PERMINTAAN
+	RequestID     *uint      `json:"request_id"`
+	UnboundAt     *time.Time `json:"unbound_at"`
+	UnboundReason string     `json:"unbound_reason"` // RESET_ADMIN / DIGANTI
+	UnboundByID   *uint      `json:"unbound_by_id"`  // Admin / penyetuju yang melepas device
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ASNInvitation: Undangan aktivasi akun pegawai baru. Link aktivasi dikirim ke email pegawai,
+// pegawai membuat password sendiri. Token hanya disimpan dalam bentuk hash.
+type ASNInvitation struct {
+	gorm.Model
+	ASNID      uint       `json:"asn_id" gorm:"index"`
+	Email      string     `json:"email"`
+	TokenHash  string     `json:"-" gorm:"size:64;uniqueIndex"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	SentByID   *uint      `json:"sent_by_id"`  // Admin yang membuat / mengirim ulang undangan
+	EmailSent  bool       `json:"email_sent"`  // false = pengiriman email gagal (cek konfigurasi SMTP)
+	AcceptedAt *time.Time `json:"accepted_at"` // Akun diaktivasi melalui undangan ini
+	RevokedAt  *time.Time `json:"revoked_at"`  // Diganti undangan baru / dibatalkan admin
+}
@@ -1,6 +1,10 @@
 package model
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Kehadiran struct {
 	gorm.Model
@@ -14,7 +18,7 @@ type Kehadiran struct {
 	JamMasukReal       string `json:"jam_masuk_real"`
 	JamPulangReal      string `json:"jam_pulang_real"`
 	StatusMasuk        string `json:"status_masuk"`        // HADIR/TERLAMBAT/CUTI/IZIN/ALPHA
-	StatusPulang       string `json:"status_pulang"`       // HADIR/PULANG_CEPAT/CUTI/IZIN
+	StatusPulang       string `json:"status_pulang"`       // HADIR/PULANG_CEPAT/CUTI/IZIN/ALPHA/TIDAK_ABSEN_PULANG
 	StatusLokasiMasuk  string `json:"status_lokasi_masuk"` // VALID/INVALID
 	StatusLokasiPulang string `json:"status_lokasi_pulang"`
 	KoordinatMasuk     string `json:"koordinat_masuk"`
@@ -24,4 +28,27 @@ type Kehadiran struct {
 	Hari    string `json:"hari"`
 	Bulan   string `json:"bulan"`
 	Tahun   string `json:"tahun"`
+
+	// Verifikasi wajah selfie check-in vs foto referensi (ASN.FotoReferensi) (kosong = tidak diverifikasi)
+	FaceScore        *float64   `json:"face_score"`
+	FaceStatus       string     `json:"face_status" gorm:"size:10;index"`        // LOLOS/GAGAL/ERROR
+	FaceReviewStatus string     `json:"face_review_status" gorm:"size:10;index"` // MENUNGGU/DITERIMA/DITOLAK (hanya GAGAL/ERROR)
+	FaceReviewByID   *uint      `json:"face_review_by_id"`
+	FaceReviewAt     *time.Time `json:"face_review_at"`
+	FaceReviewNote   string     `json:"face_review_note"`
+}
+
+// FotoKehadiran: Selfie bukti absen masuk/pulang. File disimpan di luar folder publik /uploads dan hanya
+// bisa diambil lewat API (pegawai ybs, atasan, admin).
+type FotoKehadiran struct {
+	gorm.Model
+	KehadiranID uint       `json:"kehadiran_id" gorm:"index"`
+	ASNID       uint       `json:"asn_id" gorm:"index"`
+	Jenis       string     `json:"jenis" gorm:"size:10"` // MASUK/PULANG
+	Path        string     `json:"-"`
+	ContentType string     `json:"content_type" gorm:"size:50"`
+	DiambilAt   time.Time  `json:"diambil_at" gorm:"index"`
+	Latitude    float64    `json:"latitude"`
+	Longitude   float64    `json:"longitude"`
+	DihapusAt   *time.Time `json:"dihapus_at"` // File dihapus karena masa retensi habis (metadata tetap disimpan)
 }
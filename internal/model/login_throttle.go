@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LoginThrottle menyimpan penghitung gagal login per NIP atau per IP.
+// LockLevel naik setiap kali terkunci sehingga durasi kunci berikutnya makin lama (progresif).
+type LoginThrottle struct {
+	gorm.Model
+	Scope        string     `json:"scope" gorm:"size:8;uniqueIndex:idx_login_throttle_key"` // NIP / IP
+	Key          string     `json:"key" gorm:"size:64;uniqueIndex:idx_login_throttle_key"`
+	FailedCount  int        `json:"failed_count"`
+	LastFailedAt *time.Time `json:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until"`
+	LockLevel    int        `json:"lock_level"`
+}
+
+// LoginLockoutEvent mencatat riwayat penguncian & pembukaan kunci akun (untuk audit admin)
+type LoginLockoutEvent struct {
+	gorm.Model
+	Scope       string     `json:"scope" gorm:"size:8;index:idx_lockout_event_key"`
+	Key         string     `json:"key" gorm:"size:64;index:idx_lockout_event_key"`
+	Event       string     `json:"event" gorm:"size:16"` // LOCKED / UNLOCKED
+	LockLevel   int        `json:"lock_level"`
+	LockedUntil *time.Time `json:"locked_until"`
+	IPAddress   string     `json:"ip_address" gorm:"size:64"` // IP yang memicu kunci
+	ActorID     *uint      `json:"actor_id"`                  // Admin yang membuka kunci
+}
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"encoding/json"
+
 	"gorm.io/gorm"
 )
 
@@ -22,6 +24,15 @@ type Shift struct {
 	NamaShift    string `json:"nama_shift"`
 	JamMasuk     string `json:"jam_masuk"`
 	JamPulang    string `json:"jam_pulang"`
+	// Aturan check-in/out (menit, relatif terhadap jam shift agar aman untuk shift lintas hari)
+	ToleransiTerlambatMenit int `json:"toleransi_terlambat_menit"` // Check-in s/d jam masuk + toleransi masih HADIR (0 = tanpa toleransi)
+	CheckInMulaiMenit       int `json:"check_in_mulai_menit"`      // Check-in paling awal N menit sebelum jam masuk (0 = tanpa batas)
+	CheckInBatasMenit       int `json:"check_in_batas_menit"`      // Check-in paling lambat N menit setelah jam masuk (0 = tanpa batas)
+	CheckOutMulaiMenit      int `json:"check_out_mulai_menit"`     // Check-out paling awal N menit sebelum jam pulang (0 = tanpa batas)
+	CheckOutBatasMenit      int `json:"check_out_batas_menit"`     // Check-out paling lambat N menit setelah jam pulang (0 = s/d BatasCheckoutJam)
+	// BatasCheckoutJam: Batas auto-checkout (jam setelah jam pulang shift). Lewat batas, kehadiran yang belum check-out
+	// ditutup otomatis dengan status TIDAK_ABSEN_PULANG. 0 = pakai default AUTO_CHECKOUT_HOURS.
+	BatasCheckoutJam int `json:"batas_checkout_jam"`
 }
 
 type Device struct {
@@ -52,7 +63,34 @@ type Organisasi struct {
 	gorm.Model
 	NamaOrganisasi string   `json:"nama_organisasi"`
 	EmailAdmin     string   `json:"email_admin"`
+	SSOOnly        bool     `json:"sso_only" gorm:"default:false"`          // Pegawai wajib login lewat SSO (password ditolak)
 	Lokasis        []Lokasi `json:"lokasis" gorm:"foreignKey:OrganisasiID"` // Relasi One-to-Many
+
+	// Backend verifikasi password, urutan fallback dipisah koma ("local", "ldap", "ldap,local"). Kosong = local.
+	PasswordBackends string `json:"password_backends" gorm:"size:50"`
+	LDAPURL          string `json:"ldap_url" gorm:"column:ldap_url"`                           // ldap://host:389 atau ldaps://host:636
+	LDAPUserDN       string `json:"ldap_user_dn" gorm:"column:ldap_user_dn"`                   // Template DN bind, misal "uid={nip},ou=pegawai,dc=dinas,dc=go,dc=id"
+	LDAPStartTLS     bool   `json:"ldap_start_tls" gorm:"column:ldap_start_tls;default:false"` // Upgrade ldap:// ke TLS sebelum bind
+
+	MaxDevices int `json:"max_devices" gorm:"default:1"` // Batas HP yang boleh terikat per pegawai
+
+	// Kebijakan password (akun dengan password lokal)
+	PasswordMinLength     int  `json:"password_min_length" gorm:"default:8"`
+	PasswordRequireUpper  bool `json:"password_require_upper" gorm:"default:false"`
+	PasswordRequireLower  bool `json:"password_require_lower" gorm:"default:false"`
+	PasswordRequireDigit  bool `json:"password_require_digit" gorm:"default:false"`
+	PasswordRequireSymbol bool `json:"password_require_symbol" gorm:"default:false"`
+	PasswordHistory       int  `json:"password_history" gorm:"default:3"`     // Tolak pemakaian ulang N password terakhir (0 = nonaktif)
+	PasswordExpiryDays    int  `json:"password_expiry_days" gorm:"default:0"` // Password wajib diganti setelah N hari (0 = tidak kadaluwarsa)
+
+	// Selfie bukti absen
+	SelfieWajib       bool `json:"selfie_wajib" gorm:"default:false"`     // Check-in/out ditolak tanpa selfie
+	SelfieRetensiHari int  `json:"selfie_retensi_hari" gorm:"default:90"` // File selfie dihapus otomatis setelah N hari (0 = simpan selamanya)
+
+	// Verifikasi wajah selfie check-in (butuh FACE_VERIFIER di server)
+	FaceVerifyEnabled   bool    `json:"face_verify_enabled" gorm:"default:false"`
+	FaceMatchThreshold  float64 `json:"face_match_threshold" gorm:"default:0.8"` // Skor >= ambang -> LOLOS, di bawahnya GAGAL & masuk antrean review
+	FaceRejectThreshold float64 `json:"face_reject_threshold" gorm:"default:0"`  // Skor < ambang -> check-in ditolak (0 = tidak pernah ditolak)
 }
 
 type Lokasi struct {
@@ -63,15 +101,31 @@ type Lokasi struct {
 	Latitude     float64 `json:"latitude"`
 	Longitude    float64 `json:"longitude"`
 	RadiusMeter  float64 `json:"radius_meter"`
+	// Geofence: GeoJSON Polygon/MultiPolygon ([longitude, latitude]). Jika diisi, validasi absen memakai
+	// batas polygon ini dan radius_meter diabaikan. Kosong = radius dari titik latitude/longitude.
+	Geofence json.RawMessage `json:"geofence" gorm:"type:text"`
+
+	Akses []LokasiAkses `json:"akses" gorm:"foreignKey:LokasiID"` // Kosong = semua pegawai organisasi boleh absen di sini
+}
+
+// LokasiAkses: Aturan pegawai yang boleh absen di suatu lokasi. Cukup cocok dengan salah satu aturan.
+type LokasiAkses struct {
+	gorm.Model
+	LokasiID uint   `json:"lokasi_id" gorm:"index"`
+	Tipe     string `json:"tipe" gorm:"type:varchar(20)"` // ASN, BIDANG, JABATAN
+	ASNID    *uint  `json:"asn_id"`                       // Tipe ASN
+	Nilai    string `json:"nilai"`                        // Nama bidang / jabatan (tipe BIDANG / JABATAN)
 }
 
 type Role struct {
 	gorm.Model
 	NamaRole    string       `json:"nama_role"`
+	Superuser   bool         `json:"superuser" gorm:"default:false"` // Lolos semua cek permission (tidak bergantung nama role)
 	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions;"`
 }
 
 type Permission struct {
 	gorm.Model
 	NamaPermission string `json:"nama_permission"` // Contoh: "view_dashboard", "create_asn"
+	Deskripsi      string `json:"deskripsi"`
 }
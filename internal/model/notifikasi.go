@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Notifikasi: Pemberitahuan in-app untuk pegawai (dibaca aplikasi mobile lewat /api/notifikasi)
+type Notifikasi struct {
+	gorm.Model
+	ASNID    uint       `json:"asn_id" gorm:"index"`
+	Tipe     string     `json:"tipe"` // TIDAK_ABSEN_PULANG
+	Judul    string     `json:"judul"`
+	Pesan    string     `json:"pesan"`
+	Tanggal  string     `json:"tanggal"` // Tanggal kehadiran terkait (YYYY-MM-DD), untuk pengajuan koreksi
+	DibacaAt *time.Time `json:"dibaca_at"`
+}
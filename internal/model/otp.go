@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PasswordResetOTP menyimpan permintaan OTP lupa password (persisten, aman untuk multi-instance).
+// Kode OTP & reset token hanya disimpan dalam bentuk hash.
+type PasswordResetOTP struct {
+	gorm.Model
+	ASNID     uint      `json:"asn_id"`
+	NIP       string    `json:"nip" gorm:"size:32;index"`
+	RequestIP string    `json:"request_ip" gorm:"size:64;index"`
+	CodeHash  string    `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Attempts  int       `json:"attempts"` // Jumlah percobaan verifikasi
+
+	VerifiedAt    *time.Time `json:"verified_at"`
+	InvalidatedAt *time.Time `json:"invalidated_at"` // Diganti OTP baru / terlalu banyak percobaan
+
+	// Reset Token: diterbitkan setelah OTP valid, dipakai sekali di /forgot-password/reset
+	ResetTokenHash      string     `json:"-" gorm:"size:64;index"`
+	ResetTokenExpiresAt *time.Time `json:"reset_token_expires_at"`
+	ResetAt             *time.Time `json:"reset_at"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ServiceAccount: Akun mesin per organisasi untuk integrasi sistem lain (payroll, e-Kinerja).
+// Mengakses API dengan API key (header X-API-Key), bukan login NIP. API key hanya ditampilkan
+// sekali saat dibuat / diganti; yang disimpan hanya hash SHA-256.
+type ServiceAccount struct {
+	gorm.Model
+	OrganisasiID uint       `json:"organisasi_id" gorm:"index"`
+	Nama         string     `json:"nama"`
+	Deskripsi    string     `json:"deskripsi"`
+	Scopes       string     `json:"scopes" gorm:"size:255"`    // Permission yang diizinkan, dipisah koma (misal "view_rekap")
+	KeyPrefix    string     `json:"key_prefix" gorm:"size:16"` // Awalan API key untuk identifikasi (sk_xxxxxxxx)
+	KeyHash      string     `json:"-" gorm:"size:64;uniqueIndex"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+	LastUsedIP   string     `json:"last_used_ip" gorm:"size:45"`
+	CreatedByID  uint       `json:"created_by_id"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	RevokedByID  *uint      `json:"revoked_by_id"`
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session mewakili satu login (satu "family" refresh token) pada satu perangkat/browser.
+// Setiap refresh akan merotasi token di dalam session yang sama.
+type Session struct {
+	gorm.Model
+	ASNID        uint       `json:"asn_id" gorm:"index"`
+	DeviceID     *uint      `json:"device_id"`  // Terisi untuk login mobile (device binding)
+	Client       string     `json:"client"`     // MOBILE / WEB
+	IPAddress    string     `json:"ip_address"` // IP saat terakhir digunakan
+	UserAgent    string     `json:"user_agent"`
+	LastSeenAt   time.Time  `json:"last_seen_at"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	RevokeReason string     `json:"revoke_reason"` // LOGOUT / REUSE_DETECTED / PASSWORD_CHANGED / NONAKTIF / ...
+
+	// Relasi
+	Device *Device `json:"device" gorm:"foreignKey:DeviceID"`
+}
+
+// RefreshToken menyimpan hash dari jti refresh token yang pernah diterbitkan.
+// Token yang sudah dipakai (UsedAt != nil) lalu dipakai lagi dianggap dicuri -> seluruh session dicabut.
+type RefreshToken struct {
+	gorm.Model
+	SessionID uint       `json:"session_id" gorm:"index"`
+	TokenHash string     `json:"-" gorm:"size:64;uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
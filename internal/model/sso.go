@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SSOLoginState: Satu percobaan login SSO (OIDC authorization code) yang sedang berjalan.
+// State hanya disimpan dalam bentuk hash & hanya bisa dipakai sekali di callback.
+type SSOLoginState struct {
+	gorm.Model
+	StateHash    string     `json:"-" gorm:"size:64;uniqueIndex"`
+	Nonce        string     `json:"-"`
+	CodeVerifier string     `json:"-"`      // PKCE
+	Client       string     `json:"client"` // MOBILE / WEB
+	ExpiresAt    time.Time  `json:"expires_at"`
+	UsedAt       *time.Time `json:"used_at"`
+
+	// Data perangkat (khusus MOBILE) untuk device binding saat callback
+	DeviceUUID    string `json:"device_uuid"`
+	Brand         string `json:"brand"`
+	Series        string `json:"series"`
+	FirebaseToken string `json:"-"`
+}
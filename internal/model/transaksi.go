@@ -23,7 +23,7 @@ type PerizinanKehadiran struct {
 	ASNID            uint   `json:"asn_id"`
 	NIPAtasan        string `json:"nip_atasan"`
 	TanggalKehadiran string `json:"tanggal_kehadiran"`
-	TipeKoreksi      string `json:"tipe_koreksi"` // TELAT, PULANG_CEPAT, LUAR_RADIUS
+	TipeKoreksi      string `json:"tipe_koreksi"` // TELAT, PULANG_CEPAT, TIDAK_ABSEN_PULANG, LUAR_RADIUS
 	IsLokasi         bool   `json:"is_lokasi"`    // True jika Perizinan Lokasi, False jika Perizinan Kehadiran
 	Alasan           string `json:"alasan"`
 	Status           string `json:"status" gorm:"default:PENDING"`
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TwoFactor menyimpan secret TOTP milik ASN. 2FA baru aktif setelah ConfirmedAt terisi.
+type TwoFactor struct {
+	gorm.Model
+	ASNID        uint       `json:"asn_id" gorm:"uniqueIndex"`
+	Secret       string     `json:"-" gorm:"size:64"`
+	ConfirmedAt  *time.Time `json:"confirmed_at"`
+	LastUsedStep int64      `json:"-"` // Mencegah kode TOTP yang sama dipakai dua kali
+}
+
+// RecoveryCode adalah kode cadangan sekali pakai jika aplikasi authenticator hilang
+type RecoveryCode struct {
+	gorm.Model
+	ASNID    uint       `json:"asn_id" gorm:"index"`
+	CodeHash string     `json:"-" gorm:"size:64"`
+	UsedAt   *time.Time `json:"used_at"`
+}
@@ -0,0 +1,319 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"my-flutter-backend/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Provider: Identity provider eksternal dengan alur authorization code.
+// Implementasi bawaan adalah OpenID Connect (lihat Client); provider lain cukup memenuhi interface ini.
+type Provider interface {
+	// AuthCodeURL: URL halaman login IdP. codeChallenge = PKCE S256 dari code verifier.
+	AuthCodeURL(state, nonce, codeChallenge string) string
+	// Exchange: Tukar authorization code dengan identitas user yang sudah diverifikasi (termasuk nonce).
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error)
+}
+
+// Identity: Identitas user dari IdP
+type Identity struct {
+	Subject string // Claim "sub", unik & permanen per user di IdP
+	NIP     string // Claim NIP (nama claim diatur lewat OIDC_NIP_CLAIM)
+	Email   string
+	Nama    string
+}
+
+// Config: Konfigurasi client OIDC (Relying Party)
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURI  string
+	Scopes       []string
+	NIPClaim     string
+}
+
+// Enabled: SSO aktif jika OIDC_ISSUER diatur
+func Enabled() bool {
+	return config.GetEnv("OIDC_ISSUER", "") != ""
+}
+
+// FromEnv: Provider OIDC dari environment. Mengembalikan nil jika OIDC_ISSUER belum diatur (SSO nonaktif).
+//
+//	OIDC_ISSUER, OIDC_CLIENT_ID, OIDC_CLIENT_SECRET, OIDC_REDIRECT_URI
+//	OIDC_SCOPES    (default "openid profile email")
+//	OIDC_NIP_CLAIM (default "nip"; isi "sub" jika subject IdP adalah NIP)
+func FromEnv() Provider {
+	if !Enabled() {
+		return nil
+	}
+	return NewClient(Config{
+		Issuer:       config.GetEnv("OIDC_ISSUER", ""),
+		ClientID:     config.GetEnv("OIDC_CLIENT_ID", ""),
+		ClientSecret: config.GetEnv("OIDC_CLIENT_SECRET", ""),
+		RedirectURI:  config.GetEnv("OIDC_REDIRECT_URI", ""),
+		Scopes:       strings.Fields(config.GetEnv("OIDC_SCOPES", "openid profile email")),
+		NIPClaim:     config.GetEnv("OIDC_NIP_CLAIM", "nip"),
+	})
+}
+
+// Client: Implementasi Provider untuk OpenID Connect (discovery + JWKS + PKCE)
+type Client struct {
+	cfg        Config
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	discovery *discoveryDocument
+	keys      map[string]*rsa.PublicKey
+}
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+func NewClient(cfg Config) *Client {
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+	if cfg.NIPClaim == "" {
+		cfg.NIPClaim = "nip"
+	}
+	return &Client{cfg: cfg, httpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// discover: Ambil /.well-known/openid-configuration (di-cache setelah berhasil)
+func (p *Client) discover(ctx context.Context) (*discoveryDocument, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var doc discoveryDocument
+	if err := p.getJSON(ctx, p.cfg.Issuer+"/.well-known/openid-configuration", "", &doc); err != nil {
+		return nil, fmt.Errorf("discovery OIDC gagal: %w", err)
+	}
+	if strings.TrimSuffix(doc.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("issuer discovery %q tidak sama dengan OIDC_ISSUER", doc.Issuer)
+	}
+	p.discovery = &doc
+	return p.discovery, nil
+}
+
+func (p *Client) AuthCodeURL(state, nonce, codeChallenge string) string {
+	doc, err := p.discover(context.Background())
+	if err != nil {
+		return ""
+	}
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURI)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", codeChallenge)
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return doc.AuthorizationEndpoint + sep + q.Encode()
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (p *Client) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error) {
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. Tukar code -> token (client_secret_basic + PKCE)
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURI)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token endpoint tidak bisa dihubungi: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tok tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return nil, fmt.Errorf("respons token endpoint tidak valid: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || tok.IDToken == "" {
+		return nil, fmt.Errorf("token endpoint menolak: %s %s", tok.Error, tok.ErrorDescription)
+	}
+
+	// 2. Verifikasi ID token (signature JWKS, iss, aud, exp, nonce)
+	claims, err := p.verifyIDToken(ctx, doc, tok.IDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Claim NIP boleh tidak ada di ID token -> coba userinfo endpoint
+	if _, ok := claims[p.cfg.NIPClaim]; !ok && doc.UserinfoEndpoint != "" && tok.AccessToken != "" {
+		var info map[string]interface{}
+		if err := p.getJSON(ctx, doc.UserinfoEndpoint, tok.AccessToken, &info); err == nil && info["sub"] == claims["sub"] {
+			for k, v := range info {
+				if _, exists := claims[k]; !exists {
+					claims[k] = v
+				}
+			}
+		}
+	}
+
+	identity := &Identity{
+		Subject: claimString(claims, "sub"),
+		NIP:     claimString(claims, p.cfg.NIPClaim),
+		Email:   claimString(claims, "email"),
+		Nama:    claimString(claims, "name"),
+	}
+	if identity.Subject == "" {
+		return nil, errors.New("ID token tidak memiliki claim sub")
+	}
+	return identity, nil
+}
+
+func (p *Client) verifyIDToken(ctx context.Context, doc *discoveryDocument, idToken, nonce string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(idToken, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.publicKey(ctx, doc, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512"}),
+		jwt.WithIssuer(doc.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+		jwt.WithJSONNumber(), // NIP 18 digit tidak muat di float64
+	)
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("ID token tidak valid: %w", err)
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	if claimString(claims, "nonce") != nonce {
+		return nil, errors.New("nonce ID token tidak cocok")
+	}
+	return claims, nil
+}
+
+// publicKey: Cari kunci di JWKS IdP. kid tidak dikenal -> JWKS diambil ulang (rotasi kunci di IdP).
+func (p *Client) publicKey(ctx context.Context, doc *discoveryDocument, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	key, ok := p.keys[kid]
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(ctx, doc.JWKSURI, "", &set); err != nil {
+		return nil, fmt.Errorf("JWKS IdP tidak bisa diambil: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	// IdP dengan satu kunci kadang tidak mengirim kid
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("kid %q tidak ada di JWKS IdP", kid)
+}
+
+func (p *Client) getJSON(ctx context.Context, endpoint, bearer string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: status %d", endpoint, resp.StatusCode)
+	}
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	return dec.Decode(out)
+}
+
+func claimString(claims jwt.MapClaims, name string) string {
+	switch v := claims[name].(type) {
+	case string:
+		return v
+	case json.Number:
+		// NIP kadang dikirim sebagai angka oleh IdP
+		return v.String()
+	}
+	return ""
+}
@@ -0,0 +1,110 @@
+package passwordauth
+
+import (
+	"errors"
+	"fmt"
+	"my-flutter-backend/internal/model"
+	"strings"
+)
+
+// Backend: Sumber verifikasi password login (hash lokal di tabel ASN, direktori LDAP, dst).
+type Backend interface {
+	Name() string
+	// Verify: nil jika password benar. ErrInvalidCredentials jika ditolak,
+	// ErrUnavailable jika backend tidak bisa dihubungi (boleh lanjut ke backend berikutnya).
+	Verify(asn *model.ASN, password string) error
+}
+
+var (
+	ErrInvalidCredentials = errors.New("password salah")
+	ErrUnavailable        = errors.New("backend password tidak tersedia")
+)
+
+const (
+	BackendLocal = "local"
+	BackendLDAP  = "ldap"
+)
+
+// ParseOrder: Urutan backend dari pengaturan organisasi ("ldap,local"). Kosong -> hanya lokal.
+func ParseOrder(spec string) ([]string, error) {
+	var order []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if name != BackendLocal && name != BackendLDAP {
+			return nil, fmt.Errorf("backend password %q tidak dikenal", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("backend password %q disebut lebih dari sekali", name)
+		}
+		seen[name] = true
+		order = append(order, name)
+	}
+	if len(order) == 0 {
+		order = []string{BackendLocal}
+	}
+	return order, nil
+}
+
+// Backends: Daftar backend untuk ASN sesuai pengaturan organisasinya (Organisasi harus di-preload).
+// Role superuser selalu memakai password lokal agar tetap bisa masuk saat direktori bermasalah
+// dan tidak bisa diambil alih lewat server LDAP organisasi.
+func Backends(asn *model.ASN) []Backend {
+	if asn.Role.Superuser {
+		return []Backend{Local{}}
+	}
+
+	order, err := ParseOrder(asn.Organisasi.PasswordBackends)
+	if err != nil {
+		// Pengaturan rusak: jangan diam-diam membuka login lewat password lokal
+		return nil
+	}
+
+	backends := make([]Backend, 0, len(order))
+	for _, name := range order {
+		switch name {
+		case BackendLocal:
+			backends = append(backends, Local{})
+		case BackendLDAP:
+			backends = append(backends, NewLDAP(LDAPConfigFromOrganisasi(&asn.Organisasi)))
+		}
+	}
+	return backends
+}
+
+// Verify: Cek password sesuai urutan backend organisasi.
+// Backend berikutnya hanya dicoba jika backend sebelumnya tidak tersedia (server mati/timeout);
+// password yang ditolak backend yang bisa dihubungi langsung dianggap salah.
+func Verify(asn *model.ASN, password string) error {
+	if password == "" {
+		return ErrInvalidCredentials
+	}
+
+	backends := Backends(asn)
+	if len(backends) == 0 {
+		return ErrUnavailable
+	}
+
+	var lastErr error = ErrUnavailable
+	for _, backend := range backends {
+		err := backend.Verify(asn, password)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, ErrUnavailable) {
+			return ErrInvalidCredentials
+		}
+		lastErr = err
+	}
+	return lastErr
+}
+
+// LocalManaged: Password utama akun disimpan di aplikasi (bisa diganti/di-reset lewat aplikasi).
+// False jika organisasi memakai direktori eksternal sebagai backend pertama.
+func LocalManaged(asn *model.ASN) bool {
+	backends := Backends(asn)
+	return len(backends) > 0 && backends[0].Name() == BackendLocal
+}
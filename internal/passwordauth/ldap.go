@@ -0,0 +1,371 @@
+package passwordauth
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"my-flutter-backend/config"
+	"my-flutter-backend/internal/model"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LDAPConfig: Koneksi ke direktori LDAP/Active Directory organisasi.
+//
+//	URL        ldap://host:389 atau ldaps://host:636
+//	UserDN     template DN dengan placeholder {nip}, misal "uid={nip},ou=pegawai,dc=dinas,dc=go,dc=id"
+//	           (Active Directory juga menerima UPN: "{nip}@dinas.go.id")
+//	StartTLS   upgrade koneksi ldap:// ke TLS sebelum bind (password tidak dikirim polos)
+type LDAPConfig struct {
+	URL      string
+	UserDN   string
+	StartTLS bool
+	Timeout  time.Duration
+}
+
+// LDAPConfigFromOrganisasi: Pengaturan LDAP organisasi + timeout dari ENV LDAP_TIMEOUT_SECONDS (default 5)
+func LDAPConfigFromOrganisasi(org *model.Organisasi) LDAPConfig {
+	return LDAPConfig{
+		URL:      org.LDAPURL,
+		UserDN:   org.LDAPUserDN,
+		StartTLS: org.LDAPStartTLS,
+		Timeout:  time.Duration(config.GetEnvAsInt("LDAP_TIMEOUT_SECONDS", 5)) * time.Second,
+	}
+}
+
+// Validate: Dipakai admin saat menyimpan pengaturan organisasi
+func (cfg LDAPConfig) Validate() error {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Host == "" || (u.Scheme != "ldap" && u.Scheme != "ldaps") {
+		return errors.New("ldap_url harus berformat ldap://host:port atau ldaps://host:port")
+	}
+	if !strings.Contains(cfg.UserDN, "{nip}") {
+		return errors.New("ldap_user_dn harus mengandung placeholder {nip}")
+	}
+	if cfg.StartTLS && u.Scheme == "ldaps" {
+		return errors.New("ldap_start_tls tidak dipakai bersama ldaps://")
+	}
+	return nil
+}
+
+// LDAP: Verifikasi password dengan simple bind sebagai DN milik NIP
+type LDAP struct {
+	cfg LDAPConfig
+}
+
+func NewLDAP(cfg LDAPConfig) *LDAP {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	return &LDAP{cfg: cfg}
+}
+
+func (l *LDAP) Name() string { return BackendLDAP }
+
+// LDAP result code (RFC 4511)
+const (
+	ldapSuccess             = 0
+	ldapNoSuchObject        = 32
+	ldapInvalidDNSyntax     = 34
+	ldapInappropriateAuth   = 48
+	ldapInvalidCredentials  = 49
+	ldapInsufficientAccess  = 50
+	ldapUnwillingToPerform  = 53
+	ldapStartTLSOID         = "1.3.6.1.4.1.1466.20037"
+	ldapProtocolVersion     = 3
+	ldapMaxResponseByteSize = 1 << 20
+)
+
+// LDAPResultError: Bind ditolak server dengan result code tertentu
+type LDAPResultError struct {
+	Code    int
+	Message string
+}
+
+func (e *LDAPResultError) Error() string {
+	return fmt.Sprintf("LDAP result %d: %s", e.Code, e.Message)
+}
+
+func (l *LDAP) Verify(asn *model.ASN, password string) error {
+	// Bind dengan password kosong = "unauthenticated bind" yang selalu sukses, wajib ditolak di sini
+	if password == "" {
+		return ErrInvalidCredentials
+	}
+	if err := l.cfg.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	err := l.Bind(UserDN(l.cfg.UserDN, asn.NIP), password)
+	var result *LDAPResultError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &result):
+		switch result.Code {
+		case ldapNoSuchObject, ldapInvalidDNSyntax, ldapInappropriateAuth, ldapInvalidCredentials, ldapInsufficientAccess, ldapUnwillingToPerform:
+			return ErrInvalidCredentials
+		}
+	}
+	// Error jaringan/TLS/protokol atau server sibuk -> anggap tidak tersedia
+	return fmt.Errorf("%w: %v", ErrUnavailable, err)
+}
+
+// UserDN: Isi placeholder {nip} di template DN (nilai di-escape sesuai RFC 4514)
+func UserDN(template, nip string) string {
+	return strings.ReplaceAll(template, "{nip}", escapeDNValue(nip))
+}
+
+func escapeDNValue(v string) string {
+	var b strings.Builder
+	for i, r := range v {
+		switch {
+		case strings.ContainsRune(`,+"\<>;=`, r),
+			i == 0 && (r == ' ' || r == '#'),
+			i == len(v)-1 && r == ' ':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20:
+			fmt.Fprintf(&b, "\\%02x", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Bind: Buka koneksi, (StartTLS), simple bind, lalu unbind. Nil jika bind berhasil.
+func (l *LDAP) Bind(dn, password string) error {
+	u, err := url.Parse(l.cfg.URL)
+	if err != nil {
+		return err
+	}
+	host := u.Host
+	if u.Port() == "" {
+		port := "389"
+		if u.Scheme == "ldaps" {
+			port = "636"
+		}
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	dialer := &net.Dialer{Timeout: l.cfg.Timeout}
+	var conn net.Conn
+	if u.Scheme == "ldaps" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", host, tlsConfig(u.Hostname()))
+	} else {
+		conn, err = dialer.Dial("tcp", host)
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { conn.Close() }() // conn bisa diganti koneksi TLS setelah StartTLS
+	conn.SetDeadline(time.Now().Add(l.cfg.Timeout))
+
+	msgID := 1
+	if l.cfg.StartTLS {
+		if err := ldapRoundTrip(conn, msgID, startTLSRequest(), 0x78); err != nil {
+			return fmt.Errorf("StartTLS gagal: %w", err)
+		}
+		tlsConn := tls.Client(conn, tlsConfig(u.Hostname()))
+		if err := tlsConn.Handshake(); err != nil {
+			return err
+		}
+		conn = tlsConn
+		msgID++
+	}
+
+	if err := ldapRoundTrip(conn, msgID, bindRequest(dn, password), 0x61); err != nil {
+		return err
+	}
+
+	// UnbindRequest: [APPLICATION 2] NULL, tanpa respons
+	conn.Write(berTLV(0x30, append(berInt(msgID+1), 0x42, 0x00)))
+	return nil
+}
+
+var (
+	ldapRootCAsOnce sync.Once
+	ldapRootCAs     *x509.CertPool
+)
+
+// tlsConfig: Sertifikat diverifikasi dengan CA sistem, atau CA internal dari ENV LDAP_CA_FILE (PEM)
+func tlsConfig(serverName string) *tls.Config {
+	ldapRootCAsOnce.Do(func() {
+		path := config.GetEnv("LDAP_CA_FILE", "")
+		if path == "" {
+			return
+		}
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Println("Warning: LDAP_CA_FILE tidak bisa dibaca:", err)
+			return
+		}
+		pool := x509.NewCertPool()
+		if pool.AppendCertsFromPEM(pem) {
+			ldapRootCAs = pool
+		}
+	})
+	return &tls.Config{ServerName: serverName, RootCAs: ldapRootCAs, MinVersion: tls.VersionTLS12}
+}
+
+// --- BER encoding minimal untuk LDAPv3 (hanya BindRequest & StartTLS) ---
+
+func berLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+func berTLV(tag byte, value []byte) []byte {
+	out := append([]byte{tag}, berLength(len(value))...)
+	return append(out, value...)
+}
+
+func berInt(v int) []byte {
+	b := []byte{byte(v)}
+	for v >>= 8; v > 0; v >>= 8 {
+		b = append([]byte{byte(v)}, b...)
+	}
+	if b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return berTLV(0x02, b)
+}
+
+// bindRequest: [APPLICATION 0] { version, name, simple [0] password }
+func bindRequest(dn, password string) []byte {
+	var body []byte
+	body = append(body, berInt(ldapProtocolVersion)...)
+	body = append(body, berTLV(0x04, []byte(dn))...)
+	body = append(body, berTLV(0x80, []byte(password))...)
+	return berTLV(0x60, body)
+}
+
+// startTLSRequest: ExtendedRequest [APPLICATION 23] { requestName [0] OID StartTLS }
+func startTLSRequest() []byte {
+	return berTLV(0x77, berTLV(0x80, []byte(ldapStartTLSOID)))
+}
+
+// ldapRoundTrip: Kirim satu LDAPMessage lalu baca respons dengan tag protocolOp yang diharapkan
+func ldapRoundTrip(conn net.Conn, msgID int, op []byte, responseTag byte) error {
+	msg := berTLV(0x30, append(berInt(msgID), op...))
+	if _, err := conn.Write(msg); err != nil {
+		return err
+	}
+
+	tag, body, err := readBER(bufio.NewReader(conn))
+	if err != nil {
+		return err
+	}
+	if tag != 0x30 {
+		return errors.New("respons LDAP tidak valid")
+	}
+
+	// messageID
+	tag, id, rest, err := parseBER(body)
+	if err != nil || tag != 0x02 || berToInt(id) != msgID {
+		return errors.New("messageID respons LDAP tidak cocok")
+	}
+	// protocolOp
+	tag, result, _, err := parseBER(rest)
+	if err != nil || tag != responseTag {
+		return errors.New("jenis respons LDAP tidak terduga")
+	}
+
+	// LDAPResult ::= { resultCode ENUMERATED, matchedDN, diagnosticMessage, ... }
+	tag, code, rest, err := parseBER(result)
+	if err != nil || tag != 0x0a {
+		return errors.New("resultCode LDAP tidak valid")
+	}
+	resultCode := berToInt(code)
+	if resultCode == ldapSuccess {
+		return nil
+	}
+
+	var message string
+	if _, _, rest, err = parseBER(rest); err == nil { // matchedDN
+		if _, diag, _, err := parseBER(rest); err == nil {
+			message = string(diag)
+		}
+	}
+	return &LDAPResultError{Code: resultCode, Message: message}
+}
+
+// readBER: Baca satu elemen TLV utuh dari koneksi
+func readBER(r *bufio.Reader) (byte, []byte, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	first, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length := int(first)
+	if first&0x80 != 0 {
+		n := int(first & 0x7f)
+		if n == 0 || n > 4 {
+			return 0, nil, errors.New("panjang BER tidak didukung")
+		}
+		length = 0
+		for i := 0; i < n; i++ {
+			b, err := r.ReadByte()
+			if err != nil {
+				return 0, nil, err
+			}
+			length = length<<8 | int(b)
+		}
+	}
+	if length > ldapMaxResponseByteSize {
+		return 0, nil, errors.New("respons LDAP terlalu besar")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return tag, body, nil
+}
+
+// parseBER: Pecah elemen TLV pertama di buffer -> (tag, value, sisa)
+func parseBER(buf []byte) (byte, []byte, []byte, error) {
+	if len(buf) < 2 {
+		return 0, nil, nil, errors.New("BER terpotong")
+	}
+	tag, length, offset := buf[0], int(buf[1]), 2
+	if buf[1]&0x80 != 0 {
+		n := int(buf[1] & 0x7f)
+		if n == 0 || n > 4 || len(buf) < 2+n {
+			return 0, nil, nil, errors.New("panjang BER tidak valid")
+		}
+		length = 0
+		for _, b := range buf[2 : 2+n] {
+			length = length<<8 | int(b)
+		}
+		offset += n
+	}
+	if length < 0 || len(buf) < offset+length {
+		return 0, nil, nil, errors.New("BER terpotong")
+	}
+	return tag, buf[offset : offset+length], buf[offset+length:], nil
+}
+
+func berToInt(b []byte) int {
+	v := 0
+	for _, x := range b {
+		v = v<<8 | int(x)
+	}
+	return v
+}
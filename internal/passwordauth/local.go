@@ -0,0 +1,19 @@
+package passwordauth
+
+import (
+	"my-flutter-backend/internal/model"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Local: Password bcrypt di kolom ASN.Password
+type Local struct{}
+
+func (Local) Name() string { return BackendLocal }
+
+func (Local) Verify(asn *model.ASN, password string) error {
+	if err := bcrypt.CompareHashAndPassword([]byte(asn.Password), []byte(password)); err != nil {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package passwordauth
+
+import (
+	"errors"
+	"my-flutter-backend/internal/ldapmock"
+	"my-flutter-backend/internal/model"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	testNIP         = "198001012005011001"
+	testDN          = "uid={nip},ou=pegawai,dc=dinas,dc=go,dc=id"
+	ldapPassword    = "rahasia-ldap"
+	localPassword   = "rahasia-lokal"
+	ldapTestTimeout = 2 * time.Second
+)
+
+// startMockLDAP: Jalankan internal/ldapmock di port acak, return URL ldap://
+func startMockLDAP(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go ldapmock.New(testDN, map[string]string{testNIP: ldapPassword}).Serve(ln)
+	return "ldap://" + ln.Addr().String()
+}
+
+// unreachableLDAP: Alamat yang tadinya listen lalu ditutup (koneksi ditolak)
+func unreachableLDAP(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return "ldap://" + addr
+}
+
+func testASN(t *testing.T, backends, ldapURL string) *model.ASN {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(localPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	return &model.ASN{
+		NIP:      testNIP,
+		Password: string(hash),
+		Organisasi: model.Organisasi{
+			PasswordBackends: backends,
+			LDAPURL:          ldapURL,
+			LDAPUserDN:       testDN,
+		},
+	}
+}
+
+func TestLDAPBind(t *testing.T) {
+	url := startMockLDAP(t)
+	asn := testASN(t, BackendLDAP, url)
+	backend := NewLDAP(LDAPConfig{URL: url, UserDN: testDN, Timeout: ldapTestTimeout})
+
+	if err := backend.Verify(asn, ldapPassword); err != nil {
+		t.Errorf("bind dengan password benar: %v", err)
+	}
+	if err := backend.Verify(asn, "salah"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("bind dengan password salah: err = %v, want ErrInvalidCredentials", err)
+	}
+
+	other := *asn
+	other.NIP = "199002022010012002"
+	if err := backend.Verify(&other, ldapPassword); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("bind NIP tidak terdaftar: err = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestLDAPUnreachable(t *testing.T) {
+	url := unreachableLDAP(t)
+	backend := NewLDAP(LDAPConfig{URL: url, UserDN: testDN, Timeout: ldapTestTimeout})
+
+	if err := backend.Verify(testASN(t, BackendLDAP, url), ldapPassword); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("server mati: err = %v, want ErrUnavailable", err)
+	}
+}
+
+// Backend berikutnya hanya dicoba jika backend sebelumnya tidak tersedia, bukan jika menolak password
+func TestVerifyFallbackOrder(t *testing.T) {
+	up := startMockLDAP(t)
+	down := unreachableLDAP(t)
+
+	cases := []struct {
+		name, backends, url, password string
+		superuser                     bool
+		want                          error
+	}{
+		{"LDAPFirstAccepts", "ldap,local", up, ldapPassword, false, nil},
+		{"LDAPFirstRejectsNoFallback", "ldap,local", up, localPassword, false, ErrInvalidCredentials},
+		{"LDAPDownFallsBackToLocal", "ldap,local", down, localPassword, false, nil},
+		{"LDAPDownLocalRejects", "ldap,local", down, ldapPassword, false, ErrInvalidCredentials},
+		{"LocalFirstAccepts", "local,ldap", up, localPassword, false, nil},
+		{"LocalFirstRejectsNoFallback", "local,ldap", up, ldapPassword, false, ErrInvalidCredentials},
+		{"LDAPOnlyDown", "ldap", down, ldapPassword, false, ErrUnavailable},
+		{"EmptyPassword", "ldap,local", up, "", false, ErrInvalidCredentials},
+		{"InvalidOrder", "ldap,radius", up, ldapPassword, false, ErrUnavailable},
+		{"SuperuserAlwaysLocal", "ldap", up, ldapPassword, true, ErrInvalidCredentials},
+		{"SuperuserLocalAccepts", "ldap", down, localPassword, true, nil},
+	}
+	t.Setenv("LDAP_TIMEOUT_SECONDS", "2")
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			asn := testASN(t, tc.backends, tc.url)
+			asn.Role.Superuser = tc.superuser
+			err := Verify(asn, tc.password)
+			if tc.want == nil {
+				if err != nil {
+					t.Fatalf("err = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("err = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package passwordauth
+
+import (
+	"fmt"
+	"my-flutter-backend/internal/model"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	DefaultMinLength = 8
+	MinMinLength     = 6  // Batas bawah min_length yang boleh diatur organisasi
+	MaxMinLength     = 72 // bcrypt hanya memakai 72 byte pertama
+	MaxHistory       = 24
+	MaxExpiryDays    = 3650
+)
+
+// Policy: Kebijakan password organisasi
+type Policy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	History       int // Jumlah password terakhir yang tidak boleh dipakai ulang
+	ExpiryDays    int // 0 = tidak kadaluwarsa
+}
+
+func PolicyFromOrganisasi(org *model.Organisasi) Policy {
+	p := Policy{
+		MinLength:     org.PasswordMinLength,
+		RequireUpper:  org.PasswordRequireUpper,
+		RequireLower:  org.PasswordRequireLower,
+		RequireDigit:  org.PasswordRequireDigit,
+		RequireSymbol: org.PasswordRequireSymbol,
+		History:       org.PasswordHistory,
+		ExpiryDays:    org.PasswordExpiryDays,
+	}
+	if p.MinLength < MinMinLength {
+		p.MinLength = DefaultMinLength
+	}
+	return p
+}
+
+// PolicyError: Daftar aturan yang dilanggar password baru
+type PolicyError struct {
+	Violations []string
+}
+
+func (e *PolicyError) Error() string {
+	return "Password tidak memenuhi kebijakan: " + strings.Join(e.Violations, "; ")
+}
+
+// Validate: Cek password baru terhadap kebijakan. NIP pemilik akun tidak boleh ada di dalam password.
+func (p Policy) Validate(password, nip string) error {
+	var violations []string
+
+	if len([]rune(password)) < p.MinLength {
+		violations = append(violations, fmt.Sprintf("minimal %d karakter", p.MinLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		violations = append(violations, "wajib mengandung huruf besar")
+	}
+	if p.RequireLower && !hasLower {
+		violations = append(violations, "wajib mengandung huruf kecil")
+	}
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, "wajib mengandung angka")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, "wajib mengandung simbol")
+	}
+	if nip = strings.TrimSpace(nip); nip != "" && strings.Contains(password, nip) {
+		violations = append(violations, "tidak boleh mengandung NIP")
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// Expired: Password terakhir diganti lebih dari ExpiryDays hari lalu
+func (p Policy) Expired(changedAt time.Time) bool {
+	return p.ExpiryDays > 0 && time.Since(changedAt) > time.Duration(p.ExpiryDays)*24*time.Hour
+}
+
+// ValidateSettings: Dipakai admin saat menyimpan kebijakan organisasi
+func (p Policy) ValidateSettings() error {
+	switch {
+	case p.MinLength < MinMinLength || p.MinLength > MaxMinLength:
+		return fmt.Errorf("password_min_length harus antara %d dan %d", MinMinLength, MaxMinLength)
+	case p.History < 0 || p.History > MaxHistory:
+		return fmt.Errorf("password_history harus antara 0 dan %d", MaxHistory)
+	case p.ExpiryDays < 0 || p.ExpiryDays > MaxExpiryDays:
+		return fmt.Errorf("password_expiry_days harus antara 0 dan %d", MaxExpiryDays)
+	}
+	return nil
+}
@@ -2,28 +2,38 @@ package repository
 
 import (
 	"my-flutter-backend/internal/model"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type ASNRepository interface {
 	FindByNIP(nip string) (*model.ASN, error)
+	FindBySSOSubject(subject string) (*model.ASN, error)
+	SetSSOSubject(id uint, subject string) error
 	GetLokasiByOrganisasiID(orgID uint) (*model.Lokasi, error)
+	GetOrganisasiByID(orgID uint) (*model.Organisasi, error)
 	FindByID(id uint) (*model.ASN, error)
+	FindByIDWithOrganisasi(id uint) (*model.ASN, error)
+	FindByIDInOrg(id, orgID uint) (*model.ASN, error)
 	Create(asn *model.ASN) error
 	Update(asn *model.ASN) error
-	Delete(id uint) error
-	AddDevice(device *model.Device) error
+	SetPassword(asn *model.ASN, hash string, mustChange bool) error
+	AddPasswordHistory(asnID uint, hash string) error
+	GetPasswordHistory(asnID uint, limit int) ([]string, error)
+	Delete(id, orgID uint) error
 	GetAll(search string) ([]model.ASN, error)
-	ResetDevice(asnID uint) error
 	Count() (int64, error)
 	GetByPermission(permissionName string, orgID uint) ([]model.ASN, error)
 	GetAllByOrganisasiID(orgID uint) ([]model.ASN, error)
 	GetByAtasanID(atasanID uint) ([]model.ASN, error)
 	GetAdminsByOrganisasiID(orgID uint) ([]model.ASN, error)
-	UpdateStatus(id uint, isActive bool) error
+	UpdateStatus(id, orgID uint, isActive bool) error
 }
 
+// passwordHistoryKeep: Sama dengan batas maksimal password_history organisasi
+const passwordHistoryKeep = 24
+
 type asnRepository struct {
 	db *gorm.DB
 }
@@ -39,18 +49,50 @@ func (r *asnRepository) FindByNIP(nip string) (*model.ASN, error) {
 	return &asn, err
 }
 
+// FindBySSOSubject: Cari ASN yang sudah terhubung ke akun IdP (claim "sub")
+func (r *asnRepository) FindBySSOSubject(subject string) (*model.ASN, error) {
+	var asn model.ASN
+	err := r.db.Preload("Role.Permissions").Preload("Organisasi").Preload("Devices").Preload("Atasan").Where("sso_subject = ?", subject).First(&asn).Error
+	return &asn, err
+}
+
+// SetSSOSubject: Hubungkan ASN ke akun IdP (sekali saja, saat login SSO pertama via claim NIP)
+func (r *asnRepository) SetSSOSubject(id uint, subject string) error {
+	return r.db.Model(&model.ASN{}).Where("id = ?", id).Update("sso_subject", subject).Error
+}
+
 func (r *asnRepository) GetLokasiByOrganisasiID(orgID uint) (*model.Lokasi, error) {
 	var lokasi model.Lokasi
 	err := r.db.Where("organisasi_id = ?", orgID).First(&lokasi).Error
 	return &lokasi, err
 }
 
+func (r *asnRepository) GetOrganisasiByID(orgID uint) (*model.Organisasi, error) {
+	var org model.Organisasi
+	err := r.db.First(&org, orgID).Error
+	return &org, err
+}
+
 func (r *asnRepository) FindByID(id uint) (*model.ASN, error) {
 	var asn model.ASN
 	err := r.db.Preload("Role.Permissions").Preload("Atasan").Preload("Devices").First(&asn, id).Error
 	return &asn, err
 }
 
+// FindByIDWithOrganisasi: FindByID + pengaturan organisasi (backend password, SSO) untuk verifikasi ulang password
+func (r *asnRepository) FindByIDWithOrganisasi(id uint) (*model.ASN, error) {
+	var asn model.ASN
+	err := r.db.Preload("Role.Permissions").Preload("Organisasi").Preload("Atasan").Preload("Devices").First(&asn, id).Error
+	return &asn, err
+}
+
+// FindByIDInOrg: Sama seperti FindByID, tapi hanya jika ASN milik organisasi orgID (untuk akses admin)
+func (r *asnRepository) FindByIDInOrg(id, orgID uint) (*model.ASN, error) {
+	var asn model.ASN
+	err := r.db.Scopes(scopeOrg(orgID)).Preload("Role.Permissions").Preload("Atasan").Preload("Devices").First(&asn, id).Error
+	return &asn, err
+}
+
 func (r *asnRepository) Create(asn *model.ASN) error {
 	return r.db.Create(asn).Error
 }
@@ -61,12 +103,57 @@ func (r *asnRepository) Update(asn *model.ASN) error {
 	return r.db.Omit("Role").Save(asn).Error
 }
 
-func (r *asnRepository) Delete(id uint) error {
-	return r.db.Delete(&model.ASN{}, id).Error
+// SetPassword: Simpan hash password baru + catat ke riwayat password (maksimal 24 entri terakhir per ASN)
+func (r *asnRepository) SetPassword(asn *model.ASN, hash string, mustChange bool) error {
+	now := time.Now()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.ASN{}).Where("id = ?", asn.ID).Updates(map[string]interface{}{
+			"password":             hash,
+			"password_changed_at":  now,
+			"must_change_password": mustChange,
+			"activation_pending":   false, // Password sudah ditentukan -> undangan aktivasi tidak diperlukan lagi
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := addPasswordHistory(tx, asn.ID, hash); err != nil {
+			return err
+		}
+		asn.Password = hash
+		asn.PasswordChangedAt = &now
+		asn.MustChangePassword = mustChange
+		asn.ActivationPending = false
+		return nil
+	})
+}
+
+func (r *asnRepository) AddPasswordHistory(asnID uint, hash string) error {
+	return addPasswordHistory(r.db, asnID, hash)
+}
+
+func addPasswordHistory(tx *gorm.DB, asnID uint, hash string) error {
+	if err := tx.Create(&model.PasswordHistory{ASNID: asnID, Hash: hash}).Error; err != nil {
+		return err
+	}
+	// Riwayat lama di luar batas maksimal kebijakan tidak diperlukan lagi
+	var stale []uint
+	err := tx.Model(&model.PasswordHistory{}).Where("asn_id = ?", asnID).
+		Order("id desc").Offset(passwordHistoryKeep).Limit(1000).Pluck("id", &stale).Error
+	if err != nil || len(stale) == 0 {
+		return err
+	}
+	return tx.Delete(&model.PasswordHistory{}, stale).Error
+}
+
+// GetPasswordHistory: Hash dari N password terakhir (terbaru dulu)
+func (r *asnRepository) GetPasswordHistory(asnID uint, limit int) ([]string, error) {
+	var hashes []string
+	err := r.db.Model(&model.PasswordHistory{}).Where("asn_id = ?", asnID).Order("id desc").Limit(limit).Pluck("hash", &hashes).Error
+	return hashes, err
 }
 
-func (r *asnRepository) AddDevice(device *model.Device) error {
-	return r.db.Create(device).Error
+func (r *asnRepository) Delete(id, orgID uint) error {
+	return notFoundIfNoRows(r.db.Scopes(scopeOrg(orgID)).Delete(&model.ASN{}, id))
 }
 
 func (r *asnRepository) GetAll(search string) ([]model.ASN, error) {
@@ -84,12 +171,6 @@ func (r *asnRepository) GetAll(search string) ([]model.ASN, error) {
 	return asns, err
 }
 
-func (r *asnRepository) ResetDevice(asnID uint) error {
-	// Gunakan Unscoped() untuk Hard Delete (Hapus Permanen)
-	// Ini PENTING agar UUID device tersebut benar-benar hilang dan bisa didaftarkan ulang
-	return r.db.Unscoped().Where("asn_id = ?", asnID).Delete(&model.Device{}).Error
-}
-
 func (r *asnRepository) Count() (int64, error) {
 	var count int64
 	err := r.db.Model(&model.ASN{}).Where("is_active = ?", true).Count(&count).Error
@@ -133,6 +214,6 @@ func (r *asnRepository) GetAdminsByOrganisasiID(orgID uint) ([]model.ASN, error)
 	return asns, err
 }
 
-func (r *asnRepository) UpdateStatus(id uint, isActive bool) error {
-	return r.db.Model(&model.ASN{}).Where("id = ?", id).Update("is_active", isActive).Error
+func (r *asnRepository) UpdateStatus(id, orgID uint, isActive bool) error {
+	return r.db.Model(&model.ASN{}).Scopes(scopeOrg(orgID)).Where("id = ?", id).Update("is_active", isActive).Error
 }
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"time"
+
+	"my-flutter-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// AuditLogFilter: Filter untuk pencarian audit log. Field kosong/nol diabaikan.
+type AuditLogFilter struct {
+	OrganisasiID uint
+	ActorNIP     string
+	Action       string
+	EntityType   string
+	EntityID     uint
+	From         *time.Time
+	To           *time.Time
+	Limit        int
+	Offset       int
+}
+
+type AuditLogRepository interface {
+	Create(entry *model.AuditLog) error
+	Find(filter AuditLogFilter) ([]model.AuditLog, int64, error)
+}
+
+type auditLogRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditLogRepository(db *gorm.DB) AuditLogRepository {
+	return &auditLogRepository{db}
+}
+
+func (r *auditLogRepository) Create(entry *model.AuditLog) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *auditLogRepository) Find(filter AuditLogFilter) ([]model.AuditLog, int64, error) {
+	query := r.db.Model(&model.AuditLog{})
+	if filter.OrganisasiID != 0 {
+		query = query.Scopes(scopeOrg(filter.OrganisasiID))
+	}
+	if filter.ActorNIP != "" {
+		query = query.Where("actor_nip = ?", filter.ActorNIP)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != 0 {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var logs []model.AuditLog
+	err := query.Order("id desc").Limit(filter.Limit).Offset(filter.Offset).Find(&logs).Error
+	return logs, total, err
+}
@@ -10,8 +10,9 @@ type BannerRepository interface {
 	GetAllActive(orgID uint) ([]model.Banner, error) // Untuk Mobile
 	GetAll(orgID uint) ([]model.Banner, error)       // Untuk Admin
 	Create(banner *model.Banner) error
-	Delete(id uint) error
-	ToggleStatus(id uint) error
+	GetByIDInOrg(id, orgID uint) (*model.Banner, error)
+	Delete(id, orgID uint) error
+	ToggleStatus(id, orgID uint) error
 }
 
 type bannerRepository struct {
@@ -38,14 +39,24 @@ func (r *bannerRepository) Create(banner *model.Banner) error {
 	return r.db.Create(banner).Error
 }
 
-func (r *bannerRepository) Delete(id uint) error {
+func (r *bannerRepository) GetByIDInOrg(id, orgID uint) (*model.Banner, error) {
+	var banner model.Banner
+	err := r.db.Scopes(scopeOrg(orgID)).First(&banner, id).Error
+	return &banner, err
+}
+
+func (r *bannerRepository) Delete(id, orgID uint) error {
 	// Jangan hapus data (Delete), tapi update is_active jadi false
-	return r.db.Model(&model.Banner{}).Where("id = ?", id).Update("is_active", false).Error
+	var banner model.Banner
+	if err := r.db.Scopes(scopeOrg(orgID)).First(&banner, id).Error; err != nil {
+		return err
+	}
+	return r.db.Model(&banner).Update("is_active", false).Error
 }
 
-func (r *bannerRepository) ToggleStatus(id uint) error {
+func (r *bannerRepository) ToggleStatus(id, orgID uint) error {
 	var banner model.Banner
-	if err := r.db.First(&banner, id).Error; err != nil {
+	if err := r.db.Scopes(scopeOrg(orgID)).First(&banner, id).Error; err != nil {
 		return err
 	}
 	return r.db.Model(&banner).Update("is_active", !banner.IsActive).Error
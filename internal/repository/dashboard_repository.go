@@ -47,7 +47,7 @@ func (r *dashboardRepository) GetDashboardStats(orgID uint, date string, month i
 		Where("asns.organisasi_id = ? AND kehadirans.tanggal = ?", orgID, date).
 		Group("status_masuk").Select("status_masuk, count(*) as count").Scan(&daily)
 
-	dailyMap := map[string]int64{"HADIR": 0, "TERLAMBAT": 0, "IZIN": 0, "CUTI": 0}
+	dailyMap := map[string]int64{"HADIR": 0, "TERLAMBAT": 0, "IZIN": 0, "CUTI": 0, "ALPHA": 0}
 	for _, d := range daily {
 		dailyMap[d.StatusMasuk] = d.Count
 	}
@@ -60,6 +60,14 @@ func (r *dashboardRepository) GetDashboardStats(orgID uint, date string, month i
 		Count(&pcDaily)
 	dailyMap["PULANG_CEPAT"] = pcDaily
 
+	// Tidak Absen Pulang (ditutup auto-checkout) biasanya baru muncul untuk shift kemarin, tetap dihitung per tanggal
+	var tapDaily int64
+	r.db.Table("kehadirans").
+		Joins("JOIN asns ON asns.id = kehadirans.asn_id").
+		Where("asns.organisasi_id = ? AND kehadirans.tanggal = ? AND status_pulang = ?", orgID, date, "TIDAK_ABSEN_PULANG").
+		Count(&tapDaily)
+	dailyMap["TIDAK_ABSEN_PULANG"] = tapDaily
+
 	stats["hari_ini"] = dailyMap
 
 	// 3. Statistik Bulanan (Bulan Ini)
@@ -78,10 +86,14 @@ func (r *dashboardRepository) GetDashboardStats(orgID uint, date string, month i
 		Select("status_masuk, status_pulang, perizinan_kehadiran_id").
 		Scan(&monthlyRecords)
 
-	monthlyMap := map[string]int64{"HADIR": 0, "TERLAMBAT": 0, "TL_CP_DIIZINKAN": 0, "IZIN": 0, "CUTI": 0}
+	monthlyMap := map[string]int64{"HADIR": 0, "TERLAMBAT": 0, "TL_CP_DIIZINKAN": 0, "TIDAK_ABSEN_PULANG": 0, "TAP_DIIZINKAN": 0, "IZIN": 0, "CUTI": 0, "ALPHA": 0}
 
 	for _, m := range monthlyRecords {
-		// 1. Handle Cuti/Izin explicit status
+		// 1. Handle Cuti/Izin/Alpha explicit status
+		if m.StatusMasuk == "ALPHA" {
+			monthlyMap["ALPHA"]++
+			continue
+		}
 		if m.StatusMasuk == "CUTI" {
 			monthlyMap["CUTI"]++
 			continue
@@ -95,6 +107,16 @@ func (r *dashboardRepository) GetDashboardStats(orgID uint, date string, month i
 		// Logic: If Late OR Early -> Problematic. If Permit -> Excused. Else -> Unexcused.
 		hasIssue := m.StatusMasuk == "TERLAMBAT" || m.StatusPulang == "PULANG_CEPAT"
 
+		// Lupa check-out (ditutup auto-checkout) dihitung terpisah dari TL/CP
+		if m.StatusPulang == "TIDAK_ABSEN_PULANG" {
+			if m.PerizinanKehadiranID != nil {
+				monthlyMap["TAP_DIIZINKAN"]++
+			} else {
+				monthlyMap["TIDAK_ABSEN_PULANG"]++
+			}
+			continue
+		}
+
 		if m.PerizinanKehadiranID != nil {
 			if hasIssue {
 				monthlyMap["TL_CP_DIIZINKAN"]++
@@ -128,11 +150,13 @@ func (r *dashboardRepository) GetDashboardStats(orgID uint, date string, month i
 		"hadir_tepat_waktu": monthlyMap["HADIR"],
 		"tl_cp":             monthlyMap["TERLAMBAT"], // Tanpa Izin
 		"tl_cp_diizinkan":   monthlyMap["TL_CP_DIIZINKAN"],
+		"tap":               monthlyMap["TIDAK_ABSEN_PULANG"], // Tanpa Izin
+		"tap_diizinkan":     monthlyMap["TAP_DIIZINKAN"],
 		"izin":              monthlyMap["IZIN"],
 		"cuti":              monthlyMap["CUTI"],
 		"total_jadwal":      totalJadwal,
-		"alfa":              totalJadwal - (monthlyMap["HADIR"] + monthlyMap["TERLAMBAT"] + monthlyMap["TL_CP_DIIZINKAN"] + monthlyMap["IZIN"] + monthlyMap["CUTI"]), // Basic calc
-		"belum_absen":       0,                                                                                                                                       // Placeholder needs proper logic if needed
+		"alfa":              monthlyMap["ALPHA"], // Dicatat sweep ALPHA setelah shift selesai
+		"belum_absen":       totalJadwal - (monthlyMap["HADIR"] + monthlyMap["TERLAMBAT"] + monthlyMap["TL_CP_DIIZINKAN"] + monthlyMap["TIDAK_ABSEN_PULANG"] + monthlyMap["TAP_DIIZINKAN"] + monthlyMap["IZIN"] + monthlyMap["CUTI"] + monthlyMap["ALPHA"]),
 	}
 
 	// 4. Detail Harian Untuk Grafik
@@ -147,7 +171,14 @@ func (r *dashboardRepository) GetDashboardStats(orgID uint, date string, month i
 	// Post-processing untuk menyesuaikan logic warna chart (Orange vs Kuning vs Hijau)
 	for i := range details {
 		// Logika: Jika Status Masuk terlambat ATAU Status Pulang Pulang Cepat
-		if details[i].StatusMasuk == "TERLAMBAT" || details[i].StatusPulang == "PULANG_CEPAT" {
+		if details[i].StatusPulang == "TIDAK_ABSEN_PULANG" {
+			// Lupa check-out: kategori sendiri, tidak digabung ke TL/CP
+			if details[i].PerizinanKehadiranID != nil {
+				details[i].StatusMasuk = "TAP_DIIZINKAN"
+			} else {
+				details[i].StatusMasuk = "TIDAK_ABSEN_PULANG"
+			}
+		} else if details[i].StatusMasuk == "TERLAMBAT" || details[i].StatusPulang == "PULANG_CEPAT" {
 			if details[i].PerizinanKehadiranID != nil {
 				// Jika ada izin -> Orange (TL_CP_DIIZINKAN)
 				details[i].StatusMasuk = "TL_CP_DIIZINKAN"
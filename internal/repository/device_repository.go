@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"errors"
+	"my-flutter-backend/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrDeviceRequestProcessed: Permintaan ganti device sudah disetujui/ditolak sebelumnya
+	ErrDeviceRequestProcessed = errors.New("permintaan sudah diproses")
+	// ErrDeviceInUse: UUID device sudah terikat ke akun lain
+	ErrDeviceInUse = errors.New("device sudah dipakai akun lain")
+)
+
+type DeviceRepository interface {
+	// Bind: Daftarkan device ke ASN sekaligus catat riwayatnya
+	Bind(device *model.Device, via string, requestID *uint) error
+	// UnbindAll: Lepas semua device ASN (reset oleh admin). Riwayat tetap disimpan.
+	UnbindAll(asnID uint, reason string, actorID *uint) error
+	GetHistory(asnID uint) ([]model.DeviceHistory, error)
+
+	CreateRequest(req *model.DeviceChangeRequest) error
+	GetPendingRequest(asnID uint) (*model.DeviceChangeRequest, error)
+	GetRequestsByASN(asnID uint) ([]model.DeviceChangeRequest, error)
+	GetRequestByIDInOrg(id, orgID uint) (*model.DeviceChangeRequest, error)
+	// GetPendingRequests: Permintaan MENUNGGU di organisasi; nipAtasan kosong = semua (untuk admin)
+	GetPendingRequests(orgID uint, nipAtasan string) ([]model.DeviceChangeRequest, error)
+	// Approve: Setujui permintaan, lepas device lama jika batas penuh, lalu daftarkan device baru.
+	// Mengembalikan device baru dan ID device yang dilepas.
+	Approve(req *model.DeviceChangeRequest, maxDevices int, approverID uint, catatan string) (*model.Device, []uint, error)
+	Reject(req *model.DeviceChangeRequest, approverID uint, catatan string) error
+}
+
+type deviceRepository struct {
+	db *gorm.DB
+}
+
+func NewDeviceRepository(db *gorm.DB) DeviceRepository {
+	return &deviceRepository{db}
+}
+
+func (r *deviceRepository) Bind(device *model.Device, via string, requestID *uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return bindDevice(tx, device, via, requestID)
+	})
+}
+
+func bindDevice(tx *gorm.DB, device *model.Device, via string, requestID *uint) error {
+	if err := tx.Create(device).Error; err != nil {
+		return err
+	}
+	return tx.Create(&model.DeviceHistory{
+		ASNID:     device.ASNID,
+		DeviceID:  device.ID,
+		UUID:      device.UUID,
+		Brand:     device.Brand,
+		Series:    device.Series,
+		BoundAt:   time.Now(),
+		BoundVia:  via,
+		RequestID: requestID,
+	}).Error
+}
+
+// unbindDevice: Hard delete device (agar UUID bisa didaftarkan ulang) dan tutup riwayatnya
+func unbindDevice(tx *gorm.DB, device *model.Device, reason string, actorID *uint) error {
+	if err := tx.Unscoped().Delete(&model.Device{}, device.ID).Error; err != nil {
+		return err
+	}
+	return tx.Model(&model.DeviceHistory{}).
+		Where("device_id = ? AND unbound_at IS NULL", device.ID).
+		Updates(map[string]interface{}{"unbound_at": time.Now(), "unbound_reason": reason, "unbound_by_id": actorID}).Error
+}
+
+func (r *deviceRepository) UnbindAll(asnID uint, reason string, actorID *uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var devices []model.Device
+		if err := tx.Where("asn_id = ?", asnID).Find(&devices).Error; err != nil {
+			return err
+		}
+		for i := range devices {
+			if err := unbindDevice(tx, &devices[i], reason, actorID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *deviceRepository) GetHistory(asnID uint) ([]model.DeviceHistory, error) {
+	var history []model.DeviceHistory
+	err := r.db.Where("asn_id = ?", asnID).Order("bound_at desc").Find(&history).Error
+	return history, err
+}
+
+func (r *deviceRepository) CreateRequest(req *model.DeviceChangeRequest) error {
+	return r.db.Create(req).Error
+}
+
+func (r *deviceRepository) GetPendingRequest(asnID uint) (*model.DeviceChangeRequest, error) {
+	var req model.DeviceChangeRequest
+	err := r.db.Where("asn_id = ? AND status = ?", asnID, "MENUNGGU").Order("created_at desc").First(&req).Error
+	return &req, err
+}
+
+func (r *deviceRepository) GetRequestsByASN(asnID uint) ([]model.DeviceChangeRequest, error) {
+	var list []model.DeviceChangeRequest
+	err := r.db.Where("asn_id = ?", asnID).Order("created_at desc").Find(&list).Error
+	return list, err
+}
+
+func (r *deviceRepository) GetRequestByIDInOrg(id, orgID uint) (*model.DeviceChangeRequest, error) {
+	var req model.DeviceChangeRequest
+	err := r.db.Scopes(scopeASNOrg(orgID)).Preload("ASN").First(&req, id).Error
+	return &req, err
+}
+
+func (r *deviceRepository) GetPendingRequests(orgID uint, nipAtasan string) ([]model.DeviceChangeRequest, error) {
+	var list []model.DeviceChangeRequest
+	query := r.db.Scopes(scopeASNOrg(orgID)).Preload("ASN").Where("status = ?", "MENUNGGU")
+	if nipAtasan != "" {
+		query = query.Where("nip_atasan = ?", nipAtasan)
+	}
+	err := query.Order("created_at asc").Find(&list).Error
+	return list, err
+}
+
+// processRequest: Ubah status MENUNGGU -> status baru. Gagal jika sudah diproses (approval ganda/bersamaan).
+func processRequest(tx *gorm.DB, req *model.DeviceChangeRequest, status string, approverID uint, catatan string) error {
+	now := time.Now()
+	result := tx.Model(&model.DeviceChangeRequest{}).
+		Where("id = ? AND status = ?", req.ID, "MENUNGGU").
+		Updates(map[string]interface{}{"status": status, "processed_by_id": approverID, "processed_at": now, "catatan": catatan})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDeviceRequestProcessed
+	}
+	req.Status = status
+	req.ProcessedByID = &approverID
+	req.ProcessedAt = &now
+	req.Catatan = catatan
+	return nil
+}
+
+func (r *deviceRepository) Approve(req *model.DeviceChangeRequest, maxDevices int, approverID uint, catatan string) (*model.Device, []uint, error) {
+	device := model.Device{
+		ASNID:         req.ASNID,
+		UUID:          req.UUID,
+		Brand:         req.Brand,
+		Series:        req.Series,
+		FirebaseToken: req.FirebaseToken,
+	}
+	var released []uint
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := processRequest(tx, req, "DISETUJUI", approverID, catatan); err != nil {
+			return err
+		}
+
+		var existing model.Device
+		if err := tx.Where("uuid = ?", req.UUID).Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+		if existing.ID != 0 {
+			if existing.ASNID != req.ASNID {
+				return ErrDeviceInUse
+			}
+			device = existing // Sudah terikat (misal didaftarkan admin lebih dulu)
+			return nil
+		}
+
+		var devices []model.Device
+		if err := tx.Where("asn_id = ?", req.ASNID).Order("created_at asc").Find(&devices).Error; err != nil {
+			return err
+		}
+
+		// Device pilihan pegawai dilepas lebih dulu, sisanya device terlama
+		if req.ReplaceDeviceID != nil {
+			for i := range devices {
+				if devices[i].ID == *req.ReplaceDeviceID && len(devices) >= maxDevices {
+					if err := unbindDevice(tx, &devices[i], "DIGANTI", &approverID); err != nil {
+						return err
+					}
+					released = append(released, devices[i].ID)
+					devices = append(devices[:i], devices[i+1:]...)
+					break
+				}
+			}
+		}
+		for len(devices) > 0 && len(devices) >= maxDevices {
+			if err := unbindDevice(tx, &devices[0], "DIGANTI", &approverID); err != nil {
+				return err
+			}
+			released = append(released, devices[0].ID)
+			devices = devices[1:]
+		}
+
+		return bindDevice(tx, &device, "PERMINTAAN", &req.ID)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &device, released, nil
+}
+
+func (r *deviceRepository) Reject(req *model.DeviceChangeRequest, approverID uint, catatan string) error {
+	return processRequest(r.db, req, "DITOLAK", approverID, catatan)
+}
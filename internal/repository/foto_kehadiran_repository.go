@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"my-flutter-backend/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type FotoKehadiranRepository interface {
+	Create(foto *model.FotoKehadiran) error
+	GetByKehadiran(kehadiranID uint, jenis string) (*model.FotoKehadiran, error)
+	GetByKehadiranIDs(kehadiranIDs []uint) ([]model.FotoKehadiran, error)
+	// GetExpired: Foto yang filenya masih ada tapi sudah melewati retensi organisasi pegawai (maks limit baris)
+	GetExpired(now time.Time, limit int) ([]model.FotoKehadiran, error)
+	MarkPurged(id uint, at time.Time) error
+}
+
+type fotoKehadiranRepository struct {
+	db *gorm.DB
+}
+
+func NewFotoKehadiranRepository(db *gorm.DB) FotoKehadiranRepository {
+	return &fotoKehadiranRepository{db}
+}
+
+func (r *fotoKehadiranRepository) Create(foto *model.FotoKehadiran) error {
+	return r.db.Create(foto).Error
+}
+
+func (r *fotoKehadiranRepository) GetByKehadiran(kehadiranID uint, jenis string) (*model.FotoKehadiran, error) {
+	var foto model.FotoKehadiran
+	err := r.db.Where("kehadiran_id = ? AND jenis = ?", kehadiranID, jenis).Order("id desc").First(&foto).Error
+	if err != nil {
+		return nil, err
+	}
+	return &foto, nil
+}
+
+func (r *fotoKehadiranRepository) GetByKehadiranIDs(kehadiranIDs []uint) ([]model.FotoKehadiran, error) {
+	var list []model.FotoKehadiran
+	if len(kehadiranIDs) == 0 {
+		return list, nil
+	}
+	err := r.db.Where("kehadiran_id IN ?", kehadiranIDs).Order("id asc").Find(&list).Error
+	return list, err
+}
+
+func (r *fotoKehadiranRepository) GetExpired(now time.Time, limit int) ([]model.FotoKehadiran, error) {
+	var list []model.FotoKehadiran
+	err := r.db.
+		Joins("JOIN asns ON asns.id = foto_kehadirans.asn_id").
+		Joins("JOIN organisasis ON organisasis.id = asns.organisasi_id").
+		Where("foto_kehadirans.dihapus_at IS NULL AND organisasis.selfie_retensi_hari > 0").
+		Where("foto_kehadirans.diambil_at < DATE_SUB(?, INTERVAL organisasis.selfie_retensi_hari DAY)", now).
+		Order("foto_kehadirans.id asc").
+		Limit(limit).
+		Find(&list).Error
+	return list, err
+}
+
+func (r *fotoKehadiranRepository) MarkPurged(id uint, at time.Time) error {
+	return r.db.Model(&model.FotoKehadiran{}).Where("id = ?", id).Updates(map[string]interface{}{"dihapus_at": at, "path": ""}).Error
+}
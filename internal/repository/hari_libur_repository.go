@@ -9,9 +9,9 @@ import (
 type HariLiburRepository interface {
 	GetAll(orgID uint) ([]model.HariLibur, error)
 	Create(libur *model.HariLibur) error
-	Delete(id uint) error
+	Delete(id, orgID uint) error
 	IsHoliday(date string, orgID uint) (bool, error)
-	GetByID(id uint) (*model.HariLibur, error)
+	GetByIDInOrg(id, orgID uint) (*model.HariLibur, error)
 	Update(libur *model.HariLibur) error
 }
 
@@ -33,8 +33,8 @@ func (r *hariLiburRepository) Create(libur *model.HariLibur) error {
 	return r.db.Create(libur).Error
 }
 
-func (r *hariLiburRepository) Delete(id uint) error {
-	return r.db.Delete(&model.HariLibur{}, id).Error
+func (r *hariLiburRepository) Delete(id, orgID uint) error {
+	return notFoundIfNoRows(r.db.Scopes(scopeOrg(orgID)).Delete(&model.HariLibur{}, id))
 }
 
 func (r *hariLiburRepository) IsHoliday(date string, orgID uint) (bool, error) {
@@ -46,9 +46,9 @@ func (r *hariLiburRepository) IsHoliday(date string, orgID uint) (bool, error) {
 	return count > 0, nil
 }
 
-func (r *hariLiburRepository) GetByID(id uint) (*model.HariLibur, error) {
+func (r *hariLiburRepository) GetByIDInOrg(id, orgID uint) (*model.HariLibur, error) {
 	var libur model.HariLibur
-	err := r.db.First(&libur, id).Error
+	err := r.db.Scopes(scopeOrg(orgID)).First(&libur, id).Error
 	return &libur, err
 }
 
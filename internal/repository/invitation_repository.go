@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"errors"
+	"my-flutter-backend/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvitationUsed: Undangan sudah dipakai / dibatalkan (aktivasi ganda atau bersamaan)
+var ErrInvitationUsed = errors.New("undangan sudah tidak berlaku")
+
+// PendingActivation: Baris laporan pegawai yang belum aktivasi akun
+type PendingActivation struct {
+	ASN             model.ASN            `json:"asn"`
+	LastInvitation  *model.ASNInvitation `json:"last_invitation"`
+	InvitationCount int                  `json:"invitation_count"`
+}
+
+type InvitationRepository interface {
+	// Create: Simpan undangan baru, undangan lama yang masih aktif otomatis dibatalkan
+	Create(inv *model.ASNInvitation) error
+	Update(inv *model.ASNInvitation) error
+	GetByTokenHash(hash string) (*model.ASNInvitation, error)
+	GetLatestByASN(asnID uint) (*model.ASNInvitation, error)
+	RevokeActive(asnID uint) (int64, error)
+	// Accept: Pakai undangan, simpan password pertama pegawai, dan tandai akun sudah aktivasi
+	Accept(inv *model.ASNInvitation, asn *model.ASN, hash string) error
+	// GetPendingActivations: Pegawai organisasi yang belum aktivasi beserta undangan terakhirnya
+	GetPendingActivations(orgID uint) ([]PendingActivation, error)
+}
+
+type invitationRepository struct {
+	db *gorm.DB
+}
+
+func NewInvitationRepository(db *gorm.DB) InvitationRepository {
+	return &invitationRepository{db}
+}
+
+func (r *invitationRepository) Create(inv *model.ASNInvitation) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := revokeActiveInvitations(tx, inv.ASNID).Error; err != nil {
+			return err
+		}
+		return tx.Create(inv).Error
+	})
+}
+
+func (r *invitationRepository) Update(inv *model.ASNInvitation) error {
+	return r.db.Save(inv).Error
+}
+
+func (r *invitationRepository) GetByTokenHash(hash string) (*model.ASNInvitation, error) {
+	var inv model.ASNInvitation
+	err := r.db.Where("token_hash = ?", hash).First(&inv).Error
+	return &inv, err
+}
+
+func (r *invitationRepository) GetLatestByASN(asnID uint) (*model.ASNInvitation, error) {
+	var inv model.ASNInvitation
+	err := r.db.Where("asn_id = ?", asnID).Order("created_at desc").First(&inv).Error
+	return &inv, err
+}
+
+func (r *invitationRepository) RevokeActive(asnID uint) (int64, error) {
+	result := revokeActiveInvitations(r.db, asnID)
+	return result.RowsAffected, result.Error
+}
+
+func revokeActiveInvitations(tx *gorm.DB, asnID uint) *gorm.DB {
+	return tx.Model(&model.ASNInvitation{}).
+		Where("asn_id = ? AND accepted_at IS NULL AND revoked_at IS NULL", asnID).
+		Update("revoked_at", time.Now())
+}
+
+func (r *invitationRepository) Accept(inv *model.ASNInvitation, asn *model.ASN, hash string) error {
+	now := time.Now()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.ASNInvitation{}).
+			Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", inv.ID).
+			Update("accepted_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvitationUsed
+		}
+
+		err := tx.Model(&model.ASN{}).Where("id = ?", asn.ID).Updates(map[string]interface{}{
+			"password":             hash,
+			"password_changed_at":  now,
+			"must_change_password": false,
+			"activation_pending":   false,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := addPasswordHistory(tx, asn.ID, hash); err != nil {
+			return err
+		}
+
+		inv.AcceptedAt = &now
+		asn.Password = hash
+		asn.PasswordChangedAt = &now
+		asn.MustChangePassword = false
+		asn.ActivationPending = false
+		return nil
+	})
+}
+
+func (r *invitationRepository) GetPendingActivations(orgID uint) ([]PendingActivation, error) {
+	var asns []model.ASN
+	if err := r.db.Scopes(scopeOrg(orgID)).Where("activation_pending = ?", true).Order("created_at asc").Find(&asns).Error; err != nil {
+		return nil, err
+	}
+	if len(asns) == 0 {
+		return []PendingActivation{}, nil
+	}
+
+	ids := make([]uint, len(asns))
+	for i, a := range asns {
+		ids[i] = a.ID
+	}
+	var invitations []model.ASNInvitation
+	if err := r.db.Where("asn_id IN ?", ids).Order("created_at asc").Find(&invitations).Error; err != nil {
+		return nil, err
+	}
+
+	latest := make(map[uint]*model.ASNInvitation)
+	count := make(map[uint]int)
+	for i := range invitations {
+		latest[invitations[i].ASNID] = &invitations[i]
+		count[invitations[i].ASNID]++
+	}
+
+	result := make([]PendingActivation, len(asns))
+	for i, a := range asns {
+		result[i] = PendingActivation{ASN: a, LastInvitation: latest[a.ID], InvitationCount: count[a.ID]}
+	}
+	return result, nil
+}
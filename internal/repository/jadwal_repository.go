@@ -11,9 +11,9 @@ type JadwalRepository interface {
 	Create(jadwal *model.Jadwal) error
 	GetByASNAndDate(asnID uint, date string) (*model.Jadwal, error)
 	GetByDate(date string, orgID uint, search string) ([]model.Jadwal, error)
-	GetByID(id uint) (*model.Jadwal, error)
+	GetByIDInOrg(id, orgID uint) (*model.Jadwal, error)
 	Update(jadwal *model.Jadwal) error
-	Delete(id uint) error
+	Delete(id, orgID uint) error
 	CreateMany(jadwal []model.Jadwal) error
 	CountByShiftID(shiftID uint) (int64, error)
 	DeleteByDate(date string, orgID uint) error
@@ -65,9 +65,9 @@ func (r *jadwalRepository) GetByDate(date string, orgID uint, search string) ([]
 	return jadwals, err
 }
 
-func (r *jadwalRepository) GetByID(id uint) (*model.Jadwal, error) {
+func (r *jadwalRepository) GetByIDInOrg(id, orgID uint) (*model.Jadwal, error) {
 	var jadwal model.Jadwal
-	err := r.db.Preload("ASN").Preload("Shift").First(&jadwal, id).Error
+	err := r.db.Scopes(scopeASNOrg(orgID)).Preload("ASN").Preload("Shift").First(&jadwal, id).Error
 	return &jadwal, err
 }
 
@@ -75,8 +75,8 @@ func (r *jadwalRepository) Update(jadwal *model.Jadwal) error {
 	return r.db.Save(jadwal).Error
 }
 
-func (r *jadwalRepository) Delete(id uint) error {
-	return r.db.Delete(&model.Jadwal{}, id).Error
+func (r *jadwalRepository) Delete(id, orgID uint) error {
+	return notFoundIfNoRows(r.db.Scopes(scopeASNOrg(orgID)).Delete(&model.Jadwal{}, id))
 }
 
 func (r *jadwalRepository) CreateMany(jadwal []model.Jadwal) error {
@@ -11,6 +11,13 @@ type KehadiranRepository interface {
 	Create(kehadiran model.Kehadiran) error
 	GetTodayAttendance(asnID uint) (*model.Kehadiran, error)
 	Update(kehadiran *model.Kehadiran) error
+	// GetFaceReviewQueue: Kehadiran organisasi dengan status review wajah tertentu (MENUNGGU/DITERIMA/DITOLAK)
+	GetFaceReviewQueue(orgID uint, reviewStatus string) ([]FaceReviewItem, error)
+	// ReviewFace: Simpan keputusan review wajah. Hanya untuk kehadiran organisasi ini yang masih MENUNGGU.
+	ReviewFace(id, orgID uint, decision, note string, reviewerID uint) error
+	// SaveWithFoto: Create/update kehadiran beserta selfie-nya dalam satu transaksi (foto boleh nil)
+	SaveWithFoto(kehadiran *model.Kehadiran, foto *model.FotoKehadiran) error
+	GetByID(id uint) (*model.Kehadiran, error)
 	GetHistory(asnID uint) ([]model.Kehadiran, error)
 	CreateMany(kehadiran []model.Kehadiran) error
 	GetByDate(asnID uint, date string) (*model.Kehadiran, error)
@@ -19,6 +26,28 @@ type KehadiranRepository interface {
 	GetByDateAndOrg(date string, orgID uint) ([]model.Kehadiran, error)
 	GetByMonthAndOrg(month string, year string, orgID uint) ([]model.Kehadiran, error)
 	DeleteByPerizinanID(perizinanID uint) error
+	// GetAlphaCandidates: Jadwal aktif pada rentang tanggal tanpa data kehadiran apapun dan bukan hari libur
+	// organisasi pegawai (orgID 0 = semua organisasi). Dipakai sweep ALPHA.
+	GetAlphaCandidates(from, to string, orgID uint) ([]model.Jadwal, error)
+	// GetOpenCheckouts: Kehadiran HADIR/TERLAMBAT pada rentang tanggal yang belum check-out, beserta shift jadwalnya
+	GetOpenCheckouts(from, to string, orgID uint) ([]OpenCheckout, error)
+	// CloseOpenCheckout: Set status pulang jika kehadiran masih belum check-out. false = sudah check-out duluan.
+	CloseOpenCheckout(id uint, statusPulang string) (bool, error)
+}
+
+// FaceReviewItem: Kehadiran + identitas pegawai untuk antrean review verifikasi wajah
+type FaceReviewItem struct {
+	model.Kehadiran
+	NIP           string `json:"nip"`
+	Nama          string `json:"nama"`
+	Foto          string `json:"foto"`           // Foto profil (diatur pegawai)
+	FotoReferensi string `json:"foto_referensi"` // Foto pembanding verifikasi wajah (diatur admin)
+}
+
+// OpenCheckout: Kehadiran yang belum check-out + shift jadwalnya (untuk auto-checkout)
+type OpenCheckout struct {
+	Kehadiran model.Kehadiran
+	Shift     model.Shift
 }
 
 type kehadiranRepository struct {
@@ -48,6 +77,27 @@ func (r *kehadiranRepository) Update(kehadiran *model.Kehadiran) error {
 	return r.db.Save(kehadiran).Error
 }
 
+func (r *kehadiranRepository) SaveWithFoto(kehadiran *model.Kehadiran, foto *model.FotoKehadiran) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(kehadiran).Error; err != nil {
+			return err
+		}
+		if foto == nil {
+			return nil
+		}
+		foto.KehadiranID = kehadiran.ID
+		return tx.Create(foto).Error
+	})
+}
+
+func (r *kehadiranRepository) GetByID(id uint) (*model.Kehadiran, error) {
+	var kehadiran model.Kehadiran
+	if err := r.db.First(&kehadiran, id).Error; err != nil {
+		return nil, err
+	}
+	return &kehadiran, nil
+}
+
 func (r *kehadiranRepository) GetHistory(asnID uint) ([]model.Kehadiran, error) {
 	var history []model.Kehadiran
 	err := r.db.Where("asn_id = ?", asnID).Order("created_at desc").Find(&history).Error
@@ -105,3 +155,87 @@ func (r *kehadiranRepository) DeleteByPerizinanID(perizinanID uint) error {
 	// Menghapus semua record kehadiran yang terkait dengan ID perizinan cuti tertentu
 	return r.db.Where("perizinan_cuti_id = ?", perizinanID).Delete(&model.Kehadiran{}).Error
 }
+
+func (r *kehadiranRepository) GetAlphaCandidates(from, to string, orgID uint) ([]model.Jadwal, error) {
+	var jadwals []model.Jadwal
+	query := r.db.Preload("Shift").
+		Joins("JOIN asns ON asns.id = jadwals.asn_id AND asns.deleted_at IS NULL").
+		Where("jadwals.is_active = ? AND asns.is_active = ? AND jadwals.tanggal BETWEEN ? AND ?", true, true, from, to).
+		Where("NOT EXISTS (SELECT 1 FROM kehadirans k WHERE k.deleted_at IS NULL AND (k.jadwal_id = jadwals.id OR (k.asn_id = jadwals.asn_id AND k.tanggal = jadwals.tanggal)))").
+		Where("NOT EXISTS (SELECT 1 FROM hari_liburs l WHERE l.deleted_at IS NULL AND l.organisasi_id = asns.organisasi_id AND l.tanggal = jadwals.tanggal)")
+	if orgID != 0 {
+		query = query.Where("asns.organisasi_id = ?", orgID)
+	}
+	err := query.Find(&jadwals).Error
+	return jadwals, err
+}
+
+func (r *kehadiranRepository) GetOpenCheckouts(from, to string, orgID uint) ([]OpenCheckout, error) {
+	var rows []model.Kehadiran
+	query := r.db.Model(&model.Kehadiran{}).
+		Joins("JOIN asns ON asns.id = kehadirans.asn_id").
+		Where("kehadirans.tanggal BETWEEN ? AND ?", from, to).
+		Where("kehadirans.status_masuk IN ?", []string{"HADIR", "TERLAMBAT"}).
+		Where("(kehadirans.jam_pulang_real = '' OR kehadirans.jam_pulang_real IS NULL)").
+		Where("(kehadirans.status_pulang = '' OR kehadirans.status_pulang IS NULL)")
+	if orgID != 0 {
+		query = query.Where("asns.organisasi_id = ?", orgID)
+	}
+	if err := query.Find(&rows).Error; err != nil || len(rows) == 0 {
+		return nil, err
+	}
+
+	jadwalIDs := make([]uint, 0, len(rows))
+	for _, k := range rows {
+		jadwalIDs = append(jadwalIDs, k.JadwalID)
+	}
+	var jadwals []model.Jadwal
+	if err := r.db.Preload("Shift").Where("id IN ?", jadwalIDs).Find(&jadwals).Error; err != nil {
+		return nil, err
+	}
+	shiftByJadwal := make(map[uint]model.Shift, len(jadwals))
+	for _, j := range jadwals {
+		shiftByJadwal[j.ID] = j.Shift
+	}
+
+	result := make([]OpenCheckout, 0, len(rows))
+	for _, k := range rows {
+		shift, ok := shiftByJadwal[k.JadwalID]
+		if !ok {
+			continue // Jadwal sudah dihapus, tidak bisa menentukan jam pulang
+		}
+		result = append(result, OpenCheckout{Kehadiran: k, Shift: shift})
+	}
+	return result, nil
+}
+
+func (r *kehadiranRepository) CloseOpenCheckout(id uint, statusPulang string) (bool, error) {
+	res := r.db.Model(&model.Kehadiran{}).
+		Where("id = ? AND (jam_pulang_real = '' OR jam_pulang_real IS NULL)", id).
+		Update("status_pulang", statusPulang)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *kehadiranRepository) GetFaceReviewQueue(orgID uint, reviewStatus string) ([]FaceReviewItem, error) {
+	var items []FaceReviewItem
+	err := r.db.Model(&model.Kehadiran{}).
+		Select("kehadirans.*, asns.nip, asns.nama, asns.foto, asns.foto_referensi").
+		Joins("JOIN asns ON asns.id = kehadirans.asn_id").
+		Where("asns.organisasi_id = ? AND kehadirans.face_review_status = ?", orgID, reviewStatus).
+		Order("kehadirans.tanggal desc, kehadirans.id desc").
+		Limit(500).
+		Scan(&items).Error
+	return items, err
+}
+
+func (r *kehadiranRepository) ReviewFace(id, orgID uint, decision, note string, reviewerID uint) error {
+	return notFoundIfNoRows(r.db.Model(&model.Kehadiran{}).
+		Scopes(scopeASNOrg(orgID)).
+		Where("id = ? AND face_review_status = ?", id, "MENUNGGU").
+		Updates(map[string]interface{}{
+			"face_review_status": decision,
+			"face_review_note":   note,
+			"face_review_by_id":  reviewerID,
+			"face_review_at":     time.Now(),
+		}))
+}
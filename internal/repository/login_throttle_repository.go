@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"my-flutter-backend/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type LoginThrottleRepository interface {
+	Get(scope, key string) (*model.LoginThrottle, error)
+	RegisterFailure(scope, key string, now, windowStart, levelResetBefore time.Time) (*model.LoginThrottle, error)
+	Lock(throttle *model.LoginThrottle, maxFailed, level int, until time.Time) (bool, error)
+	Reset(scope, key string) error
+	CreateEvent(event *model.LoginLockoutEvent) error
+	GetEvents(scope, key string, limit int) ([]model.LoginLockoutEvent, error)
+}
+
+type loginThrottleRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginThrottleRepository(db *gorm.DB) LoginThrottleRepository {
+	return &loginThrottleRepository{db}
+}
+
+func (r *loginThrottleRepository) Get(scope, key string) (*model.LoginThrottle, error) {
+	var throttle model.LoginThrottle
+	err := r.db.Where("scope = ? AND `key` = ?", scope, key).First(&throttle).Error
+	return &throttle, err
+}
+
+// RegisterFailure: Tambah penghitung gagal secara atomik (aman untuk request paralel) lalu baca ulang hasilnya.
+// Penghitung mulai dari 1 lagi jika kegagalan terakhir sebelum windowStart, level kunci kembali 0 jika sebelum levelResetBefore.
+func (r *loginThrottleRepository) RegisterFailure(scope, key string, now, windowStart, levelResetBefore time.Time) (*model.LoginThrottle, error) {
+	var throttle model.LoginThrottle
+	if err := r.db.Where(model.LoginThrottle{Scope: scope, Key: key}).FirstOrCreate(&throttle).Error; err != nil {
+		// Request paralel lain bisa saja lebih dulu membuat baris yang sama (unique index)
+		if err := r.db.Where("scope = ? AND `key` = ?", scope, key).First(&throttle).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	// Level kunci kembali ke awal jika sudah lama tidak ada percobaan gagal
+	err := r.db.Model(&model.LoginThrottle{}).
+		Where("id = ? AND last_failed_at < ?", throttle.ID, levelResetBefore).
+		Update("lock_level", 0).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// GORM mengurutkan kolom map, jadi failed_count dihitung sebelum last_failed_at diganti (MySQL memakai nilai baru untuk SET berikutnya)
+	err = r.db.Model(&model.LoginThrottle{}).Where("id = ?", throttle.ID).Updates(map[string]interface{}{
+		"failed_count":   gorm.Expr("CASE WHEN last_failed_at IS NULL OR last_failed_at < ? THEN 1 ELSE failed_count + 1 END", windowStart),
+		"last_failed_at": now,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.First(&throttle, throttle.ID).Error
+	return &throttle, err
+}
+
+// Lock: Kunci baris ke level berikutnya. Hanya satu request yang berhasil (false jika sudah dikunci request lain).
+func (r *loginThrottleRepository) Lock(throttle *model.LoginThrottle, maxFailed, level int, until time.Time) (bool, error) {
+	res := r.db.Model(&model.LoginThrottle{}).
+		Where("id = ? AND failed_count >= ? AND lock_level = ?", throttle.ID, maxFailed, level-1).
+		Updates(map[string]interface{}{"failed_count": 0, "lock_level": level, "locked_until": until})
+	return res.RowsAffected == 1, res.Error
+}
+
+// Reset: Hapus penghitung gagal & kunci (login berhasil / dibuka admin)
+func (r *loginThrottleRepository) Reset(scope, key string) error {
+	return r.db.Model(&model.LoginThrottle{}).
+		Where("scope = ? AND `key` = ?", scope, key).
+		Updates(map[string]interface{}{"failed_count": 0, "locked_until": nil, "lock_level": 0}).Error
+}
+
+func (r *loginThrottleRepository) CreateEvent(event *model.LoginLockoutEvent) error {
+	return r.db.Create(event).Error
+}
+
+func (r *loginThrottleRepository) GetEvents(scope, key string, limit int) ([]model.LoginLockoutEvent, error) {
+	var events []model.LoginLockoutEvent
+	err := r.db.Where("scope = ? AND `key` = ?", scope, key).Order("created_at desc").Limit(limit).Find(&events).Error
+	return events, err
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"my-flutter-backend/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type NotifikasiRepository interface {
+	Create(notif *model.Notifikasi) error
+	GetByASNID(asnID uint, limit int) ([]model.Notifikasi, error)
+	CountUnread(asnID uint) (int64, error)
+	MarkRead(id, asnID uint) error
+	MarkAllRead(asnID uint) error
+}
+
+type notifikasiRepository struct {
+	db *gorm.DB
+}
+
+func NewNotifikasiRepository(db *gorm.DB) NotifikasiRepository {
+	return &notifikasiRepository{db}
+}
+
+func (r *notifikasiRepository) Create(notif *model.Notifikasi) error {
+	return r.db.Create(notif).Error
+}
+
+func (r *notifikasiRepository) GetByASNID(asnID uint, limit int) ([]model.Notifikasi, error) {
+	var list []model.Notifikasi
+	err := r.db.Where("asn_id = ?", asnID).Order("created_at desc").Limit(limit).Find(&list).Error
+	return list, err
+}
+
+func (r *notifikasiRepository) CountUnread(asnID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Notifikasi{}).Where("asn_id = ? AND dibaca_at IS NULL", asnID).Count(&count).Error
+	return count, err
+}
+
+// MarkRead: Tandai satu notifikasi milik pegawai sebagai dibaca
+func (r *notifikasiRepository) MarkRead(id, asnID uint) error {
+	var notif model.Notifikasi
+	if err := r.db.Where("id = ? AND asn_id = ?", id, asnID).First(&notif).Error; err != nil {
+		return err
+	}
+	if notif.DibacaAt != nil {
+		return nil
+	}
+	return r.db.Model(&notif).Update("dibaca_at", time.Now()).Error
+}
+
+func (r *notifikasiRepository) MarkAllRead(asnID uint) error {
+	return r.db.Model(&model.Notifikasi{}).Where("asn_id = ? AND dibaca_at IS NULL", asnID).Update("dibaca_at", time.Now()).Error
+}
@@ -10,10 +10,11 @@ type OrganisasiRepository interface {
 	GetFirst() (*model.Organisasi, error)
 	GetByID(id uint) (*model.Organisasi, error)
 	Update(org *model.Organisasi) error
-	GetLokasiByID(id uint) (*model.Lokasi, error)
+	GetLokasiByIDInOrg(id, orgID uint) (*model.Lokasi, error)
 	UpdateLokasi(lokasi *model.Lokasi) error
 	CreateLokasi(lokasi *model.Lokasi) error
-	DeleteLokasi(id uint) error
+	DeleteLokasi(id, orgID uint) error
+	ReplaceLokasiAkses(lokasiID uint, akses []model.LokasiAkses) error
 	Create(org *model.Organisasi) error
 	GetAll() ([]model.Organisasi, error)
 }
@@ -35,7 +36,7 @@ func (r *organisasiRepository) GetFirst() (*model.Organisasi, error) {
 
 func (r *organisasiRepository) GetByID(id uint) (*model.Organisasi, error) {
 	var org model.Organisasi
-	err := r.db.Preload("Lokasis").First(&org, id).Error
+	err := r.db.Preload("Lokasis.Akses").First(&org, id).Error
 	return &org, err
 }
 
@@ -53,9 +54,9 @@ func (r *organisasiRepository) GetAll() ([]model.Organisasi, error) {
 	return orgs, err
 }
 
-func (r *organisasiRepository) GetLokasiByID(id uint) (*model.Lokasi, error) {
+func (r *organisasiRepository) GetLokasiByIDInOrg(id, orgID uint) (*model.Lokasi, error) {
 	var lokasi model.Lokasi
-	err := r.db.First(&lokasi, id).Error
+	err := r.db.Scopes(scopeOrg(orgID)).First(&lokasi, id).Error
 	return &lokasi, err
 }
 
@@ -67,6 +68,27 @@ func (r *organisasiRepository) CreateLokasi(lokasi *model.Lokasi) error {
 	return r.db.Create(lokasi).Error
 }
 
-func (r *organisasiRepository) DeleteLokasi(id uint) error {
-	return r.db.Delete(&model.Lokasi{}, id).Error
+func (r *organisasiRepository) DeleteLokasi(id, orgID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := notFoundIfNoRows(tx.Scopes(scopeOrg(orgID)).Delete(&model.Lokasi{}, id)); err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("lokasi_id = ?", id).Delete(&model.LokasiAkses{}).Error
+	})
+}
+
+// ReplaceLokasiAkses: Ganti seluruh aturan akses lokasi (list kosong = lokasi terbuka untuk semua pegawai)
+func (r *organisasiRepository) ReplaceLokasiAkses(lokasiID uint, akses []model.LokasiAkses) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("lokasi_id = ?", lokasiID).Delete(&model.LokasiAkses{}).Error; err != nil {
+			return err
+		}
+		if len(akses) == 0 {
+			return nil
+		}
+		for i := range akses {
+			akses[i].LokasiID = lokasiID
+		}
+		return tx.Create(&akses).Error
+	})
 }
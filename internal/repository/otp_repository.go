@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"my-flutter-backend/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type OTPRepository interface {
+	Create(otp *model.PasswordResetOTP) error
+	Update(otp *model.PasswordResetOTP) error
+	GetLatestByNIP(nip string) (*model.PasswordResetOTP, error)
+	GetByResetTokenHash(hash string) (*model.PasswordResetOTP, error)
+	CountByNIPSince(nip string, since time.Time) (int64, error)
+	CountByIPSince(ip string, since time.Time) (int64, error)
+	InvalidateActiveByNIP(nip string) error
+	UseAttempt(id uint, maxAttempts int) (bool, error)
+	Invalidate(id uint) error
+	MarkVerified(id uint, resetTokenHash string, resetTokenExpiresAt time.Time) (bool, error)
+	MarkReset(id uint) (bool, error)
+	UnmarkReset(id uint) error
+}
+
+type otpRepository struct {
+	db *gorm.DB
+}
+
+func NewOTPRepository(db *gorm.DB) OTPRepository {
+	return &otpRepository{db}
+}
+
+func (r *otpRepository) Create(otp *model.PasswordResetOTP) error {
+	return r.db.Create(otp).Error
+}
+
+func (r *otpRepository) Update(otp *model.PasswordResetOTP) error {
+	return r.db.Save(otp).Error
+}
+
+func (r *otpRepository) GetLatestByNIP(nip string) (*model.PasswordResetOTP, error) {
+	var otp model.PasswordResetOTP
+	err := r.db.Where("nip = ?", nip).Order("created_at desc").First(&otp).Error
+	return &otp, err
+}
+
+func (r *otpRepository) GetByResetTokenHash(hash string) (*model.PasswordResetOTP, error) {
+	var otp model.PasswordResetOTP
+	err := r.db.Where("reset_token_hash = ?", hash).First(&otp).Error
+	return &otp, err
+}
+
+func (r *otpRepository) CountByNIPSince(nip string, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.PasswordResetOTP{}).Where("nip = ? AND created_at >= ?", nip, since).Count(&count).Error
+	return count, err
+}
+
+func (r *otpRepository) CountByIPSince(ip string, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.PasswordResetOTP{}).Where("request_ip = ? AND created_at >= ?", ip, since).Count(&count).Error
+	return count, err
+}
+
+// InvalidateActiveByNIP: OTP lama otomatis tidak berlaku saat OTP baru diminta
+func (r *otpRepository) InvalidateActiveByNIP(nip string) error {
+	return r.db.Model(&model.PasswordResetOTP{}).
+		Where("nip = ? AND verified_at IS NULL AND invalidated_at IS NULL", nip).
+		Update("invalidated_at", time.Now()).Error
+}
+
+// UseAttempt: Jatah satu percobaan verifikasi dipakai secara atomik (aman untuk tebakan paralel).
+// false jika jatah habis atau OTP sudah tidak aktif.
+func (r *otpRepository) UseAttempt(id uint, maxAttempts int) (bool, error) {
+	res := r.db.Model(&model.PasswordResetOTP{}).
+		Where("id = ? AND attempts < ? AND verified_at IS NULL AND invalidated_at IS NULL", id, maxAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *otpRepository) Invalidate(id uint) error {
+	return r.db.Model(&model.PasswordResetOTP{}).
+		Where("id = ? AND invalidated_at IS NULL", id).
+		Update("invalidated_at", time.Now()).Error
+}
+
+// MarkVerified: Terbitkan reset token hanya sekali per OTP (false jika sudah diverifikasi/dibatalkan lebih dulu)
+func (r *otpRepository) MarkVerified(id uint, resetTokenHash string, resetTokenExpiresAt time.Time) (bool, error) {
+	res := r.db.Model(&model.PasswordResetOTP{}).
+		Where("id = ? AND verified_at IS NULL AND invalidated_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"verified_at":            time.Now(),
+			"reset_token_hash":       resetTokenHash,
+			"reset_token_expires_at": resetTokenExpiresAt,
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+// MarkReset: Klaim reset token secara atomik (false jika sudah pernah dipakai)
+func (r *otpRepository) MarkReset(id uint) (bool, error) {
+	res := r.db.Model(&model.PasswordResetOTP{}).
+		Where("id = ? AND reset_at IS NULL", id).
+		Update("reset_at", time.Now())
+	return res.RowsAffected > 0, res.Error
+}
+
+// UnmarkReset: Kembalikan klaim jika penggantian password gagal disimpan
+func (r *otpRepository) UnmarkReset(id uint) error {
+	return r.db.Model(&model.PasswordResetOTP{}).Where("id = ?", id).Update("reset_at", nil).Error
+}
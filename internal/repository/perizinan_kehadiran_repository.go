@@ -11,6 +11,7 @@ type PerizinanKehadiranRepository interface {
 	GetByASNID(asnID uint) ([]model.PerizinanKehadiran, error)
 	GetByAtasanID(atasanID uint) ([]model.PerizinanKehadiran, error)
 	GetByID(id uint) (*model.PerizinanKehadiran, error)
+	GetByIDInOrg(id, orgID uint) (*model.PerizinanKehadiran, error)
 	Update(koreksi *model.PerizinanKehadiran) error
 	Delete(id uint) error
 }
@@ -49,6 +50,13 @@ func (r *perizinanKehadiranRepository) GetByID(id uint) (*model.PerizinanKehadir
 	return &koreksi, err
 }
 
+// GetByIDInOrg: Untuk approval oleh atasan/admin, hanya koreksi pegawai satu organisasi
+func (r *perizinanKehadiranRepository) GetByIDInOrg(id, orgID uint) (*model.PerizinanKehadiran, error) {
+	var koreksi model.PerizinanKehadiran
+	err := r.db.Scopes(scopeASNOrg(orgID)).First(&koreksi, id).Error
+	return &koreksi, err
+}
+
 func (r *perizinanKehadiranRepository) Update(koreksi *model.PerizinanKehadiran) error {
 	return r.db.Save(koreksi).Error
 }
@@ -11,8 +11,11 @@ type PerizinanRepository interface {
 	GetByASNID(asnID uint) ([]model.PerizinanCuti, error)
 	GetByAtasanID(atasanID uint) ([]model.PerizinanCuti, error)
 	GetByID(id uint) (*model.PerizinanCuti, error)
+	GetByIDInOrg(id, orgID uint) (*model.PerizinanCuti, error)
 	Update(izin *model.PerizinanCuti) error
 	Delete(id uint) error
+	// GetApprovedByASNIDs: Izin/cuti yang sudah disetujui (termasuk yang sedang diajukan pembatalan)
+	GetApprovedByASNIDs(asnIDs []uint) ([]model.PerizinanCuti, error)
 }
 
 type perizinanRepository struct {
@@ -50,6 +53,13 @@ func (r *perizinanRepository) GetByID(id uint) (*model.PerizinanCuti, error) {
 	return &izin, err
 }
 
+// GetByIDInOrg: Untuk approval oleh atasan/admin, hanya izin pegawai satu organisasi
+func (r *perizinanRepository) GetByIDInOrg(id, orgID uint) (*model.PerizinanCuti, error) {
+	var izin model.PerizinanCuti
+	err := r.db.Scopes(scopeASNOrg(orgID)).First(&izin, id).Error
+	return &izin, err
+}
+
 func (r *perizinanRepository) Update(izin *model.PerizinanCuti) error {
 	return r.db.Save(izin).Error
 }
@@ -57,3 +67,12 @@ func (r *perizinanRepository) Update(izin *model.PerizinanCuti) error {
 func (r *perizinanRepository) Delete(id uint) error {
 	return r.db.Delete(&model.PerizinanCuti{}, id).Error
 }
+
+func (r *perizinanRepository) GetApprovedByASNIDs(asnIDs []uint) ([]model.PerizinanCuti, error) {
+	var list []model.PerizinanCuti
+	if len(asnIDs) == 0 {
+		return list, nil
+	}
+	err := r.db.Where("asn_id IN ? AND status IN ?", asnIDs, []string{"DISETUJUI", "MEMBATALKAN"}).Find(&list).Error
+	return list, err
+}
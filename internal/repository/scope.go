@@ -0,0 +1,33 @@
+package repository
+
+import "gorm.io/gorm"
+
+// --- ISOLASI TENANT (ORGANISASI) ---
+// Admin hanya boleh menyentuh data organisasinya sendiri. Akses data milik organisasi lain
+// lewat method *InOrg / method yang menerima orgID diperlakukan seperti data tidak ada
+// (gorm.ErrRecordNotFound), sehingga handler cukup membalas 404 tanpa membocorkan keberadaannya.
+
+// scopeOrg: Filter tabel yang punya kolom organisasi_id (asns, lokasis, banners, shifts, hari_liburs)
+func scopeOrg(orgID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("organisasi_id = ?", orgID)
+	}
+}
+
+// scopeASNOrg: Filter tabel yang terhubung ke organisasi lewat asn_id (jadwals, perizinan, kehadiran)
+func scopeASNOrg(orgID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("asn_id IN (SELECT id FROM asns WHERE organisasi_id = ?)", orgID)
+	}
+}
+
+// notFoundIfNoRows: Update/Delete yang tidak mengenai baris manapun (ID salah / beda organisasi) dianggap tidak ditemukan
+func notFoundIfNoRows(result *gorm.DB) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"my-flutter-backend/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// lastUsedResolution: last_used_at cukup diperbarui sekali per menit (menghindari write di setiap request)
+const lastUsedResolution = time.Minute
+
+type ServiceAccountRepository interface {
+	Create(sa *model.ServiceAccount) error
+	Update(sa *model.ServiceAccount) error
+	GetAllByOrg(orgID uint) ([]model.ServiceAccount, error)
+	GetByIDInOrg(id, orgID uint) (*model.ServiceAccount, error)
+	// FindActiveByKeyHash: Service account dengan API key tsb yang belum dicabut & belum kadaluwarsa
+	FindActiveByKeyHash(hash string) (*model.ServiceAccount, error)
+	TouchLastUsed(id uint, ip string) error
+}
+
+type serviceAccountRepository struct {
+	db *gorm.DB
+}
+
+func NewServiceAccountRepository(db *gorm.DB) ServiceAccountRepository {
+	return &serviceAccountRepository{db}
+}
+
+func (r *serviceAccountRepository) Create(sa *model.ServiceAccount) error {
+	return r.db.Create(sa).Error
+}
+
+func (r *serviceAccountRepository) Update(sa *model.ServiceAccount) error {
+	return r.db.Save(sa).Error
+}
+
+func (r *serviceAccountRepository) GetAllByOrg(orgID uint) ([]model.ServiceAccount, error) {
+	var list []model.ServiceAccount
+	err := r.db.Scopes(scopeOrg(orgID)).Order("created_at desc").Find(&list).Error
+	return list, err
+}
+
+func (r *serviceAccountRepository) GetByIDInOrg(id, orgID uint) (*model.ServiceAccount, error) {
+	var sa model.ServiceAccount
+	err := r.db.Scopes(scopeOrg(orgID)).First(&sa, id).Error
+	return &sa, err
+}
+
+func (r *serviceAccountRepository) FindActiveByKeyHash(hash string) (*model.ServiceAccount, error) {
+	var sa model.ServiceAccount
+	err := r.db.Where("key_hash = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", hash, time.Now()).
+		First(&sa).Error
+	return &sa, err
+}
+
+func (r *serviceAccountRepository) TouchLastUsed(id uint, ip string) error {
+	now := time.Now()
+	return r.db.Model(&model.ServiceAccount{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, now.Add(-lastUsedResolution)).
+		Updates(map[string]interface{}{"last_used_at": now, "last_used_ip": ip}).Error
+}
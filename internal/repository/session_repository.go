@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"my-flutter-backend/config"
+	"my-flutter-backend/internal/model"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	Create(session *model.Session) error
+	GetByID(id uint) (*model.Session, error)
+	Update(session *model.Session) error
+	Revoke(id uint, reason string) error
+	RevokeAllByASN(asnID uint, reason string) error
+	RevokeAllByASNExcept(asnID uint, exceptID uint, reason string) error
+	RevokeAllByDevice(deviceID uint, reason string) error
+	GetActiveByASN(asnID uint) ([]model.Session, error)
+	IsActive(id uint) (bool, error)
+	CreateRefreshToken(token *model.RefreshToken) error
+	GetRefreshTokenByHash(hash string) (*model.RefreshToken, error)
+	MarkRefreshTokenUsed(id uint) (bool, error)
+}
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db}
+}
+
+func (r *sessionRepository) Create(session *model.Session) error {
+	return r.db.Create(session).Error
+}
+
+func (r *sessionRepository) GetByID(id uint) (*model.Session, error) {
+	var session model.Session
+	err := r.db.Preload("Device").First(&session, id).Error
+	return &session, err
+}
+
+func (r *sessionRepository) Update(session *model.Session) error {
+	return r.db.Omit("Device").Save(session).Error
+}
+
+func (r *sessionRepository) Revoke(id uint, reason string) error {
+	defer invalidateSessionCache()
+	return r.db.Model(&model.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoke_reason": reason}).Error
+}
+
+func (r *sessionRepository) RevokeAllByASN(asnID uint, reason string) error {
+	defer invalidateSessionCache()
+	return r.db.Model(&model.Session{}).
+		Where("asn_id = ? AND revoked_at IS NULL", asnID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoke_reason": reason}).Error
+}
+
+func (r *sessionRepository) RevokeAllByASNExcept(asnID uint, exceptID uint, reason string) error {
+	defer invalidateSessionCache()
+	return r.db.Model(&model.Session{}).
+		Where("asn_id = ? AND id <> ? AND revoked_at IS NULL", asnID, exceptID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoke_reason": reason}).Error
+}
+
+func (r *sessionRepository) RevokeAllByDevice(deviceID uint, reason string) error {
+	defer invalidateSessionCache()
+	return r.db.Model(&model.Session{}).
+		Where("device_id = ? AND revoked_at IS NULL", deviceID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoke_reason": reason}).Error
+}
+
+func (r *sessionRepository) GetActiveByASN(asnID uint) ([]model.Session, error) {
+	var sessions []model.Session
+	err := r.db.Preload("Device").
+		Where("asn_id = ? AND revoked_at IS NULL AND expires_at > ?", asnID, time.Now()).
+		Order("last_seen_at desc").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *sessionRepository) CreateRefreshToken(token *model.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *sessionRepository) GetRefreshTokenByHash(hash string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+	err := r.db.Where("token_hash = ?", hash).First(&token).Error
+	return &token, err
+}
+
+// MarkRefreshTokenUsed menandai token sudah dirotasi secara atomik.
+// Return false jika token sudah pernah dipakai (termasuk race dua request refresh bersamaan).
+func (r *sessionRepository) MarkRefreshTokenUsed(id uint) (bool, error) {
+	result := r.db.Model(&model.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// --- CACHE STATUS SESSION ---
+// Auth mengecek claim "sid" di setiap request agar access token dari session yang dicabut (logout, revoke)
+// langsung ditolak. Status disimpan sebentar (in-process) supaya tidak query DB di setiap request; cache
+// dikosongkan setiap kali ada pencabutan session di instance ini, instance lain menyusul setelah TTL.
+
+type cachedSession struct {
+	active   bool
+	loadedAt time.Time
+}
+
+var (
+	sessionCache   = make(map[uint]cachedSession)
+	sessionCacheMu sync.RWMutex
+)
+
+func sessionCacheTTL() time.Duration {
+	return time.Duration(config.GetEnvAsInt("SESSION_CACHE_TTL_SECONDS", 15)) * time.Second
+}
+
+func invalidateSessionCache() {
+	sessionCacheMu.Lock()
+	sessionCache = make(map[uint]cachedSession)
+	sessionCacheMu.Unlock()
+}
+
+// IsActive: Session ada dan belum dicabut
+func (r *sessionRepository) IsActive(id uint) (bool, error) {
+	sessionCacheMu.RLock()
+	cached, ok := sessionCache[id]
+	sessionCacheMu.RUnlock()
+	if ok && time.Since(cached.loadedAt) < sessionCacheTTL() {
+		return cached.active, nil
+	}
+
+	var count int64
+	if err := r.db.Model(&model.Session{}).Where("id = ? AND revoked_at IS NULL", id).Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	sessionCacheMu.Lock()
+	sessionCache[id] = cachedSession{active: count > 0, loadedAt: time.Now()}
+	sessionCacheMu.Unlock()
+	return count > 0, nil
+}
@@ -11,8 +11,8 @@ type ShiftRepository interface {
 	GetAll(orgID uint) ([]model.Shift, error)
 	Create(shift *model.Shift) error
 	Update(shift *model.Shift) error
-	Delete(id uint) error
-	GetByID(id uint) (*model.Shift, error)
+	Delete(id, orgID uint) error
+	GetByIDInOrg(id, orgID uint) (*model.Shift, error)
 	FindOrCreate(orgID uint, jamMasuk, jamPulang string) (*model.Shift, error)
 }
 
@@ -38,13 +38,13 @@ func (r *shiftRepository) Update(shift *model.Shift) error {
 	return r.db.Save(shift).Error
 }
 
-func (r *shiftRepository) Delete(id uint) error {
-	return r.db.Delete(&model.Shift{}, id).Error
+func (r *shiftRepository) Delete(id, orgID uint) error {
+	return notFoundIfNoRows(r.db.Scopes(scopeOrg(orgID)).Delete(&model.Shift{}, id))
 }
 
-func (r *shiftRepository) GetByID(id uint) (*model.Shift, error) {
+func (r *shiftRepository) GetByIDInOrg(id, orgID uint) (*model.Shift, error) {
 	var shift model.Shift
-	err := r.db.First(&shift, id).Error
+	err := r.db.Scopes(scopeOrg(orgID)).First(&shift, id).Error
 	return &shift, err
 }
 
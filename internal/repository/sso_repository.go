@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"my-flutter-backend/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SSORepository interface {
+	CreateState(state *model.SSOLoginState) error
+	ConsumeState(stateHash string) (*model.SSOLoginState, error)
+}
+
+type ssoRepository struct {
+	db *gorm.DB
+}
+
+func NewSSORepository(db *gorm.DB) SSORepository {
+	return &ssoRepository{db}
+}
+
+func (r *ssoRepository) CreateState(state *model.SSOLoginState) error {
+	return r.db.Create(state).Error
+}
+
+// ConsumeState: Tandai state terpakai secara atomik (callback yang sama tidak bisa diputar ulang).
+// State tidak dikenal, kadaluwarsa, atau sudah dipakai -> gorm.ErrRecordNotFound.
+func (r *ssoRepository) ConsumeState(stateHash string) (*model.SSOLoginState, error) {
+	now := time.Now()
+	result := r.db.Model(&model.SSOLoginState{}).
+		Where("state_hash = ? AND used_at IS NULL AND expires_at > ?", stateHash, now).
+		Update("used_at", now)
+	if err := notFoundIfNoRows(result); err != nil {
+		return nil, err
+	}
+
+	var state model.SSOLoginState
+	err := r.db.Where("state_hash = ?", stateHash).First(&state).Error
+	return &state, err
+}
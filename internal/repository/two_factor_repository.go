@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"my-flutter-backend/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TwoFactorRepository interface {
+	GetByASN(asnID uint) (*model.TwoFactor, error)
+	Save(tf *model.TwoFactor) error
+	UseStep(id uint, step int64) (bool, error)
+	DeleteByASN(asnID uint) error
+	ReplaceRecoveryCodes(asnID uint, hashes []string) error
+	UseRecoveryCode(asnID uint, hash string) (bool, error)
+	CountUnusedRecoveryCodes(asnID uint) (int64, error)
+}
+
+type twoFactorRepository struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorRepository(db *gorm.DB) TwoFactorRepository {
+	return &twoFactorRepository{db}
+}
+
+func (r *twoFactorRepository) GetByASN(asnID uint) (*model.TwoFactor, error) {
+	var tf model.TwoFactor
+	err := r.db.Where("asn_id = ?", asnID).First(&tf).Error
+	return &tf, err
+}
+
+func (r *twoFactorRepository) Save(tf *model.TwoFactor) error {
+	return r.db.Save(tf).Error
+}
+
+// UseStep: Tandai time-step TOTP sudah dipakai (atomic, agar kode tidak bisa di-replay)
+func (r *twoFactorRepository) UseStep(id uint, step int64) (bool, error) {
+	result := r.db.Model(&model.TwoFactor{}).
+		Where("id = ? AND last_used_step < ?", id, step).
+		Update("last_used_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteByASN: Nonaktifkan 2FA beserta semua recovery code
+func (r *twoFactorRepository) DeleteByASN(asnID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("asn_id = ?", asnID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("asn_id = ?", asnID).Delete(&model.TwoFactor{}).Error
+	})
+}
+
+// ReplaceRecoveryCodes: Recovery code lama dihapus, diganti set baru
+func (r *twoFactorRepository) ReplaceRecoveryCodes(asnID uint, hashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("asn_id = ?", asnID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]model.RecoveryCode, 0, len(hashes))
+		for _, h := range hashes {
+			codes = append(codes, model.RecoveryCode{ASNID: asnID, CodeHash: h})
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *twoFactorRepository) UseRecoveryCode(asnID uint, hash string) (bool, error) {
+	result := r.db.Model(&model.RecoveryCode{}).
+		Where("asn_id = ? AND code_hash = ? AND used_at IS NULL", asnID, hash).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *twoFactorRepository) CountUnusedRecoveryCodes(asnID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.RecoveryCode{}).Where("asn_id = ? AND used_at IS NULL", asnID).Count(&count).Error
+	return count, err
+}
@@ -3,6 +3,8 @@ package routes
 import (
 	"my-flutter-backend/internal/handler"
 	"my-flutter-backend/internal/middleware"
+	"my-flutter-backend/internal/model"
+	"my-flutter-backend/internal/oidc"
 	"my-flutter-backend/internal/repository"
 
 	"github.com/gofiber/fiber/v2"
@@ -11,41 +13,85 @@ import (
 
 func SetupASNRoutes(app *fiber.App, db *gorm.DB) {
 	repo := repository.NewASNRepository(db)
-	hdl := handler.NewASNHandler(repo)
+	sessionRepo := repository.NewSessionRepository(db)
+	otpRepo := repository.NewOTPRepository(db)
+	throttleRepo := repository.NewLoginThrottleRepository(db)
+	twoFactorRepo := repository.NewTwoFactorRepository(db)
+	ssoRepo := repository.NewSSORepository(db)
+	deviceRepo := repository.NewDeviceRepository(db)
+	inviteRepo := repository.NewInvitationRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	hdl := handler.NewASNHandler(repo, sessionRepo, otpRepo, throttleRepo, twoFactorRepo, ssoRepo, deviceRepo, inviteRepo, roleRepo, oidc.FromEnv())
 
 	// Auth Routes
 	app.Post("/api/login", hdl.Login)
-	app.Post("/api/web-login", hdl.WebLogin) // Endpoint Login Khusus Web
+	app.Post("/api/web-login", hdl.WebLogin)                       // Endpoint Login Khusus Web
+	app.Post("/api/web-login/2fa", hdl.VerifyWebLogin2FA)          // Step 2: Kode TOTP / Recovery Code
+	app.Post("/api/web-login/2fa/setup", hdl.SetupWebLogin2FA)     // Step 2 (belum punya 2FA): Dapat QR
+	app.Post("/api/web-login/2fa/confirm", hdl.ConfirmWebLogin2FA) // Step 3: Konfirmasi kode pertama -> Login
 	app.Post("/api/refresh-token", hdl.RefreshToken)
+	app.Post("/api/sso/oidc/start", hdl.StartSSO)       // SSO Step 1: Dapat authorization_url IdP
+	app.Post("/api/sso/oidc/callback", hdl.SSOCallback) // SSO Step 2: Tukar code & state -> Token
+	app.Get("/.well-known/jwks.json", hdl.JWKS)         // Public key untuk verifikasi token (RS256/EdDSA)
 
 	// Forgot Password Routes (Mobile Flow)
 	app.Post("/api/forgot-password/request", hdl.RequestOTP)       // 1. Cek NIP & Email -> Kirim OTP
-	app.Post("/api/forgot-password/verify", hdl.VerifyOTP)         // 2. Cek OTP -> Lanjut ke halaman password baru
-	app.Post("/api/forgot-password/reset", hdl.ResetPasswordFinal) // 3. Submit Password Baru
+	app.Post("/api/forgot-password/verify", hdl.VerifyOTP)         // 2. Cek OTP -> Dapat reset_token
+	app.Post("/api/forgot-password/reset", hdl.ResetPasswordFinal) // 3. Submit Password Baru + reset_token
+
+	// Aktivasi Akun Undangan (link dari email undangan)
+	app.Post("/api/activation/check", hdl.CheckActivation) // Cek token -> Data pegawai & kebijakan password
+	app.Post("/api/activation", hdl.Activate)              // Buat password pertama -> Akun aktif
+
+	// Ganti Device: Diajukan dari layar login HP baru (device_change_token dari respons /api/login)
+	app.Post("/api/device-change/request", hdl.SubmitDeviceChange)
+
+	// Approval Ganti Device: Atasan langsung atau admin kelola_pegawai (dicek di handler)
+	deviceChange := app.Group("/api/device-change/approval", middleware.Auth)
+	auditDeviceChange := middleware.AuditTarget{Entity: "device_change", Model: model.DeviceChangeRequest{}}
+	deviceChange.Get("/", hdl.GetDeviceChangeApprovals)
+	deviceChange.Post("/:id", middleware.NoImpersonation, middleware.Audit("APPROVAL", auditDeviceChange), hdl.ProcessDeviceChange)
 
 	// Profile Routes (Protected)
 	api := app.Group("/api/asn", middleware.Auth)
 	api.Get("/profile", hdl.GetProfile)
 	api.Put("/profile", hdl.UpdateProfile)
-	api.Put("/password", hdl.ChangePassword)
+	api.Put("/password", middleware.NoImpersonation, hdl.ChangePassword)
 	api.Get("/atasan-list", hdl.GetListAtasan)      // Get List Kandidat Atasan
 	api.Post("/atasan", hdl.UpdateAtasan)           // Update Atasan Saya
 	api.Get("/bawahan", hdl.GetSubordinates)        // Get List Bawahan (Untuk Atasan)
+	api.Get("/devices", hdl.GetMyDevices)           // Device Terdaftar & Permintaan Ganti Device
 	api.Post("/upload-foto", hdl.UploadFotoProfile) // Upload Foto Profile
 
+	// 2FA (TOTP) Akun Sendiri
+	api.Get("/2fa", hdl.GetTwoFactorStatus)
+	api.Post("/2fa/setup", middleware.NoImpersonation, hdl.SetupTwoFactor)
+	api.Post("/2fa/confirm", middleware.NoImpersonation, hdl.ConfirmTwoFactor)
+	api.Post("/2fa/recovery-codes", middleware.NoImpersonation, hdl.RegenerateRecoveryCodes)
+	api.Delete("/2fa", middleware.NoImpersonation, hdl.DisableTwoFactor)
+
 	// Admin Routes (Kelola Pegawai)
-	// admin := app.Group("/api/admin/asn", middleware.Auth, middleware.Role("Admin", "Super Admin"))
-	// Ganti jadi Permission Based:
-	admin := app.Group("/api/admin/asn", middleware.Auth, middleware.Permission("edit_jadwal"))
+	admin := withPermission(app.Group("/api/admin/asn", middleware.Auth), "kelola_pegawai")
+	audit := middleware.AuditTarget{Entity: "asn", Model: model.ASN{}}
 	admin.Get("/", hdl.GetAll)
-	admin.Get("/:id", hdl.GetASNDetail) // Route baru untuk detail
-	admin.Post("/", hdl.CreateASN)
-	admin.Post("/import", hdl.ImportASN) // Route Import Excel
-	admin.Put("/:id", hdl.UpdateASN)
-	admin.Patch("/:id/status", hdl.ToggleActiveASN)         // Toggle Status Aktif/Nonaktif
-	admin.Put("/:id/reset-password", hdl.ResetUserPassword) // Route reset password (Lupa Password)
-	admin.Delete("/:id", hdl.DeleteASN)
-	admin.Delete("/:id/device", hdl.ResetDevice)
+	admin.Get("/pending-activation", hdl.GetPendingActivations) // Laporan pegawai yang belum aktivasi akun
+	admin.Get("/:id", hdl.GetASNDetail)                         // Route baru untuk detail
+	admin.Post("/", middleware.Audit("CREATE", audit), hdl.CreateASN)
+	admin.Post("/import", middleware.Audit("IMPORT", audit), hdl.ImportASN) // Route Import Excel
+	admin.Put("/:id", middleware.Audit("UPDATE", audit), hdl.UpdateASN)
+	admin.Patch("/:id/status", middleware.Audit("UPDATE_STATUS", audit), hdl.ToggleActiveASN)          // Toggle Status Aktif/Nonaktif
+	admin.Put("/:id/reset-password", middleware.Audit("RESET_PASSWORD", audit), hdl.ResetUserPassword) // Route reset password (Lupa Password)
+	admin.Post("/:id/invitation", middleware.Audit("INVITE", audit), hdl.ResendInvitation)             // Kirim ulang undangan aktivasi
+	admin.Delete("/:id/invitation", middleware.Audit("REVOKE_INVITE", audit), hdl.RevokeInvitation)    // Batalkan undangan aktif
+	admin.Delete("/:id", middleware.Audit("DELETE", audit), hdl.DeleteASN)
+	admin.Delete("/:id/device", middleware.Audit("RESET_DEVICE", audit), hdl.ResetDevice)
+	admin.Get("/:id/devices", hdl.GetDeviceHistory)                                    // Device aktif + riwayat semua device yang pernah terikat
+	admin.Delete("/:id/2fa", middleware.Audit("RESET_2FA", audit), hdl.ResetTwoFactor) // Reset 2FA (authenticator hilang)
+	// Foto pembanding verifikasi wajah (foto profil bisa diganti pegawai sendiri, jadi tidak dipakai)
+	admin.Put("/:id/foto-referensi", middleware.Audit("SET_FOTO_REFERENSI", audit), hdl.SetFotoReferensi)
+
+	// Impersonasi (Khusus Super Admin, lintas organisasi): Token sementara untuk melihat aplikasi sebagai pegawai
+	app.Post("/api/admin/asn/:id/impersonate", middleware.Auth, middleware.Superuser, middleware.Audit("IMPERSONATE", audit), hdl.Impersonate)
 
 	// Public Routes (Image Serving)
 	app.Get("/api/public/asn/:id/foto", hdl.GetFotoProfile)
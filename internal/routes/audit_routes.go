@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"my-flutter-backend/internal/handler"
+	"my-flutter-backend/internal/middleware"
+	"my-flutter-backend/internal/repository"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func SetupAuditRoutes(app *fiber.App, db *gorm.DB) {
+	repo := repository.NewAuditLogRepository(db)
+	hdl := handler.NewAuditHandler(repo)
+
+	// Audit Log Mutasi Administratif (Pemeriksaan Inspektorat)
+	// Bisa diakses login admin maupun API key service account (scope view_audit)
+	api := withPermission(app.Group("/api/admin/audit", middleware.AuthWithAPIKey), "view_audit")
+	api.Get("/", hdl.GetAll)
+}
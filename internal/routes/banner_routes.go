@@ -3,6 +3,7 @@ package routes
 import (
 	"my-flutter-backend/internal/handler"
 	"my-flutter-backend/internal/middleware"
+	"my-flutter-backend/internal/model"
 	"my-flutter-backend/internal/repository"
 
 	"github.com/gofiber/fiber/v2"
@@ -15,8 +16,9 @@ func SetupBannerRoutes(app *fiber.App, db *gorm.DB) {
 
 	app.Get("/api/banner", middleware.Auth, hdl.GetAll) // Mobile (Active Only)
 
-	admin := app.Group("/api/admin/banner", middleware.Auth, middleware.Permission("edit_jadwal"))
+	admin := withPermission(app.Group("/api/admin/banner", middleware.Auth), "kelola_banner")
+	audit := middleware.AuditTarget{Entity: "banner", Model: model.Banner{}}
 	admin.Get("/", hdl.GetAllAdmin) // Admin (All)
-	admin.Post("/", hdl.Create)
-	admin.Put("/:id/toggle", hdl.ToggleStatus) // Toggle Active/Inactive
+	admin.Post("/", middleware.Audit("CREATE", audit), hdl.Create)
+	admin.Put("/:id/toggle", middleware.Audit("TOGGLE_STATUS", audit), hdl.ToggleStatus) // Toggle Active/Inactive
 }
@@ -3,6 +3,7 @@ package routes
 import (
 	"my-flutter-backend/internal/handler"
 	"my-flutter-backend/internal/middleware"
+	"my-flutter-backend/internal/model"
 	"my-flutter-backend/internal/repository"
 
 	"github.com/gofiber/fiber/v2"
@@ -13,10 +14,11 @@ func SetupHariLiburRoutes(app *fiber.App, db *gorm.DB) {
 	repo := repository.NewHariLiburRepository(db)
 	hdl := handler.NewHariLiburHandler(repo)
 
-	api := app.Group("/api/admin/hari-libur", middleware.Auth, middleware.Permission("edit_jadwal"))
+	api := withPermission(app.Group("/api/admin/hari-libur", middleware.Auth), "edit_jadwal")
+	audit := middleware.AuditTarget{Entity: "hari_libur", Model: model.HariLibur{}}
 
 	api.Get("/", hdl.GetAll)
-	api.Post("/", hdl.Create)
-	api.Put("/:id", hdl.Update)
-	api.Delete("/:id", hdl.Delete)
+	api.Post("/", middleware.Audit("CREATE", audit), hdl.Create)
+	api.Put("/:id", middleware.Audit("UPDATE", audit), hdl.Update)
+	api.Delete("/:id", middleware.Audit("DELETE", audit), hdl.Delete)
 }
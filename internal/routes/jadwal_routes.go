@@ -3,6 +3,7 @@ package routes
 import (
 	"my-flutter-backend/internal/handler"
 	"my-flutter-backend/internal/middleware"
+	"my-flutter-backend/internal/model"
 	"my-flutter-backend/internal/repository"
 
 	"github.com/gofiber/fiber/v2"
@@ -20,15 +21,18 @@ func SetupJadwalRoutes(app *fiber.App, db *gorm.DB) {
 	// Mobile Routes
 	app.Get("/api/jadwal/saya", middleware.Auth, hdl.GetJadwalSaya)
 
-	api := app.Group("/api/admin", middleware.Auth, middleware.Permission("edit_jadwal"))
-	api.Get("/jadwal", hdl.GetJadwalHarian)                   // Lihat per tanggal
-	api.Get("/jadwal/dashboard-stats", hdl.GetDashboardStats) // PENTING: Taruh ini SEBELUM :id
-	api.Get("/jadwal/:id", hdl.GetJadwalDetail)               // Detail untuk Edit
-	api.Post("/jadwal", hdl.CreateJadwal)                     // Buat manual satu
-	api.Post("/jadwal/import", hdl.ImportJadwal)              // Import Excel
-	api.Post("/jadwal/generate", hdl.GenerateJadwalBulanan)
-	api.Post("/jadwal/generate-daily", hdl.GenerateJadwalHarian) // Bulk Harian
-	api.Put("/jadwal/:id", hdl.UpdateJadwal)                     // Edit Shift
-	api.Delete("/jadwal/:id", hdl.DeleteJadwal)                  // Hapus
-	api.Delete("/jadwal/date/bulk", hdl.DeleteJadwalByDate)      // Hapus Massal per Tanggal
+	// Prefix group sengaja "/api/admin/jadwal" (bukan "/api/admin") agar permission
+	// jadwal tidak ikut berlaku untuk route admin lain yang didaftarkan setelahnya.
+	api := withPermission(app.Group("/api/admin/jadwal", middleware.Auth), "edit_jadwal")
+	audit := middleware.AuditTarget{Entity: "jadwal", Model: model.Jadwal{}}
+	api.Get("/", hdl.GetJadwalHarian)                                        // Lihat per tanggal
+	api.Get("/dashboard-stats", hdl.GetDashboardStats)                       // PENTING: Taruh ini SEBELUM :id
+	api.Get("/:id", hdl.GetJadwalDetail)                                     // Detail untuk Edit
+	api.Post("/", middleware.Audit("CREATE", audit), hdl.CreateJadwal)       // Buat manual satu
+	api.Post("/import", middleware.Audit("IMPORT", audit), hdl.ImportJadwal) // Import Excel
+	api.Post("/generate", middleware.Audit("GENERATE", audit), hdl.GenerateJadwalBulanan)
+	api.Post("/generate-daily", middleware.Audit("GENERATE", audit), hdl.GenerateJadwalHarian) // Bulk Harian
+	api.Put("/:id", middleware.Audit("UPDATE", audit), hdl.UpdateJadwal)                       // Edit Shift
+	api.Delete("/:id", middleware.Audit("DELETE", audit), hdl.DeleteJadwal)                    // Hapus
+	api.Delete("/date/bulk", middleware.Audit("DELETE_BULK", audit), hdl.DeleteJadwalByDate)   // Hapus Massal per Tanggal
 }
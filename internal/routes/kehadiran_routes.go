@@ -1,8 +1,10 @@
 package routes
 
 import (
+	"my-flutter-backend/internal/faceverify"
 	"my-flutter-backend/internal/handler"
 	"my-flutter-backend/internal/middleware"
+	"my-flutter-backend/internal/model"
 	"my-flutter-backend/internal/repository"
 
 	"github.com/gofiber/fiber/v2"
@@ -14,15 +16,24 @@ func SetupKehadiranRoutes(app *fiber.App, db *gorm.DB) {
 	kehadiranRepo := repository.NewKehadiranRepository(db)
 	jadwalRepo := repository.NewJadwalRepository(db)
 	orgRepo := repository.NewOrganisasiRepository(db) // Tambah ini
-	hdl := handler.NewKehadiranHandler(kehadiranRepo, asnRepo, jadwalRepo, orgRepo)
+	fotoRepo := repository.NewFotoKehadiranRepository(db)
+	hdl := handler.NewKehadiranHandler(kehadiranRepo, asnRepo, jadwalRepo, orgRepo, fotoRepo, faceverify.FromEnv())
 
 	// Grouping route khusus kehadiran
 	api := app.Group("/api/kehadiran", middleware.Auth)
 
-	api.Post("/checkin", hdl.CheckIn)
-	api.Post("/checkout", hdl.CheckOut)
+	api.Post("/checkin", middleware.NoImpersonation, hdl.CheckIn) // Token impersonasi tidak boleh absen atas nama pegawai
+	api.Post("/checkout", middleware.NoImpersonation, hdl.CheckOut)
 	api.Get("/riwayat", hdl.GetHistory)
 	api.Get("/status-hari-ini", hdl.GetTodayStatus)
 	api.Get("/rekap", hdl.GetRekap)
 	api.Post("/check-location", hdl.CheckLocationValidity)
+	api.Get("/:id/selfie/:jenis", hdl.GetSelfie) // Pegawai ybs, atasan langsung, atau admin view_rekap/review_wajah
+
+	// Antrean review verifikasi wajah (check-in yang GAGAL/ERROR)
+	review := withPermission(app.Group("/api/admin/face-review", middleware.Auth), "review_wajah")
+	reviewHdl := handler.NewFaceReviewHandler(kehadiranRepo)
+	audit := middleware.AuditTarget{Entity: "kehadiran", Model: model.Kehadiran{}}
+	review.Get("/", reviewHdl.GetQueue)
+	review.Post("/:id", middleware.NoImpersonation, middleware.Audit("FACE_REVIEW", audit), reviewHdl.Review)
 }
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"my-flutter-backend/internal/handler"
+	"my-flutter-backend/internal/middleware"
+	"my-flutter-backend/internal/repository"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func SetupNotifikasiRoutes(app *fiber.App, db *gorm.DB) {
+	repo := repository.NewNotifikasiRepository(db)
+	hdl := handler.NewNotifikasiHandler(repo)
+
+	api := app.Group("/api/notifikasi", middleware.Auth)
+
+	api.Get("/", hdl.GetAll)
+	api.Put("/baca", hdl.MarkAllRead)
+	api.Put("/:id/baca", hdl.MarkRead)
+}
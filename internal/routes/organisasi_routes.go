@@ -3,6 +3,7 @@ package routes
 import (
 	"my-flutter-backend/internal/handler"
 	"my-flutter-backend/internal/middleware"
+	"my-flutter-backend/internal/model"
 	"my-flutter-backend/internal/repository"
 
 	"github.com/gofiber/fiber/v2"
@@ -14,19 +15,24 @@ func SetupOrganisasiRoutes(app *fiber.App, db *gorm.DB) {
 	asnRepo := repository.NewASNRepository(db)
 	hdl := handler.NewOrganisasiHandler(repo, asnRepo)
 
-	// Allow Admin and Super Admin
-	api := app.Group("/api/admin/organisasi", middleware.Auth, middleware.Permission("edit_jadwal"))
+	group := app.Group("/api/admin/organisasi", middleware.Auth)
+	auditOwnOrg := middleware.AuditTarget{Entity: "organisasi", Model: model.Organisasi{}, OwnOrg: true}
+	auditOrg := middleware.AuditTarget{Entity: "organisasi", Model: model.Organisasi{}}
+	auditLokasi := middleware.AuditTarget{Entity: "lokasi", Model: model.Lokasi{}}
 
+	// Organisasi Sendiri: Info & Lokasi Absen
+	api := withPermission(group, "kelola_lokasi")
 	api.Get("/", hdl.GetInfo)
-	api.Put("/", hdl.UpdateOrganisasi)          // Update Info Organisasi (Nama & Email)
-	api.Post("/lokasi", hdl.AddLokasi)          // Tambah Lokasi Baru
-	api.Put("/lokasi/:id", hdl.UpdateLokasi)    // Update Lokasi
-	api.Delete("/lokasi/:id", hdl.DeleteLokasi) // Hapus Lokasi
+	api.Put("/", middleware.Audit("UPDATE", auditOwnOrg), hdl.UpdateOrganisasi)                  // Update Info Organisasi (Nama & Email)
+	api.Post("/lokasi", middleware.Audit("CREATE", auditLokasi), hdl.AddLokasi)                  // Tambah Lokasi Baru
+	api.Put("/lokasi/:id", middleware.Audit("UPDATE", auditLokasi), hdl.UpdateLokasi)            // Update Lokasi
+	api.Delete("/lokasi/:id", middleware.Audit("DELETE", auditLokasi), hdl.DeleteLokasi)         // Hapus Lokasi
+	api.Put("/lokasi/:id/akses", middleware.Audit("UPDATE", auditLokasi), hdl.UpdateLokasiAkses) // Atur Pegawai yang Boleh Absen di Lokasi
 
-	// Super Admin Only Routes
-	// Super Admin Only Routes
-	api.Post("/", middleware.Role("Super Admin"), hdl.CreateOrganisasi)       // Buat Organisasi Baru
-	api.Get("/all", middleware.Role("Super Admin"), hdl.GetAllOrganisasi)     // List Semua Organisasi
-	api.Put("/:id", middleware.Role("Super Admin"), hdl.UpdateOrganisasiByID) // Update Org Lain
-	api.Get("/:id/admins", middleware.Role("Super Admin"), hdl.GetAdmins)     // Get List Admin per Org
+	// Lintas Organisasi (Super Admin): Berdasarkan permission, bukan nama role
+	lintas := withPermission(group, "kelola_organisasi")
+	lintas.Post("/", middleware.Audit("CREATE", auditOrg), hdl.CreateOrganisasi)       // Buat Organisasi Baru
+	lintas.Get("/all", hdl.GetAllOrganisasi)                                           // List Semua Organisasi
+	lintas.Put("/:id", middleware.Audit("UPDATE", auditOrg), hdl.UpdateOrganisasiByID) // Update Org Lain
+	lintas.Get("/:id/admins", hdl.GetAdmins)                                           // Get List Admin per Org
 }
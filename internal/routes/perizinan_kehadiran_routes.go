@@ -3,6 +3,7 @@ package routes
 import (
 	"my-flutter-backend/internal/handler"
 	"my-flutter-backend/internal/middleware"
+	"my-flutter-backend/internal/model"
 	"my-flutter-backend/internal/repository"
 
 	"github.com/gofiber/fiber/v2"
@@ -23,7 +24,8 @@ func SetupPerizinanKehadiranRoutes(app *fiber.App, db *gorm.DB) {
 	api.Delete("/ajukan/:id", hdl.DeleteKoreksi)
 
 	// Approval Routes
-	approval := api.Group("/", middleware.Permission("approve_cuti"))
+	approval := withPermission(api, "approve_cuti")
+	audit := middleware.AuditTarget{Entity: "koreksi", Model: model.PerizinanKehadiran{}, IDField: "koreksi_id"}
 	approval.Get("/bawahan", hdl.GetBawahan)
-	approval.Post("/approval", hdl.ProcessApproval)
+	approval.Post("/approval", middleware.Audit("APPROVAL", audit), hdl.ProcessApproval)
 }
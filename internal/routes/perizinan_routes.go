@@ -3,6 +3,7 @@ package routes
 import (
 	"my-flutter-backend/internal/handler"
 	"my-flutter-backend/internal/middleware"
+	"my-flutter-backend/internal/model"
 	"my-flutter-backend/internal/repository"
 
 	"github.com/gofiber/fiber/v2"
@@ -27,10 +28,11 @@ func SetupPerizinanRoutes(app *fiber.App, db *gorm.DB) {
 
 	// Endpoint untuk Atasan (Approval)
 	// Hanya yang punya permission 'approve_cuti' yang bisa akses
-	approval := api.Group("/", middleware.Permission("approve_cuti"))
+	approval := withPermission(api, "approve_cuti")
+	audit := middleware.AuditTarget{Entity: "perizinan", Model: model.PerizinanCuti{}, IDField: "perizinan_id"}
 	approval.Get("/bawahan", hdl.GetPengajuanBawahan)
-	approval.Post("/approval", hdl.ProcessApproval)
-	approval.Post("/approve-cancel", hdl.ApproveCancel)
+	approval.Post("/approval", middleware.Audit("APPROVAL", audit), hdl.ProcessApproval)
+	approval.Post("/approve-cancel", middleware.Audit("APPROVE_CANCEL", audit), hdl.ApproveCancel)
 
 	// Endpoint untuk Pembatalan
 	api.Post("/cancel/:id", hdl.CancelIzin)
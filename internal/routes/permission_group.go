@@ -0,0 +1,57 @@
+package routes
+
+import (
+	"strings"
+
+	"my-flutter-backend/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// permissionGroup: Kumpulan route yang dibuka oleh satu permission.
+// Middleware Permission dipasang per route (bukan lewat Group/Use) supaya tidak ikut
+// berlaku untuk route lain dengan prefix yang sama, dan setiap route otomatis tercatat
+// untuk GET /api/admin/roles/permissions/routes.
+type permissionGroup struct {
+	router     fiber.Router
+	prefix     string
+	permission string
+}
+
+func withPermission(router fiber.Router, permission string) *permissionGroup {
+	prefix := ""
+	if grp, ok := router.(*fiber.Group); ok {
+		prefix = grp.Prefix
+	}
+	return &permissionGroup{router: router, prefix: prefix, permission: permission}
+}
+
+func (g *permissionGroup) Get(path string, handlers ...fiber.Handler) {
+	g.add(fiber.MethodGet, path, handlers)
+}
+
+func (g *permissionGroup) Post(path string, handlers ...fiber.Handler) {
+	g.add(fiber.MethodPost, path, handlers)
+}
+
+func (g *permissionGroup) Put(path string, handlers ...fiber.Handler) {
+	g.add(fiber.MethodPut, path, handlers)
+}
+
+func (g *permissionGroup) Patch(path string, handlers ...fiber.Handler) {
+	g.add(fiber.MethodPatch, path, handlers)
+}
+
+func (g *permissionGroup) Delete(path string, handlers ...fiber.Handler) {
+	g.add(fiber.MethodDelete, path, handlers)
+}
+
+func (g *permissionGroup) add(method, path string, handlers []fiber.Handler) {
+	fullPath := strings.TrimSuffix(g.prefix+"/"+strings.TrimPrefix(path, "/"), "/")
+	if fullPath == "" {
+		fullPath = "/"
+	}
+	middleware.RegisterPermissionRoute(g.permission, method, fullPath)
+
+	g.router.Add(method, path, append([]fiber.Handler{middleware.Permission(g.permission)}, handlers...)...)
+}
@@ -13,10 +13,12 @@ func SetupReportRoutes(app *fiber.App, db *gorm.DB) {
 	jadwalRepo := repository.NewJadwalRepository(db)
 	kehadiranRepo := repository.NewKehadiranRepository(db)
 	asnRepo := repository.NewASNRepository(db)
+	fotoRepo := repository.NewFotoKehadiranRepository(db)
 
-	hdl := handler.NewReportHandler(jadwalRepo, kehadiranRepo, asnRepo)
+	hdl := handler.NewReportHandler(jadwalRepo, kehadiranRepo, asnRepo, fotoRepo)
 
-	api := app.Group("/api/admin/reports", middleware.Auth, middleware.Permission("edit_jadwal"))
+	// Bisa diakses login admin maupun API key service account (scope view_rekap)
+	api := withPermission(app.Group("/api/admin/reports", middleware.AuthWithAPIKey), "view_rekap")
 	api.Get("/monthly", hdl.GetMonthlyRecap)
 	api.Get("/daily", hdl.GetDailyRecap)
 
@@ -3,6 +3,7 @@ package routes
 import (
 	"my-flutter-backend/internal/handler"
 	"my-flutter-backend/internal/middleware"
+	"my-flutter-backend/internal/model"
 	"my-flutter-backend/internal/repository"
 
 	"github.com/gofiber/fiber/v2"
@@ -13,11 +14,13 @@ func SetupRoleRoutes(app *fiber.App, db *gorm.DB) {
 	repo := repository.NewRoleRepository(db)
 	hdl := handler.NewRoleHandler(repo)
 
-	api := app.Group("/api/admin/roles", middleware.Auth, middleware.Permission("edit_jadwal"))
+	api := withPermission(app.Group("/api/admin/roles", middleware.Auth), "kelola_role")
+	audit := middleware.AuditTarget{Entity: "role", Model: model.Role{}, Preloads: []string{"Permissions"}}
 	api.Get("/", hdl.GetAll)
-	api.Get("/permissions", hdl.GetAllPermissions) // List semua permission yang tersedia
+	api.Get("/permissions", hdl.GetAllPermissions)          // List semua permission yang tersedia
+	api.Get("/permissions/routes", hdl.GetPermissionRoutes) // Route apa saja yang dibuka tiap permission
 	api.Get("/:id", hdl.GetDetail)
-	api.Post("/", hdl.Create)
-	api.Put("/:id", hdl.Update)
-	api.Delete("/:id", hdl.Delete)
+	api.Post("/", middleware.Audit("CREATE", audit), hdl.Create)
+	api.Put("/:id", middleware.Audit("UPDATE", audit), hdl.Update)
+	api.Delete("/:id", middleware.Audit("DELETE", audit), hdl.Delete)
 }
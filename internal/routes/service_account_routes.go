@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"my-flutter-backend/internal/handler"
+	"my-flutter-backend/internal/middleware"
+	"my-flutter-backend/internal/model"
+	"my-flutter-backend/internal/repository"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func SetupServiceAccountRoutes(app *fiber.App, db *gorm.DB) {
+	repo := repository.NewServiceAccountRepository(db)
+	hdl := handler.NewServiceAccountHandler(repo)
+
+	// Service Account & API Key untuk integrasi (payroll, e-Kinerja). Dikelola lewat login admin, bukan API key.
+	api := withPermission(app.Group("/api/admin/service-accounts", middleware.Auth), "kelola_api_key")
+	audit := middleware.AuditTarget{Entity: "service_account", Model: model.ServiceAccount{}}
+	api.Get("/", hdl.GetAll)
+	api.Post("/", middleware.NoImpersonation, middleware.Audit("CREATE", audit), hdl.Create)
+	api.Put("/:id", middleware.NoImpersonation, middleware.Audit("UPDATE", audit), hdl.Update)
+	api.Post("/:id/rotate", middleware.NoImpersonation, middleware.Audit("ROTATE_KEY", audit), hdl.Rotate) // API key baru, key lama langsung tidak berlaku
+	api.Delete("/:id", middleware.NoImpersonation, middleware.Audit("REVOKE", audit), hdl.Revoke)
+}
@@ -0,0 +1,38 @@
+package routes
+
+import (
+	"my-flutter-backend/internal/handler"
+	"my-flutter-backend/internal/middleware"
+	"my-flutter-backend/internal/model"
+	"my-flutter-backend/internal/repository"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func SetupSessionRoutes(app *fiber.App, db *gorm.DB) {
+	repo := repository.NewSessionRepository(db)
+	asnRepo := repository.NewASNRepository(db)
+	throttleRepo := repository.NewLoginThrottleRepository(db)
+	hdl := handler.NewSessionHandler(repo, asnRepo, throttleRepo)
+
+	app.Post("/api/logout", middleware.Auth, hdl.Logout)
+
+	// Sesi Saya (Mobile & Web)
+	api := app.Group("/api/asn/sessions", middleware.Auth)
+	api.Get("/", hdl.GetMySessions)
+	api.Delete("/", middleware.NoImpersonation, hdl.RevokeAllMySessions) // ?keep_current=true
+	api.Delete("/:id", middleware.NoImpersonation, hdl.RevokeMySession)
+
+	// Admin: Kelola Sesi Pegawai
+	admin := withPermission(app.Group("/api/admin/asn/:id/sessions", middleware.Auth), "kelola_pegawai")
+	audit := middleware.AuditTarget{Entity: "asn", Model: model.ASN{}}
+	admin.Get("/", hdl.GetASNSessions)
+	admin.Delete("/", middleware.Audit("REVOKE_SESSION", audit), hdl.RevokeAllASNSessions)
+	admin.Delete("/:sessionId", middleware.Audit("REVOKE_SESSION", audit), hdl.RevokeASNSession)
+
+	// Admin: Kunci Login (Brute Force Lockout)
+	lock := withPermission(app.Group("/api/admin/asn/:id/login-lock", middleware.Auth), "kelola_pegawai")
+	lock.Get("/", hdl.GetLoginLock)
+	lock.Delete("/", middleware.Audit("UNLOCK_LOGIN", audit), hdl.UnlockLogin)
+}
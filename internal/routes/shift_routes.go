@@ -3,6 +3,7 @@ package routes
 import (
 	"my-flutter-backend/internal/handler"
 	"my-flutter-backend/internal/middleware"
+	"my-flutter-backend/internal/model"
 	"my-flutter-backend/internal/repository"
 
 	"github.com/gofiber/fiber/v2"
@@ -14,9 +15,10 @@ func SetupShiftRoutes(app *fiber.App, db *gorm.DB) {
 	jadwalRepo := repository.NewJadwalRepository(db) // Tambah ini
 	hdl := handler.NewShiftHandler(repo, jadwalRepo)
 
-	api := app.Group("/api/admin/shift", middleware.Auth, middleware.Permission("edit_jadwal"))
+	api := withPermission(app.Group("/api/admin/shift", middleware.Auth), "edit_jadwal")
+	audit := middleware.AuditTarget{Entity: "shift", Model: model.Shift{}}
 	api.Get("/", hdl.GetAll)
-	api.Post("/", hdl.Create)
-	api.Put("/:id", hdl.Update)
-	api.Delete("/:id", hdl.Delete)
+	api.Post("/", middleware.Audit("CREATE", audit), hdl.Create)
+	api.Put("/:id", middleware.Audit("UPDATE", audit), hdl.Update)
+	api.Delete("/:id", middleware.Audit("DELETE", audit), hdl.Delete)
 }